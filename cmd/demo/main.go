@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/rfielding/kripke-ctl/kripke"
 )
@@ -122,7 +124,15 @@ func main() {
 
 	w := kripke.NewWorld(procs, chans, 1) // fixed seed for reproducibility
 
-	w.RunSteps(50)
+	if err := w.RunSteps(50); err != nil {
+		var v *kripke.Violation
+		if errors.As(err, &v) {
+			fmt.Fprint(os.Stderr, v)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
 
 	fmt.Println("=== Kripke engine demo (Producer/Consumer) ===")
 	fmt.Printf("Producer sent up to: %d (max %d)\n", producer.nextValue-1, producer.maxValue)
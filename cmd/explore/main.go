@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+)
+
+// explore -messages 5 -capacity 2 [-store dir] [-checkpoint file -every N] [-resume]
+//	[-prism base] [-drn file] [-pm file]
+//
+// Enumerates the full state space of the producer/consumer model and prints
+// its size. With -checkpoint the run can be killed and continued with -resume.
+// The export flags write the graph, as an MDP whose choices are the enabled
+// steps, for PRISM and Storm, with the inbox length as the reward "queued".
+
+var (
+	messagesFlag   = flag.Int("messages", 5, "number of messages the producer sends")
+	capacityFlag   = flag.Int("capacity", 2, "consumer inbox capacity")
+	storeFlag      = flag.String("store", "", "directory for an on-disk visited set (default: in memory)")
+	checkpointFlag = flag.String("checkpoint", "", "checkpoint file")
+	everyFlag      = flag.Int("every", 1000, "expanded states between checkpoints")
+	resumeFlag     = flag.Bool("resume", false, "continue from -checkpoint instead of starting over")
+	maxFlag        = flag.Int("max", 0, "stop after this many states (0 = no limit)")
+	mermaidFlag    = flag.Bool("mermaid", false, "print the state graph as a Mermaid diagram")
+	prismFlag      = flag.String("prism", "", "write base.tra, base.lab, base.sta and base.queued.srew")
+	drnFlag        = flag.String("drn", "", "write the graph in Storm's DRN format")
+	pmFlag         = flag.String("pm", "", "write the graph as a PRISM-language model")
+)
+
+func main() {
+	flag.Parse()
+	if *resumeFlag && *checkpointFlag == "" {
+		fmt.Fprintln(os.Stderr, "-resume requires -checkpoint")
+		os.Exit(2)
+	}
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run explores the model and writes the requested outputs. It returns
+// instead of exiting so that the store is always closed.
+func run() (err error) {
+	var store kripke.StateStore = kripke.NewMemoryStore()
+	if *storeFlag != "" {
+		ds, err := kripke.OpenDiskStore(*storeFlag)
+		if err != nil {
+			return fmt.Errorf("open store: %w", err)
+		}
+		store = ds
+	}
+	defer func() {
+		if cerr := store.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	w := newWorld(*messagesFlag, *capacityFlag)
+
+	opts := []kripke.ExploreOption{
+		kripke.WithStateStore(store),
+		kripke.WithMaxStates(*maxFlag),
+		kripke.WithPropositions(kripke.ChannelPropositions(w)),
+		kripke.WithVariables(kripke.ChannelVariables(w)),
+	}
+	if *checkpointFlag != "" {
+		opts = append(opts, kripke.WithCheckpoint(*checkpointFlag, *everyFlag))
+	}
+
+	var (
+		g       *kripke.Graph
+		explErr error
+	)
+	if *resumeFlag {
+		g, explErr = kripke.Resume(w, *checkpointFlag, opts...)
+	} else {
+		g, explErr = kripke.Explore(w, opts...)
+	}
+	if errors.Is(explErr, kripke.ErrStoreMismatch) {
+		return fmt.Errorf("explore: %w; resume with the -store directory of the interrupted run, or without -store if it had none", explErr)
+	}
+	if explErr != nil && explErr != kripke.ErrStateLimit {
+		return fmt.Errorf("explore: %w", explErr)
+	}
+
+	edges := 0
+	for _, s := range g.States() {
+		edges += len(g.Succ(s))
+	}
+	fmt.Printf("States: %d\nEdges:  %d\n", len(g.States()), edges)
+	if explErr == kripke.ErrStateLimit {
+		fmt.Println("(stopped at -max; graph is partial)")
+	}
+	if *mermaidFlag {
+		fmt.Println("```mermaid")
+		fmt.Print(g.GenerateStateDiagram())
+		fmt.Println("```")
+	}
+
+	rewards := map[string]kripke.StateReward{"queued": kripke.VarReward("consumer.inbox.len")}
+	if *prismFlag != "" {
+		if err := kripke.ExportPRISM(*prismFlag, g, rewards); err != nil {
+			return err
+		}
+	}
+	if err := writeFile(*drnFlag, func(f *os.File) error { return kripke.WriteDRN(f, g, rewards) }); err != nil {
+		return err
+	}
+	return writeFile(*pmFlag, func(f *os.File) error { return kripke.WritePRISMModel(f, g, rewards) })
+}
+
+// writeFile creates path and fills it with write; an empty path is skipped.
+func writeFile(path string, write func(*os.File) error) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ---------- producer/consumer model ----------
+
+type producer struct {
+	id     string
+	target kripke.Address
+	next   int
+	max    int
+}
+
+func (p *producer) ID() string            { return p.id }
+func (p *producer) Clone() kripke.Process { cp := *p; return &cp }
+
+func (p *producer) Ready(w *kripke.World) []kripke.Step {
+	ch := w.ChannelByAddress(p.target)
+	if p.next > p.max || !ch.CanSend() {
+		return nil
+	}
+	return []kripke.Step{func(w *kripke.World) {
+		kripke.SendMessage(w, kripke.Message{
+			From:    kripke.Address{ActorID: p.id, ChannelName: "out"},
+			To:      p.target,
+			Payload: p.next,
+		})
+		p.next++
+	}}
+}
+
+type consumer struct {
+	id    string
+	inbox kripke.Address
+	count int
+}
+
+func (c *consumer) ID() string            { return c.id }
+func (c *consumer) Clone() kripke.Process { cp := *c; return &cp }
+
+func (c *consumer) Ready(w *kripke.World) []kripke.Step {
+	ch := w.ChannelByAddress(c.inbox)
+	if !ch.CanRecv() {
+		return nil
+	}
+	return []kripke.Step{func(w *kripke.World) {
+		kripke.RecvAndLog(w, ch)
+		c.count++
+	}}
+}
+
+func newWorld(messages, capacity int) *kripke.World {
+	inbox := kripke.NewChannel("consumer", "inbox", capacity)
+	p := &producer{id: "producer", target: inbox.Address(), next: 1, max: messages}
+	c := &consumer{id: "consumer", inbox: inbox.Address()}
+	return kripke.NewWorld([]kripke.Process{p, c}, []*kripke.Channel{inbox}, 1)
+}
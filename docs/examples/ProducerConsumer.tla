@@ -1,5 +1,7 @@
 ---- MODULE ProducerConsumer ----
 EXTENDS Naturals, Sequences, FiniteSets, KripkeLib
+\* KripkeLib is kripke/KripkeLib.tla, which kripke.TLAModule.WriteFiles
+\* writes next to generated specs; copy it here to check this one with TLC.
 
 CONSTANTS
     MaxSteps,      \* Maximum execution steps
@@ -1,5 +1,7 @@
 ---- MODULE WorkflowWithChance ----
 EXTENDS Naturals, Sequences, KripkeLib
+\* KripkeLib is kripke/KripkeLib.tla, which kripke.TLAModule.WriteFiles
+\* writes next to generated specs; copy it here to check this one with TLC.
 
 CONSTANTS MaxAttempts
 
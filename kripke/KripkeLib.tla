@@ -0,0 +1,39 @@
+---- MODULE KripkeLib ----
+(***************************************************************************)
+(* Operators shared by the specifications kripke generates.               *)
+(*                                                                         *)
+(* A channel is a sequence of messages, oldest first, with the FIFO       *)
+(* semantics of kripke.Channel: snd enqueues at the tail while there is   *)
+(* room, rcv dequeues the head.                                            *)
+(*                                                                         *)
+(* Probabilistic steps follow the pre-rolled dice convention: a die R is  *)
+(* rolled in 0..99 before the guards are checked, and the branch whose    *)
+(* range lo..hi-1 contains R is taken. TLC explores every roll.           *)
+(***************************************************************************)
+EXTENDS Naturals, Sequences
+
+\* can_send(ch, cap): ch has room for another message. Capacities are at
+\* least 1; rendezvous channels are not supported.
+can_send(ch, cap) == Len(ch) < cap
+
+\* snd(ch, msg): ch with msg enqueued behind every message already in it.
+snd(ch, msg) == Append(ch, msg)
+
+\* can_recv(ch): ch holds a message.
+can_recv(ch) == Len(ch) > 0
+
+\* rcv(ch): the oldest message of ch and the channel without it, as
+\* [msg |-> m, channel |-> rest].
+rcv(ch) == [msg |-> Head(ch), channel |-> Tail(ch)]
+
+\* Dice: the values a die can show.
+Dice == 0..99
+
+\* rolled(R, lo, hi): the die R selects the branch lo..hi-1.
+rolled(R, lo, hi) == lo <= R /\ R < hi
+
+\* choice(lo, hi, guard, act): the branch that takes act when guard holds
+\* and the die lands in lo..hi-1, so with probability (hi - lo) / 100.
+choice(lo, hi, guard, act) == \E R \in Dice : rolled(R, lo, hi) /\ guard /\ act
+
+====
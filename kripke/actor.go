@@ -0,0 +1,436 @@
+package kripke
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------- Declarative actors ----------
+
+// Action is one guarded step of an Actor, described as data. Guards and
+// expressions use the syntax of ParseCTL and ParseExpr and may refer to
+// the actor's own variables and to len(<actor>.<channel>) for any channel.
+// Every expression is evaluated in the state before the step, so
+// Set{"a": "b", "b": "a"} swaps a and b. An action is not enabled while
+// one of its expressions has no value, such as x / 0, or while the
+// message it would receive does not have the type of Into.
+type Action struct {
+	Name string
+	// Guard is a state formula without temporal operators; "" is true.
+	Guard string
+	// Recv names one of the actor's own channels. The action is enabled
+	// only when it holds a message, and takes the oldest one.
+	Recv string
+	// Into, with Recv, is the variable that receives the message payload.
+	Into string
+	// Send enqueues one message per entry. The action is enabled only
+	// when every target channel has room.
+	Send []Send
+	// Set maps variables to their new values.
+	Set map[string]string
+}
+
+// Send is a message an Action enqueues: the value of Payload is sent to
+// the channel at To.
+type Send struct {
+	To      Address
+	Payload string
+}
+
+// Actor is a Process described by data rather than code: named int,
+// string and bool variables and a list of guarded actions. It runs like
+// any other process, and because its steps are not hidden in closures,
+// GenerateTLAPlus can translate a World of Actors into TLA+.
+type Actor struct {
+	id      string
+	vars    map[string]any
+	actions []*action // shared by clones; never modified
+}
+
+type action struct {
+	Action
+	guard    Formula
+	payloads []Expr
+	set      map[string]Expr
+}
+
+// plan is an enabled action with its payloads and updates evaluated.
+type plan struct {
+	act      *action
+	payloads []any
+	updates  map[string]any
+}
+
+// NewActor builds an actor with the given initial variables and actions,
+// parsing and type-checking every guard and expression: variables keep
+// the type of their initial value, and expressions may only read declared
+// variables and channel lengths. Actions are offered in the order given.
+func NewActor(id string, vars map[string]any, actions ...Action) (*Actor, error) {
+	a := &Actor{id: id, vars: make(map[string]any, len(vars))}
+	for name, v := range vars {
+		nv, ok := normalizeValue(v)
+		if !ok {
+			return nil, fmt.Errorf("NewActor %s: variable %s has unsupported type %T", id, name, v)
+		}
+		a.vars[name] = nv
+	}
+	names := make(map[string]bool)
+	for _, act := range actions {
+		if act.Name == "" {
+			return nil, fmt.Errorf("NewActor %s: action without a name", id)
+		}
+		if names[act.Name] {
+			return nil, fmt.Errorf("NewActor %s: duplicate action %s", id, act.Name)
+		}
+		names[act.Name] = true
+		parsed, err := a.parseAction(act)
+		if err != nil {
+			return nil, fmt.Errorf("NewActor %s: action %s: %w", id, act.Name, err)
+		}
+		a.actions = append(a.actions, parsed)
+	}
+	return a, nil
+}
+
+// MustNewActor is NewActor for actors known to be valid; it panics on
+// error.
+func MustNewActor(id string, vars map[string]any, actions ...Action) *Actor {
+	a, err := NewActor(id, vars, actions...)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (a *Actor) parseAction(act Action) (*action, error) {
+	p := &action{Action: act, guard: True(), set: make(map[string]Expr, len(act.Set))}
+	if act.Guard != "" {
+		f, err := ParseCTL(act.Guard)
+		if err != nil {
+			return nil, err
+		}
+		if !isStateFormula(f) {
+			return nil, fmt.Errorf("guard %q has temporal operators", act.Guard)
+		}
+		if err := a.checkGuard(f); err != nil {
+			return nil, fmt.Errorf("guard %q: %w", act.Guard, err)
+		}
+		p.guard = f
+	}
+	if act.Into != "" {
+		if act.Recv == "" {
+			return nil, fmt.Errorf("Into %s without Recv", act.Into)
+		}
+		if _, ok := a.vars[act.Into]; !ok {
+			return nil, fmt.Errorf("Into: undeclared variable %s", act.Into)
+		}
+	}
+	touched := make(map[string]bool)
+	if act.Recv != "" {
+		touched[Address{a.id, act.Recv}.String()] = true
+	}
+	for _, s := range act.Send {
+		if touched[s.To.String()] {
+			return nil, fmt.Errorf("channel %s is used twice", s.To)
+		}
+		touched[s.To.String()] = true
+		e, err := ParseExpr(s.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := a.typeOf(e); err != nil {
+			return nil, fmt.Errorf("payload %q: %w", s.Payload, err)
+		}
+		p.payloads = append(p.payloads, e)
+	}
+	for name, src := range act.Set {
+		if _, ok := a.vars[name]; !ok {
+			return nil, fmt.Errorf("Set: undeclared variable %s", name)
+		}
+		if name == act.Into {
+			return nil, fmt.Errorf("variable %s is both Set and Into", name)
+		}
+		e, err := ParseExpr(src)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := a.typeOf(e)
+		if err != nil {
+			return nil, fmt.Errorf("Set %s: %w", name, err)
+		}
+		if want := fmt.Sprintf("%T", a.vars[name]); typ != want {
+			return nil, fmt.Errorf("Set %s: %s is %s, not %s", name, src, typ, want)
+		}
+		p.set[name] = e
+	}
+	return p, nil
+}
+
+// checkGuard checks that the propositions of a guard are bool variables
+// and that its comparisons compare values of one type.
+func (a *Actor) checkGuard(f Formula) error {
+	switch f := f.(type) {
+	case AtomFormula:
+		if _, ok := a.vars[f.Prop].(bool); !ok {
+			return fmt.Errorf("proposition %s is not a bool variable", f.Prop)
+		}
+	case CompareFormula:
+		l, err := a.typeOf(f.Left)
+		if err != nil {
+			return err
+		}
+		r, err := a.typeOf(f.Right)
+		if err != nil {
+			return err
+		}
+		if l != r {
+			return fmt.Errorf("%s %s %s compares %s with %s", f.Left, f.Op, f.Right, l, r)
+		}
+		if l == "bool" && f.Op != "==" && f.Op != "!=" {
+			return fmt.Errorf("%s %s %s: bools are not ordered", f.Left, f.Op, f.Right)
+		}
+	case NotFormula:
+		return a.checkGuard(f.Inner)
+	case AndFormula:
+		if err := a.checkGuard(f.Left); err != nil {
+			return err
+		}
+		return a.checkGuard(f.Right)
+	case OrFormula:
+		if err := a.checkGuard(f.Left); err != nil {
+			return err
+		}
+		return a.checkGuard(f.Right)
+	}
+	return nil
+}
+
+// typeOf returns the type e evaluates to, "int", "string" or "bool", and
+// an error if e reads anything but the actor's variables and the lengths
+// of strings and channels.
+func (a *Actor) typeOf(e Expr) (string, error) {
+	switch e := e.(type) {
+	case ConstExpr:
+		return fmt.Sprintf("%T", e.Value), nil
+	case VarExpr:
+		if v, ok := a.vars[e.Name]; ok {
+			return fmt.Sprintf("%T", v), nil
+		}
+		if addr, ok := strings.CutSuffix(e.Name, ".len"); ok && isChannelAddress(addr) {
+			return "int", nil
+		}
+		return "", fmt.Errorf("undeclared variable %s", e.Name)
+	case LenExpr:
+		if v, ok := a.vars[e.Name]; ok {
+			if _, ok := v.(string); !ok {
+				return "", fmt.Errorf("%s: %s is not a string", e, e.Name)
+			}
+			return "int", nil
+		}
+		if !isChannelAddress(e.Name) {
+			return "", fmt.Errorf("%s: %s is neither a variable nor a channel", e, e.Name)
+		}
+		return "int", nil
+	case ArithExpr:
+		l, err := a.typeOf(e.Left)
+		if err != nil {
+			return "", err
+		}
+		r, err := a.typeOf(e.Right)
+		if err != nil {
+			return "", err
+		}
+		if e.Op == "+" && l == "string" && r == "string" {
+			return "string", nil
+		}
+		if l != "int" || r != "int" {
+			return "", fmt.Errorf("%s: %s of %s and %s", e, e.Op, l, r)
+		}
+		return "int", nil
+	}
+	return "", fmt.Errorf("unsupported expression %s", e)
+}
+
+// isChannelAddress reports whether name has the form <actor>.<channel>.
+func isChannelAddress(name string) bool {
+	actor, ch, ok := strings.Cut(name, ".")
+	return ok && actor != "" && ch != ""
+}
+
+// isStateFormula reports whether f is built from propositions and
+// comparisons with the boolean connectives only.
+func isStateFormula(f Formula) bool {
+	switch f := f.(type) {
+	case TrueFormula, AtomFormula, CompareFormula:
+		return true
+	case NotFormula:
+		return isStateFormula(f.Inner)
+	case AndFormula:
+		return isStateFormula(f.Left) && isStateFormula(f.Right)
+	case OrFormula:
+		return isStateFormula(f.Left) && isStateFormula(f.Right)
+	}
+	return false
+}
+
+func (a *Actor) ID() string { return a.id }
+
+// Var returns the current value of one of the actor's variables.
+func (a *Actor) Var(name string) (any, bool) {
+	v, ok := a.vars[name]
+	return v, ok
+}
+
+// VarNames returns the actor's variable names in sorted order.
+func (a *Actor) VarNames() []string {
+	return sortedKeys(a.vars)
+}
+
+// Actions returns the actor's actions as given to NewActor.
+func (a *Actor) Actions() []Action {
+	out := make([]Action, len(a.actions))
+	for i, act := range a.actions {
+		out[i] = act.Action
+	}
+	return out
+}
+
+func (a *Actor) Clone() Process {
+	cp := *a
+	cp.vars = make(map[string]any, len(a.vars))
+	for k, v := range a.vars {
+		cp.vars[k] = v
+	}
+	return &cp
+}
+
+func (a *Actor) StateKey() string {
+	parts := make([]string, 0, len(a.vars))
+	for _, name := range a.VarNames() {
+		parts = append(parts, fmt.Sprintf("%s=%#v", name, a.vars[name]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// scope is a one-state graph holding the actor's variables and the length
+// of every channel of w, on which guards and expressions are evaluated.
+func (a *Actor) scope(w *World) (*Graph, StateID) {
+	g := NewGraph()
+	s := g.AddState("s", nil)
+	vars := make(map[string]any, len(a.vars)+len(w.Channels))
+	for key, ch := range w.Channels {
+		vars[key+".len"] = ch.Len()
+	}
+	for name, v := range a.vars {
+		vars[name] = v
+	}
+	g.SetVars(s, vars)
+	return g, s
+}
+
+// plan evaluates act in w, and reports false when act is not enabled.
+func (a *Actor) plan(w *World, g *Graph, s StateID, act *action) (*plan, bool) {
+	if act.Recv != "" {
+		ch := w.ChannelByAddress(Address{a.id, act.Recv})
+		if ch == nil || !ch.CanRecv() {
+			return nil, false
+		}
+		if act.Into != "" {
+			v, ok := normalizeValue(ch.buf[0].Payload)
+			if !ok || fmt.Sprintf("%T", v) != fmt.Sprintf("%T", a.vars[act.Into]) {
+				return nil, false
+			}
+		}
+	}
+	for _, send := range act.Send {
+		ch := w.ChannelByAddress(send.To)
+		if ch == nil || !ch.CanSend() {
+			return nil, false
+		}
+	}
+	if !act.guard.Sat(g).Contains(s) {
+		return nil, false
+	}
+	p := &plan{act: act, payloads: make([]any, len(act.payloads)), updates: make(map[string]any, len(act.set))}
+	for i, e := range act.payloads {
+		v, ok := e.Eval(g, s)
+		if !ok {
+			return nil, false
+		}
+		p.payloads[i] = v
+	}
+	for name, e := range act.set {
+		v, ok := e.Eval(g, s)
+		if !ok {
+			return nil, false
+		}
+		p.updates[name] = v
+	}
+	return p, true
+}
+
+func (a *Actor) plans(w *World) []*plan {
+	g, s := a.scope(w)
+	var out []*plan
+	for _, act := range a.actions {
+		if p, ok := a.plan(w, g, s, act); ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (a *Actor) Ready(w *World) []Step {
+	var steps []Step
+	for _, p := range a.plans(w) {
+		steps = append(steps, func(w *World) { a.run(w, p) })
+	}
+	return steps
+}
+
+func (a *Actor) StepLabels(w *World) []EdgeLabel {
+	var labels []EdgeLabel
+	for _, p := range a.plans(w) {
+		labels = append(labels, EdgeLabel{Action: p.act.Name, Guard: p.act.Guard})
+	}
+	return labels
+}
+
+// run takes an enabled action: it receives, sends the evaluated payloads
+// and assigns the evaluated updates.
+func (a *Actor) run(w *World, p *plan) {
+	act := p.act
+	if act.Recv != "" {
+		msg, _ := RecvAndLog(w, w.ChannelByAddress(Address{a.id, act.Recv}))
+		if act.Into != "" {
+			a.vars[act.Into], _ = normalizeValue(msg.Payload)
+		}
+	}
+	for i, send := range act.Send {
+		SendMessage(w, Message{From: Address{a.id, act.Name}, To: send.To, Payload: p.payloads[i]})
+	}
+	for name, v := range p.updates {
+		a.vars[name] = v
+	}
+}
+
+// ActorVariables derives "<actor>.<var>" for every variable of every
+// Actor in w, the names GenerateTLAPlus reads in properties.
+func ActorVariables(w *World) Variables {
+	vs := make(Variables)
+	for i, p := range w.Procs {
+		a, ok := p.(*Actor)
+		if !ok {
+			continue
+		}
+		for _, name := range a.VarNames() {
+			vs[a.id+"."+name] = func(w *World) any {
+				if a, ok := w.Procs[i].(*Actor); ok {
+					return a.vars[name]
+				}
+				return nil
+			}
+		}
+	}
+	return vs
+}
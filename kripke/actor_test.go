@@ -0,0 +1,124 @@
+package kripke
+
+import (
+	"strings"
+	"testing"
+)
+
+// actorWorld is producerConsumerWorld written with Actors: P sends 1..n
+// to C's inbox of capacity cap, and C counts what it receives.
+func actorWorld(n, cap int) *World {
+	inbox := NewChannel("C", "inbox", cap)
+	p := MustNewActor("P", map[string]any{"next": 1, "max": n},
+		Action{
+			Name:  "send",
+			Guard: "next <= max",
+			Send:  []Send{{To: inbox.Address(), Payload: "next"}},
+			Set:   map[string]string{"next": "next + 1"},
+		})
+	c := MustNewActor("C", map[string]any{"count": 0, "last": 0},
+		Action{Name: "take", Recv: "inbox", Into: "last", Set: map[string]string{"count": "count + 1"}})
+	return NewWorld([]Process{p, c}, []*Channel{inbox}, 1)
+}
+
+func TestActorMatchesHandwrittenProcess(t *testing.T) {
+	g, err := Explore(actorWorld(3, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Explore(producerConsumerWorld(3, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.States()) != len(want.States()) {
+		t.Fatalf("actors explore %d states, processes %d", len(g.States()), len(want.States()))
+	}
+	actions := make(map[string]bool)
+	for e := range g.Edges() {
+		actions[e.Action+" ["+e.Guard+"]"] = true
+	}
+	if len(actions) != 2 || !actions["send [next <= max]"] || !actions["take []"] {
+		t.Fatalf("edge labels %v", actions)
+	}
+}
+
+func TestActorRun(t *testing.T) {
+	w := actorWorld(2, 1)
+	if err := w.RunSteps(100); err != nil {
+		t.Fatal(err)
+	}
+	c := w.Procs[1].(*Actor)
+	if count, _ := c.Var("count"); count != 2 {
+		t.Fatalf("count = %v", count)
+	}
+	if last, _ := c.Var("last"); last != 2 {
+		t.Fatalf("last = %v", last)
+	}
+	if len(w.Events) != 2 {
+		t.Fatalf("%d events logged", len(w.Events))
+	}
+
+	// Every expression sees the state before the step.
+	a := MustNewActor("A", map[string]any{"x": 1, "y": "b"},
+		Action{Name: "swap", Guard: "x < 3", Set: map[string]string{"x": "x + 1", "y": `y + "!"`}})
+	w = NewWorld([]Process{a}, nil, 1)
+	clone := a.Clone().(*Actor)
+	if err := w.RunSteps(10); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.StateKey(); got != `x=3,y="b!!"` {
+		t.Fatalf("state %s", got)
+	}
+	if got := clone.StateKey(); got != `x=1,y="b"` {
+		t.Fatalf("clone changed with the original: %s", got)
+	}
+
+	// Actions whose expressions have no value, or that would receive a
+	// payload of the wrong type, are not enabled.
+	in := NewChannel("A", "in", 1)
+	in.TrySend(Message{Payload: "text"})
+	a = MustNewActor("A", map[string]any{"x": 1, "d": 0},
+		Action{Name: "div", Set: map[string]string{"x": "x / d"}},
+		Action{Name: "recv", Recv: "in", Into: "x"})
+	w = NewWorld([]Process{a}, []*Channel{in}, 1)
+	if steps := w.EnabledSteps(); len(steps) != 0 {
+		t.Fatalf("%d steps enabled", len(steps))
+	}
+}
+
+func TestNewActorErrors(t *testing.T) {
+	to := Address{"B", "in"}
+	for _, tc := range []struct {
+		act  Action
+		want string
+	}{
+		{Action{}, "without a name"},
+		{Action{Name: "a", Guard: "AF(x > 0)"}, "temporal operators"},
+		{Action{Name: "a", Guard: "x >"}, "ParseCTL"},
+		{Action{Name: "a", Set: map[string]string{"y": "1"}}, "undeclared variable y"},
+		{Action{Name: "a", Set: map[string]string{"x": "y + 1"}}, "undeclared variable y"},
+		{Action{Name: "a", Set: map[string]string{"x": `"s"`}}, "is string, not int"},
+		{Action{Name: "a", Set: map[string]string{"x": "len(x)"}}, "x is not a string"},
+		{Action{Name: "a", Set: map[string]string{"x": "len(y)"}}, "neither a variable nor a channel"},
+		{Action{Name: "a", Set: map[string]string{"x": `x - "s"`}}, "- of int and string"},
+		{Action{Name: "a", Send: []Send{{To: to, Payload: "y"}}}, "undeclared variable y"},
+		{Action{Name: "a", Guard: "busy"}, "busy is not a bool variable"},
+		{Action{Name: "a", Guard: `x == "s"`}, "compares int with string"},
+		{Action{Name: "a", Guard: "x < B.in.len & y > 0"}, "undeclared variable y"},
+		{Action{Name: "a", Set: map[string]string{"x": "1 +"}}, "ParseExpr"},
+		{Action{Name: "a", Into: "x"}, "without Recv"},
+		{Action{Name: "a", Recv: "in", Into: "x", Set: map[string]string{"x": "1"}}, "both Set and Into"},
+		{Action{Name: "a", Send: []Send{{To: to, Payload: "1"}, {To: to, Payload: "2"}}}, "used twice"},
+	} {
+		_, err := NewActor("A", map[string]any{"x": 0}, tc.act)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: error %v, want %q", tc.act, err, tc.want)
+		}
+	}
+	if _, err := NewActor("A", nil, Action{Name: "a"}, Action{Name: "a"}); err == nil {
+		t.Error("duplicate action accepted")
+	}
+	if _, err := NewActor("A", map[string]any{"f": 1.5}); err == nil {
+		t.Error("float variable accepted")
+	}
+}
@@ -0,0 +1,412 @@
+// Package bdd implements reduced ordered binary decision diagrams in pure
+// Go. A Manager owns a table of nodes shared by every function built with
+// it; two functions are equal exactly when their Nodes are, so
+// equivalence checks and fixpoint tests are a comparison.
+//
+// Variables are numbered from 0, and a lower number is tested nearer the
+// root. Nodes are never freed: a Manager grows for as long as it is used,
+// which suits building and checking one model. ClearCaches drops the
+// operation caches but keeps the nodes.
+package bdd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Node is a boolean function owned by a Manager.
+type Node int32
+
+const (
+	False Node = 0
+	True  Node = 1
+)
+
+// terminalLevel sorts the constants below every variable.
+const terminalLevel = math.MaxInt32
+
+type node struct {
+	level  int32
+	lo, hi Node
+}
+
+type Manager struct {
+	nodes  []node
+	unique map[node]Node
+	ite    map[[3]Node]Node
+}
+
+// New returns a manager holding only the constants.
+func New() *Manager {
+	m := &Manager{unique: make(map[node]Node)}
+	m.nodes = []node{{level: terminalLevel}, {level: terminalLevel}}
+	m.ClearCaches()
+	return m
+}
+
+// ClearCaches forgets memoized operation results. Nodes stay valid.
+func (m *Manager) ClearCaches() {
+	m.ite = make(map[[3]Node]Node)
+}
+
+// NodeCount is the number of nodes the manager has created, constants
+// included.
+func (m *Manager) NodeCount() int { return len(m.nodes) }
+
+func (m *Manager) mk(level int32, lo, hi Node) Node {
+	if lo == hi {
+		return lo
+	}
+	k := node{level, lo, hi}
+	if n, ok := m.unique[k]; ok {
+		return n
+	}
+	n := Node(len(m.nodes))
+	m.nodes = append(m.nodes, k)
+	m.unique[k] = n
+	return n
+}
+
+func (m *Manager) level(f Node) int32 { return m.nodes[f].level }
+
+// Var is the function that is true when variable i is.
+func (m *Manager) Var(i int) Node {
+	if i < 0 || i >= terminalLevel {
+		panic(fmt.Sprintf("bdd: variable %d out of range", i))
+	}
+	return m.mk(int32(i), False, True)
+}
+
+// NVar is the negation of Var(i).
+func (m *Manager) NVar(i int) Node { return m.mk(int32(i), True, False) }
+
+// Inspect returns the variable f tests first and its cofactors for that
+// variable false and true. For a constant v is -1 and lo and hi are f.
+func (m *Manager) Inspect(f Node) (v int, lo, hi Node) {
+	n := m.nodes[f]
+	if n.level == terminalLevel {
+		return -1, f, f
+	}
+	return int(n.level), n.lo, n.hi
+}
+
+// cofactors of f with respect to the variable at level.
+func (m *Manager) cofactors(f Node, level int32) (Node, Node) {
+	n := m.nodes[f]
+	if n.level != level {
+		return f, f
+	}
+	return n.lo, n.hi
+}
+
+// Ite is if f then g else h.
+func (m *Manager) Ite(f, g, h Node) Node {
+	switch {
+	case f == True:
+		return g
+	case f == False:
+		return h
+	case g == h:
+		return g
+	case g == True && h == False:
+		return f
+	}
+	k := [3]Node{f, g, h}
+	if r, ok := m.ite[k]; ok {
+		return r
+	}
+	top := min(m.level(f), m.level(g), m.level(h))
+	f0, f1 := m.cofactors(f, top)
+	g0, g1 := m.cofactors(g, top)
+	h0, h1 := m.cofactors(h, top)
+	r := m.mk(top, m.Ite(f0, g0, h0), m.Ite(f1, g1, h1))
+	m.ite[k] = r
+	return r
+}
+
+func (m *Manager) Not(f Node) Node        { return m.Ite(f, False, True) }
+func (m *Manager) And(f, g Node) Node     { return m.Ite(f, g, False) }
+func (m *Manager) Or(f, g Node) Node      { return m.Ite(f, True, g) }
+func (m *Manager) Xor(f, g Node) Node     { return m.Ite(f, m.Not(g), g) }
+func (m *Manager) Equiv(f, g Node) Node   { return m.Ite(f, g, m.Not(g)) }
+func (m *Manager) Implies(f, g Node) Node { return m.Ite(f, g, True) }
+
+// AndNot is f ∧ ¬g.
+func (m *Manager) AndNot(f, g Node) Node { return m.Ite(g, False, f) }
+
+// AndAll is the conjunction of fs, True when there are none.
+func (m *Manager) AndAll(fs ...Node) Node {
+	r := True
+	for _, f := range fs {
+		r = m.And(r, f)
+	}
+	return r
+}
+
+// OrAll is the disjunction of fs, False when there are none.
+func (m *Manager) OrAll(fs ...Node) Node {
+	r := False
+	for _, f := range fs {
+		r = m.Or(r, f)
+	}
+	return r
+}
+
+// ---------- Quantification and substitution ----------
+
+// varSet marks variables by level.
+type varSet map[int32]bool
+
+func newVarSet(vars []int) varSet {
+	s := make(varSet, len(vars))
+	for _, v := range vars {
+		s[int32(v)] = true
+	}
+	return s
+}
+
+// Exists is ∃vars. f.
+func (m *Manager) Exists(f Node, vars []int) Node {
+	return m.AndExists(f, True, vars)
+}
+
+// Forall is ∀vars. f.
+func (m *Manager) Forall(f Node, vars []int) Node {
+	return m.Not(m.Exists(m.Not(f), vars))
+}
+
+// AndExists is ∃vars. f ∧ g, computed without building f ∧ g first
+// (the relational product).
+func (m *Manager) AndExists(f, g Node, vars []int) Node {
+	qs := newVarSet(vars)
+	cache := make(map[[2]Node]Node)
+	var rec func(f, g Node) Node
+	rec = func(f, g Node) Node {
+		switch {
+		case f == False || g == False:
+			return False
+		case f == True && g == True:
+			return True
+		}
+		if f > g {
+			f, g = g, f
+		}
+		k := [2]Node{f, g}
+		if r, ok := cache[k]; ok {
+			return r
+		}
+		top := min(m.level(f), m.level(g))
+		f0, f1 := m.cofactors(f, top)
+		g0, g1 := m.cofactors(g, top)
+		var r Node
+		if qs[top] {
+			r = rec(f0, g0)
+			if r != True {
+				r = m.Or(r, rec(f1, g1))
+			}
+		} else {
+			r = m.mk(top, rec(f0, g0), rec(f1, g1))
+		}
+		cache[k] = r
+		return r
+	}
+	return rec(f, g)
+}
+
+// Rename substitutes variable to for each variable from in f. The
+// mapping need not preserve the variable order.
+func (m *Manager) Rename(f Node, perm map[int]int) Node {
+	cache := make(map[Node]Node)
+	var rec func(f Node) Node
+	rec = func(f Node) Node {
+		n := m.nodes[f]
+		if n.level == terminalLevel {
+			return f
+		}
+		if r, ok := cache[f]; ok {
+			return r
+		}
+		v := int(n.level)
+		if to, ok := perm[v]; ok {
+			v = to
+		}
+		r := m.Ite(m.Var(v), rec(n.hi), rec(n.lo))
+		cache[f] = r
+		return r
+	}
+	return rec(f)
+}
+
+// Restrict fixes variable v to val in f.
+func (m *Manager) Restrict(f Node, v int, val bool) Node {
+	cache := make(map[Node]Node)
+	lv := int32(v)
+	var rec func(f Node) Node
+	rec = func(f Node) Node {
+		n := m.nodes[f]
+		if n.level > lv {
+			return f
+		}
+		if r, ok := cache[f]; ok {
+			return r
+		}
+		var r Node
+		switch {
+		case n.level < lv:
+			r = m.mk(n.level, rec(n.lo), rec(n.hi))
+		case val:
+			r = n.hi
+		default:
+			r = n.lo
+		}
+		cache[f] = r
+		return r
+	}
+	return rec(f)
+}
+
+// ---------- Queries ----------
+
+// Eval is the value of f under the assignment.
+func (m *Manager) Eval(f Node, assign func(v int) bool) bool {
+	for {
+		n := m.nodes[f]
+		if n.level == terminalLevel {
+			return f == True
+		}
+		if assign(int(n.level)) {
+			f = n.hi
+		} else {
+			f = n.lo
+		}
+	}
+}
+
+// Support lists the variables f depends on, in ascending order.
+func (m *Manager) Support(f Node) []int {
+	seen := make(map[Node]bool)
+	vars := make(map[int]bool)
+	var rec func(f Node)
+	rec = func(f Node) {
+		n := m.nodes[f]
+		if n.level == terminalLevel || seen[f] {
+			return
+		}
+		seen[f] = true
+		vars[int(n.level)] = true
+		rec(n.lo)
+		rec(n.hi)
+	}
+	rec(f)
+	out := make([]int, 0, len(vars))
+	for v := range vars {
+		out = append(out, v)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Size is the number of nodes reachable from f, constants included.
+func (m *Manager) Size(f Node) int {
+	seen := make(map[Node]bool)
+	var rec func(f Node)
+	rec = func(f Node) {
+		if seen[f] {
+			return
+		}
+		seen[f] = true
+		if n := m.nodes[f]; n.level != terminalLevel {
+			rec(n.lo)
+			rec(n.hi)
+		}
+	}
+	rec(f)
+	return len(seen)
+}
+
+// positions maps the levels of vars, sorted, to their index.
+func positions(vars []int) ([]int, map[int32]int) {
+	sorted := append([]int(nil), vars...)
+	sort.Ints(sorted)
+	pos := make(map[int32]int, len(sorted))
+	for i, v := range sorted {
+		pos[int32(v)] = i
+	}
+	return sorted, pos
+}
+
+// SatCount is the number of assignments to vars that satisfy f. The
+// support of f must lie within vars.
+func (m *Manager) SatCount(f Node, vars []int) float64 {
+	sorted, pos := positions(vars)
+	at := func(f Node) int {
+		n := m.nodes[f]
+		if n.level == terminalLevel {
+			return len(sorted)
+		}
+		p, ok := pos[n.level]
+		if !ok {
+			panic(fmt.Sprintf("bdd: SatCount: variable %d is not counted", n.level))
+		}
+		return p
+	}
+	cache := make(map[Node]float64)
+	// count is the number of assignments to the variables from f's own
+	// position on.
+	var count func(f Node) float64
+	count = func(f Node) float64 {
+		switch f {
+		case False:
+			return 0
+		case True:
+			return 1
+		}
+		if c, ok := cache[f]; ok {
+			return c
+		}
+		n := m.nodes[f]
+		p := at(f)
+		c := math.Ldexp(count(n.lo), at(n.lo)-p-1) + math.Ldexp(count(n.hi), at(n.hi)-p-1)
+		cache[f] = c
+		return c
+	}
+	return math.Ldexp(count(f), at(f))
+}
+
+// AllSat calls fn with every assignment to vars that satisfies f, as
+// values indexed like vars, until fn returns false. Assignments come in
+// ascending binary order of the sorted variables. The support of f must
+// lie within vars, and the slice passed to fn is reused.
+func (m *Manager) AllSat(f Node, vars []int, fn func(values []bool) bool) {
+	sorted, _ := positions(vars)
+	index := make(map[int]int, len(vars))
+	for i, v := range vars {
+		index[v] = i
+	}
+	values := make([]bool, len(vars))
+	var rec func(f Node, i int) bool
+	rec = func(f Node, i int) bool {
+		if f == False {
+			return true
+		}
+		if i == len(sorted) {
+			if f != True {
+				panic(fmt.Sprintf("bdd: AllSat: variable %d is not enumerated", m.level(f)))
+			}
+			return fn(values)
+		}
+		v := sorted[i]
+		lo, hi := f, f
+		if m.level(f) == int32(v) {
+			lo, hi = m.nodes[f].lo, m.nodes[f].hi
+		}
+		values[index[v]] = false
+		if !rec(lo, i+1) {
+			return false
+		}
+		values[index[v]] = true
+		return rec(hi, i+1)
+	}
+	rec(f, 0)
+}
@@ -0,0 +1,164 @@
+package bdd
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// With six variables a function is a 64-bit truth table: bit a is its
+// value under the assignment whose bit v is variable v.
+const nvars = 6
+
+var allVars = []int{0, 1, 2, 3, 4, 5}
+
+func varTable(v int) uint64 {
+	var t uint64
+	for a := 0; a < 64; a++ {
+		if a>>v&1 == 1 {
+			t |= 1 << a
+		}
+	}
+	return t
+}
+
+func table(m *Manager, f Node) uint64 {
+	var t uint64
+	for a := 0; a < 64; a++ {
+		if m.Eval(f, func(v int) bool { return a>>v&1 == 1 }) {
+			t |= 1 << a
+		}
+	}
+	return t
+}
+
+// random builds a random function and its truth table.
+func random(m *Manager, rng *rand.Rand, depth int) (Node, uint64) {
+	if depth == 0 || rng.Intn(4) == 0 {
+		v := rng.Intn(nvars)
+		if rng.Intn(2) == 0 {
+			return m.NVar(v), ^varTable(v)
+		}
+		return m.Var(v), varTable(v)
+	}
+	f, ft := random(m, rng, depth-1)
+	g, gt := random(m, rng, depth-1)
+	switch rng.Intn(5) {
+	case 0:
+		return m.And(f, g), ft & gt
+	case 1:
+		return m.Or(f, g), ft | gt
+	case 2:
+		return m.Xor(f, g), ft ^ gt
+	case 3:
+		return m.Implies(f, g), ^ft | gt
+	}
+	h, ht := random(m, rng, depth-1)
+	return m.Ite(f, g, h), ft&gt | ^ft&ht
+}
+
+// exists quantifies v out of a truth table.
+func exists(t uint64, v int) uint64 {
+	m := varTable(v)
+	hi, lo := t&m, t&^m
+	return hi | hi>>(1<<v) | lo | lo<<(1<<v)
+}
+
+func TestOperationsMatchTruthTables(t *testing.T) {
+	rng := rand.New(rand.NewSource(45))
+	m := New()
+	byTable := make(map[uint64]Node)
+	for i := 0; i < 2000; i++ {
+		f, ft := random(m, rng, 4)
+		if got := table(m, f); got != ft {
+			t.Fatalf("table %x, want %x", got, ft)
+		}
+		// Canonicity: equal functions are the same node.
+		if n, ok := byTable[ft]; ok && n != f {
+			t.Fatalf("function %x has nodes %d and %d", ft, n, f)
+		}
+		byTable[ft] = f
+		if got, want := m.SatCount(f, allVars), float64(bits.OnesCount64(ft)); got != want {
+			t.Fatalf("SatCount %v, want %v", got, want)
+		}
+
+		v, w := rng.Intn(nvars), rng.Intn(nvars)
+		if got, want := table(m, m.Exists(f, []int{v, w})), exists(exists(ft, v), w); got != want {
+			t.Fatalf("Exists %x, want %x", got, want)
+		}
+		if got, want := table(m, m.Forall(f, []int{v})), ^exists(^ft, v); got != want {
+			t.Fatalf("Forall %x, want %x", got, want)
+		}
+		g, gt := random(m, rng, 3)
+		if got, want := m.AndExists(f, g, []int{v, w}), m.Exists(m.And(f, g), []int{v, w}); got != want {
+			t.Fatalf("AndExists differs from Exists of And (%x)", ft&gt)
+		}
+		one := varTable(v)
+		if got, want := table(m, m.Restrict(f, v, true)), ft&one|(ft&one)>>(1<<v); got != want {
+			t.Fatalf("Restrict %x, want %x", got, want)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	rng := rand.New(rand.NewSource(4545))
+	m := New()
+	for i := 0; i < 500; i++ {
+		f, _ := random(m, rng, 4)
+		perm := rng.Perm(nvars)
+		mapping := make(map[int]int)
+		for from, to := range perm {
+			mapping[from] = to
+		}
+		r := m.Rename(f, mapping)
+		for a := 0; a < 64; a++ {
+			want := m.Eval(f, func(v int) bool { return a>>perm[v]&1 == 1 })
+			if got := m.Eval(r, func(v int) bool { return a>>v&1 == 1 }); got != want {
+				t.Fatalf("rename by %v differs at %b", perm, a)
+			}
+		}
+	}
+}
+
+func TestAllSatAndSupport(t *testing.T) {
+	m := New()
+	// x0 xor x2, enumerated over x0, x1, x2.
+	f := m.Xor(m.Var(0), m.Var(2))
+	var got []string
+	m.AllSat(f, []int{2, 0, 1}, func(values []bool) bool {
+		s := ""
+		for _, b := range values {
+			if b {
+				s += "1"
+			} else {
+				s += "0"
+			}
+		}
+		got = append(got, s)
+		return true
+	})
+	// values are indexed like the vars argument: x2 x0 x1.
+	want := []string{"100", "101", "010", "011"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if c := m.SatCount(f, []int{0, 1, 2}); c != 4 {
+		t.Fatalf("SatCount %v, want 4", c)
+	}
+	if s := m.Support(f); len(s) != 2 || s[0] != 0 || s[1] != 2 {
+		t.Fatalf("Support %v", s)
+	}
+	n := 0
+	m.AllSat(True, []int{0, 1, 2}, func([]bool) bool { n++; return n < 3 })
+	if n != 3 {
+		t.Fatalf("AllSat did not stop: %d calls", n)
+	}
+	if v, lo, hi := m.Inspect(f); v != 0 || lo != m.Var(2) || hi != m.NVar(2) {
+		t.Fatalf("Inspect: %d %d %d", v, lo, hi)
+	}
+}
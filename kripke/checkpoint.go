@@ -0,0 +1,136 @@
+package kripke
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// checkpointData is everything needed to continue an exploration: the
+// partial graph, the frontier as step traces from the initial World, and
+// (for stores that don't persist themselves) the visited set. Persistent
+// records which of the two kinds of store the run used.
+type checkpointData struct {
+	Graph      graphData
+	Frontier   []checkpointItem
+	Expanded   int
+	Persistent bool
+	Visited    map[string]StateID
+}
+
+type checkpointItem struct {
+	ID    StateID
+	Trace []int
+}
+
+// storeEnumerator is implemented by in-memory stores whose keys have to be
+// copied into the checkpoint file.
+type storeEnumerator interface {
+	Each(fn func(key string, id StateID) error) error
+}
+
+// checkpoint atomically replaces the checkpoint file with the current
+// exploration state. It is only called between expansions, so every state
+// in the graph is either fully expanded or on the frontier.
+func (e *explorer) checkpoint() error {
+	cp := checkpointData{
+		Graph:    e.g.data(),
+		Frontier: make([]checkpointItem, len(e.queue)),
+		Expanded: e.expanded,
+	}
+	for i, item := range e.queue {
+		cp.Frontier[i] = checkpointItem{ID: item.id, Trace: item.trace}
+	}
+	switch s := e.opts.store.(type) {
+	case PersistentStore:
+		if err := s.Sync(); err != nil {
+			return err
+		}
+		cp.Persistent = true
+	case storeEnumerator:
+		cp.Visited = make(map[string]StateID, e.opts.store.Len())
+		err := s.Each(func(key string, id StateID) error {
+			cp.Visited[key] = id
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("checkpoint: state store %T can be neither synced nor enumerated", e.opts.store)
+	}
+
+	path := e.opts.checkpointPath
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(&cp); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// ErrStoreMismatch is returned by Resume when the state store it was given
+// is not the kind, or not the store, the interrupted run used.
+var ErrStoreMismatch = errors.New("state store does not match the checkpoint")
+
+// Resume continues an exploration from the checkpoint at path. w must be the
+// same initial World that was passed to Explore: frontier states are rebuilt
+// by replaying their traces from it. When the interrupted run used a
+// PersistentStore, pass the same store (reopened) with WithStateStore;
+// Resume fails with ErrStoreMismatch when the store does not match the
+// checkpoint.
+//
+// Given the same options, the result is identical to an uninterrupted run.
+func Resume(w *World, path string, options ...ExploreOption) (*Graph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpointData
+	err = gob.NewDecoder(f).Decode(&cp)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Resume: %s: %w", path, err)
+	}
+
+	e := newExplorer(options)
+	if e.root, err = w.Clone(); err != nil {
+		return nil, err
+	}
+	if e.g, err = graphFromData(cp.Graph); err != nil {
+		return nil, fmt.Errorf("Resume: %s: %w", path, err)
+	}
+	e.expanded = cp.Expanded
+	_, persistent := e.opts.store.(PersistentStore)
+	switch {
+	case cp.Persistent && !persistent:
+		return nil, fmt.Errorf("Resume: %w: %s was written with a persistent store, and this one is in memory",
+			ErrStoreMismatch, path)
+	case !cp.Persistent && persistent:
+		return nil, fmt.Errorf("Resume: %w: %s holds its visited set, and this store is persistent",
+			ErrStoreMismatch, path)
+	case persistent && e.opts.store.Len() < len(e.g.States()):
+		return nil, fmt.Errorf("Resume: %w: the store holds %d states, the checkpoint %d",
+			ErrStoreMismatch, e.opts.store.Len(), len(e.g.States()))
+	}
+	for key, id := range cp.Visited {
+		if err := e.opts.store.Insert(key, id); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range cp.Frontier {
+		e.queue = append(e.queue, frontierItem{id: item.ID, trace: item.Trace})
+	}
+	return e.g, e.run()
+}
@@ -0,0 +1,237 @@
+package kripke
+
+import "fmt"
+
+// ---------- Composition ----------
+//
+// The compositions below build the part of the product of two graphs
+// reachable from the pairs of their initial states. A product state is
+// named "(a,b)" after its components and carries both components' labels
+// and variables. Without WithComponentNames the labels are merged (a label
+// holds if it holds in either component) and a variable set in both
+// components is an error; with it every label and variable is prefixed
+// with its component's name, so "accepted" of component "order" becomes
+// "order.accepted".
+
+// ComposeOption configures a composition.
+type ComposeOption func(*composeOptions)
+
+type composeOptions struct {
+	nameA, nameB string
+}
+
+// WithComponentNames prefixes the labels and variables of a and b with
+// "<nameA>." and "<nameB>.".
+func WithComponentNames(nameA, nameB string) ComposeOption {
+	return func(opts *composeOptions) {
+		opts.nameA, opts.nameB = nameA, nameB
+	}
+}
+
+// composeEdge is one move of the product: the successor pair and its
+// label.
+type composeEdge struct {
+	to    [2]StateID
+	label EdgeLabel
+}
+
+// SyncProduct is the synchronous product: both components move on every
+// step, so (a,b) → (a',b') whenever a → a' and b → b'. A pair deadlocks
+// when either component does. When both graphs are probabilistic the
+// probabilities multiply. The edge labels of the two moves are combined:
+// actions and processes as "x|y" unless they are equal, guards with &&.
+func SyncProduct(a, b *Graph, options ...ComposeOption) (*Graph, error) {
+	probabilistic := a.IsProbabilistic() && b.IsProbabilistic()
+	return compose(a, b, options, func(s [2]StateID) []composeEdge {
+		var out []composeEdge
+		for ea := range a.EdgesFrom(s[0]) {
+			for eb := range b.EdgesFrom(s[1]) {
+				l := joinLabels(ea.EdgeLabel, eb.EdgeLabel)
+				if probabilistic {
+					l.Prob = ea.Prob * eb.Prob
+				}
+				out = append(out, composeEdge{to: [2]StateID{ea.To, eb.To}, label: l})
+			}
+		}
+		return out
+	})
+}
+
+// Interleave is asynchronous interleaving: exactly one component moves on
+// each step, so (a,b) → (a',b) and (a,b) → (a,b'). Probabilities are
+// dropped, since which component moves is a nondeterministic choice.
+func Interleave(a, b *Graph, options ...ComposeOption) (*Graph, error) {
+	return compose(a, b, options, func(s [2]StateID) []composeEdge {
+		return interleaved(a, b, s, nil)
+	})
+}
+
+// Parallel synchronizes on shared actions: an action that labels edges of
+// both graphs can only be taken by both components together, while every
+// other edge, including those without an action, interleaves. This is the
+// parallel composition of process algebra (CSP's alphabetized parallel),
+// e.g. an order and a payment process synchronizing on "pay".
+func Parallel(a, b *Graph, options ...ComposeOption) (*Graph, error) {
+	shared := make(map[string]bool)
+	inA := alphabet(a)
+	for act := range alphabet(b) {
+		if inA[act] {
+			shared[act] = true
+		}
+	}
+	return compose(a, b, options, func(s [2]StateID) []composeEdge {
+		out := interleaved(a, b, s, shared)
+		for ea := range a.EdgesFrom(s[0]) {
+			if !shared[ea.Action] {
+				continue
+			}
+			for eb := range b.EdgesFrom(s[1]) {
+				if eb.Action == ea.Action {
+					out = append(out, composeEdge{to: [2]StateID{ea.To, eb.To}, label: joinLabels(ea.EdgeLabel, eb.EdgeLabel)})
+				}
+			}
+		}
+		return out
+	})
+}
+
+// interleaved lists the moves of one component alone, keeping its edge
+// label without the probability and skipping edges whose action is in
+// 'shared'.
+func interleaved(a, b *Graph, s [2]StateID, shared map[string]bool) []composeEdge {
+	var out []composeEdge
+	for e := range a.EdgesFrom(s[0]) {
+		if !shared[e.Action] {
+			e.Prob = 0
+			out = append(out, composeEdge{to: [2]StateID{e.To, s[1]}, label: e.EdgeLabel})
+		}
+	}
+	for e := range b.EdgesFrom(s[1]) {
+		if !shared[e.Action] {
+			e.Prob = 0
+			out = append(out, composeEdge{to: [2]StateID{s[0], e.To}, label: e.EdgeLabel})
+		}
+	}
+	return out
+}
+
+func alphabet(g *Graph) map[string]bool {
+	out := make(map[string]bool)
+	for _, ls := range g.elabels {
+		for _, l := range ls {
+			if l.Action != "" {
+				out[l.Action] = true
+			}
+		}
+	}
+	return out
+}
+
+// joinLabels combines the labels of two simultaneous moves, without
+// probability.
+func joinLabels(x, y EdgeLabel) EdgeLabel {
+	join := func(x, y, sep string) string {
+		switch {
+		case x == y || y == "":
+			return x
+		case x == "":
+			return y
+		}
+		return x + sep + y
+	}
+	return EdgeLabel{
+		Action:  join(x.Action, y.Action, "|"),
+		Guard:   join(x.Guard, y.Guard, " && "),
+		Process: join(x.Process, y.Process, "|"),
+	}
+}
+
+// compose explores the product breadth-first from the initial pairs.
+func compose(a, b *Graph, options []ComposeOption, moves func([2]StateID) []composeEdge) (*Graph, error) {
+	opts := &composeOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	g := NewGraph()
+	name := func(s [2]StateID) string {
+		return "(" + a.NameOf(s[0]) + "," + b.NameOf(s[1]) + ")"
+	}
+	var queue [][2]StateID
+	seen := make(map[[2]StateID]bool)
+	visit := func(s [2]StateID) error {
+		if seen[s] {
+			return nil
+		}
+		seen[s] = true
+		n := name(s)
+		if _, ok := g.nameToID[n]; ok {
+			return fmt.Errorf("compose: two product states are named %s", n)
+		}
+		lbls, vars, err := opts.merge(a, b, s)
+		if err != nil {
+			return fmt.Errorf("compose: state %s: %w", n, err)
+		}
+		id := g.AddState(n, lbls)
+		if len(vars) > 0 {
+			g.SetVars(id, vars)
+		}
+		queue = append(queue, s)
+		return nil
+	}
+	for _, ia := range a.InitialStates() {
+		for _, ib := range b.InitialStates() {
+			s := [2]StateID{ia, ib}
+			if err := visit(s); err != nil {
+				return nil, err
+			}
+			g.SetInitial(name(s))
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, e := range moves(s) {
+			if err := visit(e.to); err != nil {
+				return nil, err
+			}
+			g.AddLabeledEdge(name(s), name(e.to), e.label)
+		}
+	}
+	return g, nil
+}
+
+// merge combines the labels and variables of the pair s.
+func (opts *composeOptions) merge(a, b *Graph, s [2]StateID) (map[string]bool, map[string]any, error) {
+	lbls := make(map[string]bool)
+	vars := make(map[string]any)
+	prefixed := opts.nameA != "" || opts.nameB != ""
+	add := func(g *Graph, id StateID, prefix string) error {
+		if prefixed {
+			prefix += "."
+		} else {
+			prefix = ""
+		}
+		for _, k := range sortedKeys(g.labels[id]) {
+			lbls[prefix+k] = lbls[prefix+k] || g.labels[id][k]
+		}
+		for _, k := range sortedKeys(g.vars[id]) {
+			if _, clash := vars[prefix+k]; clash {
+				return fmt.Errorf("variable %s is set in both components; use WithComponentNames", k)
+			}
+			vars[prefix+k] = g.vars[id][k]
+		}
+		return nil
+	}
+	if err := add(a, s[0], opts.nameA); err != nil {
+		return nil, nil, err
+	}
+	if err := add(b, s[1], opts.nameB); err != nil {
+		return nil, nil, err
+	}
+	return lbls, vars, nil
+}
+
+// Alphabet returns the sorted set of non-empty edge actions of g.
+func (g *Graph) Alphabet() []string {
+	return sortedKeys(alphabet(g))
+}
@@ -0,0 +1,140 @@
+package kripke
+
+import (
+	"reflect"
+	"testing"
+)
+
+// orderWithActions is OrderGraph with actions: accept, then pay (which
+// delivers) or cancel.
+func orderWithActions() *Graph {
+	g := NewGraph()
+	g.AddState("New", nil)
+	g.AddState("Accepted", map[string]bool{"accepted": true})
+	g.AddState("Delivered", map[string]bool{"accepted": true, "delivered": true})
+	g.AddState("Cancelled", map[string]bool{"cancelled": true})
+	g.AddLabeledEdge("New", "Accepted", EdgeLabel{Action: "accept"})
+	g.AddLabeledEdge("Accepted", "Delivered", EdgeLabel{Action: "pay"})
+	g.AddLabeledEdge("Accepted", "Cancelled", EdgeLabel{Action: "cancel"})
+	g.AddEdge("Delivered", "Delivered")
+	g.AddEdge("Cancelled", "Cancelled")
+	g.SetInitial("New")
+	return g
+}
+
+// paymentGraph charges a card and then takes the payment.
+func paymentGraph() *Graph {
+	g := NewGraph()
+	g.AddState("Idle", nil)
+	g.AddState("Authorized", nil)
+	g.AddState("Paid", map[string]bool{"paid": true})
+	g.AddLabeledEdge("Idle", "Authorized", EdgeLabel{Action: "authorize"})
+	g.AddLabeledEdge("Authorized", "Paid", EdgeLabel{Action: "pay"})
+	g.AddEdge("Paid", "Paid")
+	g.SetInitial("Idle")
+	return g
+}
+
+func TestParallelSynchronizesSharedActions(t *testing.T) {
+	a, b := orderWithActions(), paymentGraph()
+	if got := b.Alphabet(); !reflect.DeepEqual(got, []string{"authorize", "pay"}) {
+		t.Fatalf("alphabet %v", got)
+	}
+	g, err := Parallel(a, b, WithComponentNames("order", "payment"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{
+		"AG (order.delivered -> payment.paid)",
+		"AG (payment.paid -> order.delivered)",
+		"EF order.delivered",
+		"EF (order.cancelled & EG !payment.paid)",
+	} {
+		if !holdsInitially(t, g, src) {
+			t.Fatalf("%s does not hold in the parallel composition", src)
+		}
+	}
+	id, ok := g.StateByName("(Accepted,Authorized)")
+	if !ok {
+		t.Fatalf("missing product state")
+	}
+	var pay []string
+	for i, to := range g.Succ(id) {
+		if g.Actions(id)[i] == "pay" {
+			pay = append(pay, g.NameOf(to))
+		}
+	}
+	if !reflect.DeepEqual(pay, []string{"(Delivered,Paid)"}) {
+		t.Fatalf("pay should move both components: %v", pay)
+	}
+
+	// Without synchronization the order can be delivered before payment.
+	g, err = Interleave(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holdsInitially(t, g, "AG (delivered -> paid)") {
+		t.Fatalf("interleaving should allow delivery before payment")
+	}
+	if n := len(g.States()); n != 4*3 {
+		t.Fatalf("interleaving has %d states, want 12", n)
+	}
+}
+
+func TestSyncProduct(t *testing.T) {
+	// Two coins; the product moves both at once.
+	coin := func(p float64) *Graph {
+		g := NewGraph()
+		g.AddState("H", map[string]bool{"heads": true})
+		g.AddState("T", nil)
+		g.AddEdgeProb("H", "H", p)
+		g.AddEdgeProb("H", "T", 1-p)
+		g.AddEdgeProb("T", "H", p)
+		g.AddEdgeProb("T", "T", 1-p)
+		g.SetInitial("H")
+		return g
+	}
+	g, err := SyncProduct(coin(0.5), coin(0.25), WithComponentNames("x", "y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(g.States()); n != 4 {
+		t.Fatalf("got %d states", n)
+	}
+	s, _ := g.StateByName("(H,H)")
+	sum := 0.0
+	for _, p := range g.Probs(s) {
+		sum += p
+	}
+	if len(g.Probs(s)) != 4 || sum != 1 || g.Probs(s)[0] != 0.125 {
+		t.Fatalf("probabilities %v", g.Probs(s))
+	}
+	if !holdsInitially(t, g, "x.heads & y.heads & EX (x.heads & !y.heads)") {
+		t.Fatalf("labels not prefixed")
+	}
+
+	// A deadlocked component stops the product.
+	a := NewGraph()
+	a.AddEdge("a0", "a1")
+	a.SetInitial("a0")
+	g, _ = SyncProduct(a, coin(0.5))
+	if s, _ := g.StateByName("(a1,H)"); len(g.Succ(s)) != 0 {
+		t.Fatalf("(a1,H) should deadlock")
+	}
+}
+
+func TestComposeVariableClash(t *testing.T) {
+	a := NewGraph()
+	a.SetVars(a.AddState("a", nil), map[string]any{"n": 1})
+	a.SetInitial("a")
+	b := NewGraph()
+	b.SetVars(b.AddState("b", nil), map[string]any{"n": 2})
+	b.SetInitial("b")
+	if _, err := Interleave(a, b); err == nil {
+		t.Fatalf("expected a clash on n")
+	}
+	g, err := Interleave(a, b, WithComponentNames("A", "B"))
+	if err != nil || !holdsInitially(t, g, "A.n == 1 & B.n == 2") {
+		t.Fatalf("prefixed variables: %v", err)
+	}
+}
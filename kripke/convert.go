@@ -0,0 +1,192 @@
+package kripke
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+)
+
+// Kripke is implemented by both graph types, so code that takes a Kripke
+// accepts either: the CTL checker works on k.ToGraph() and the Mermaid
+// writers on k.ToSimpleGraph(). The conversions are lossless; a Graph
+// converted to a SimpleGraph and back has the same states (in StateID
+// order), labels, variables, edges, probabilities, edge labels and
+// initial states.
+type Kripke interface {
+	ToGraph() *Graph
+	ToSimpleGraph() *SimpleGraph
+}
+
+// ToGraph returns g itself.
+func (g *Graph) ToGraph() *Graph { return g }
+
+// ToSimpleGraph returns g keyed by state name.
+func (g *Graph) ToSimpleGraph() *SimpleGraph {
+	d := g.data()
+	sg := &SimpleGraph{
+		States:  make([]NodeID, len(d.Names)),
+		Succ:    make(map[NodeID][]NodeID, len(d.Names)),
+		Initial: make([]NodeID, len(d.Init)),
+		Labels:  make(map[NodeID]map[string]bool),
+		Vars:    make(map[NodeID]map[string]any),
+		Probs:   make(map[NodeID][]float64),
+
+		EdgeLabels: make(map[NodeID][]EdgeLabel),
+	}
+	for i, name := range d.Names {
+		n := NodeID(name)
+		sg.States[i] = n
+		if len(d.Labels[i]) > 0 {
+			sg.Labels[n] = copyMap(d.Labels[i])
+		}
+		if len(d.Vars[i]) > 0 {
+			sg.Vars[n] = copyMap(d.Vars[i])
+		}
+		if len(d.Succ[i]) > 0 {
+			succ := make([]NodeID, len(d.Succ[i]))
+			for j, t := range d.Succ[i] {
+				succ[j] = NodeID(d.Names[t])
+			}
+			sg.Succ[n] = succ
+		}
+		if d.Probs[i] != nil {
+			sg.Probs[n] = append([]float64(nil), d.Probs[i]...)
+		}
+		if d.EdgeLabels[i] != nil {
+			sg.EdgeLabels[n] = append([]EdgeLabel(nil), d.EdgeLabels[i]...)
+		}
+	}
+	for i, s := range d.Init {
+		sg.Initial[i] = NodeID(d.Names[s])
+	}
+	return sg
+}
+
+// ToSimpleGraph returns sg itself.
+func (sg *SimpleGraph) ToSimpleGraph() *SimpleGraph { return sg }
+
+// ToGraph builds the CTL graph of sg. States keep their order; nodes that
+// only appear in Succ follow in sorted order. When sg.Labels is nil every
+// node is labelled with its own name, so "AF UniqueKey" can be checked on
+// a plain diagram graph.
+//
+// sg.Probs and sg.EdgeLabels of a node must be nil or as long as its
+// sg.Succ; ToGraph panics otherwise, and Validate reports it as an error.
+func (sg *SimpleGraph) ToGraph() *Graph {
+	if err := sg.Validate(); err != nil {
+		panic("kripke: " + err.Error())
+	}
+	g := NewGraph()
+	add := func(n NodeID) {
+		if _, ok := g.nameToID[string(n)]; ok {
+			return
+		}
+		lbls := map[string]bool{string(n): true}
+		if sg.Labels != nil {
+			lbls = copyMap(sg.Labels[n])
+		}
+		id := g.AddState(string(n), lbls)
+		if vs := sg.Vars[n]; len(vs) > 0 {
+			g.SetVars(id, vs)
+		}
+	}
+	for _, n := range sg.States {
+		add(n)
+	}
+	var extra []NodeID
+	for from, succ := range sg.Succ {
+		extra = append(extra, from)
+		extra = append(extra, succ...)
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i] < extra[j] })
+	for _, n := range extra {
+		add(n)
+	}
+	for _, s := range g.States() {
+		from := NodeID(g.NameOf(s))
+		probs, labels := sg.Probs[from], sg.EdgeLabels[from]
+		for j, to := range sg.Succ[from] {
+			var l EdgeLabel
+			if labels != nil {
+				l = labels[j]
+				l.Prob = 0
+			}
+			if probs != nil {
+				g.addEdgeProbLabel(string(from), string(to), probs[j], l)
+			} else {
+				g.AddLabeledEdge(string(from), string(to), l)
+			}
+		}
+	}
+	for _, n := range sg.Initial {
+		add(n)
+		g.SetInitial(string(n))
+	}
+	return g
+}
+
+// Validate checks that the probabilities and edge labels of every node
+// are parallel to its successors.
+func (sg *SimpleGraph) Validate() error {
+	for _, from := range slices.Sorted(maps.Keys(sg.Succ)) {
+		n := len(sg.Succ[from])
+		if ps := sg.Probs[from]; ps != nil && len(ps) != n {
+			return fmt.Errorf("SimpleGraph: node %q: %d probabilities for %d edges", from, len(ps), n)
+		}
+		if ls := sg.EdgeLabels[from]; ls != nil && len(ls) != n {
+			return fmt.Errorf("SimpleGraph: node %q: %d edge labels for %d edges", from, len(ls), n)
+		}
+	}
+	return nil
+}
+
+func copyMap[V any](m map[string]V) map[string]V {
+	out := make(map[string]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// ---------- ModelSpec ----------
+
+// ModelGraph builds the CTL graph of a ModelSpec, with the initial node
+// returned by BuildGraph marked initial.
+func ModelGraph(spec ModelSpec) *Graph {
+	sg, initial := spec.BuildGraph()
+	if initial != "" && !slices.Contains(sg.Initial, initial) {
+		cp := *sg
+		cp.Initial = append([]NodeID{initial}, sg.Initial...)
+		sg = &cp
+	}
+	return sg.ToGraph()
+}
+
+// SpecResult is the outcome of one CTLSpec: the formula holds when every
+// initial state satisfies it.
+type SpecResult struct {
+	Spec  CTLSpec
+	Holds bool
+	Sat   StateSet
+}
+
+// CheckModelSpec parses and checks every CTL formula of spec against
+// ModelGraph(spec).
+func CheckModelSpec(spec ModelSpec) ([]SpecResult, error) {
+	g := ModelGraph(spec)
+	var out []SpecResult
+	for _, cs := range spec.CTLFormulas() {
+		f, err := ParseCTL(cs.Formula)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", spec.Name(), cs.Name, err)
+		}
+		sat := f.Sat(g)
+		holds := len(g.InitialStates()) > 0
+		for _, s := range g.InitialStates() {
+			holds = holds && sat.Contains(s)
+		}
+		out = append(out, SpecResult{Spec: cs, Holds: holds, Sat: sat})
+	}
+	return out, nil
+}
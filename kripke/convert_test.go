@@ -0,0 +1,79 @@
+package kripke
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGraphSimpleGraphRoundTrip(t *testing.T) {
+	for _, g := range []*Graph{richGraph(), OrderGraph(), NewGraph()} {
+		back := g.ToSimpleGraph().ToGraph()
+		if !reflect.DeepEqual(normalizeData(back.data()), normalizeData(g.data())) {
+			t.Fatalf("round trip mismatch\n got %+v\nwant %+v", back.data(), g.data())
+		}
+	}
+
+	sg, initial := BuildPurpleGraph()
+	sg.Initial = []NodeID{initial}
+	back := sg.ToGraph().ToSimpleGraph()
+	if !reflect.DeepEqual(back.States, sg.States) || !reflect.DeepEqual(back.Initial, sg.Initial) ||
+		!reflect.DeepEqual(back.Labels, sg.Labels) {
+		t.Fatalf("round trip mismatch\n got %+v\nwant %+v", back, sg)
+	}
+	for _, n := range sg.States {
+		if len(back.Succ[n]) != len(sg.Succ[n]) {
+			t.Fatalf("%s: successors %v, want %v", n, back.Succ[n], sg.Succ[n])
+		}
+	}
+}
+
+func TestSimpleGraphNamesAreLabels(t *testing.T) {
+	sg := &SimpleGraph{
+		States:  []NodeID{"Start"},
+		Succ:    map[NodeID][]NodeID{"Start": {"Work"}, "Work": {"Work", "Done"}},
+		Initial: []NodeID{"Start"},
+	}
+	g := sg.ToGraph()
+	if len(g.States()) != 3 || g.NameOf(1) != "Done" {
+		t.Fatalf("nodes only named in Succ should follow in sorted order: %+v", g.data())
+	}
+	if !holdsInitially(t, g, "EF Done & !AF Done & AX Work") {
+		t.Fatalf("node names should act as labels")
+	}
+}
+
+func TestMermaidFromGraph(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMermaidStateDiagram(OrderGraph().ToSimpleGraph(), "", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "[*] --> s0") || !strings.Contains(buf.String(), "s0 --> s1") {
+		t.Fatalf("unexpected diagram:\n%s", buf.String())
+	}
+}
+
+func TestSimpleGraphMismatchedLengths(t *testing.T) {
+	for _, sg := range []*SimpleGraph{
+		{States: []NodeID{"a"}, Succ: map[NodeID][]NodeID{"a": {"a", "b"}}, Probs: map[NodeID][]float64{"a": {1}}},
+		{States: []NodeID{"a"}, Succ: map[NodeID][]NodeID{"a": {"b"}}, EdgeLabels: map[NodeID][]EdgeLabel{"a": {{}, {}}}},
+	} {
+		err := sg.Validate()
+		if err == nil || !strings.Contains(err.Error(), `node "a"`) {
+			t.Fatalf("Validate: %v", err)
+		}
+		if err := WriteMermaidStateDiagram(sg, "", new(bytes.Buffer)); err == nil {
+			t.Fatalf("WriteMermaidStateDiagram accepted %+v", sg)
+		}
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), err.Error()) {
+					t.Fatalf("ToGraph: recovered %v, want %v", r, err)
+				}
+			}()
+			sg.ToGraph()
+		}()
+	}
+}
@@ -1,5 +1,7 @@
 package kripke
 
+import "fmt"
+
 // ---------- Core Kripke Graph Types ----------
 
 type StateID int
@@ -39,13 +41,17 @@ func (s StateSet) Equal(other StateSet) bool {
 }
 
 // Graph is a finite Kripke structure: states, initial states,
-// successor edges, and atomic proposition labels.
+// successor edges, atomic proposition labels, and typed variable
+// valuations for relational propositions.
 type Graph struct {
 	nextID   int
 	nameToID map[string]StateID
 	idToName map[StateID]string
 	labels   map[StateID]map[string]bool
+	vars     map[StateID]map[string]any
 	succ     map[StateID][]StateID
+	prob     map[StateID][]float64   // parallel to succ; nil if never set
+	elabels  map[StateID][]EdgeLabel // parallel to succ; nil if never set
 	init     []StateID
 }
 
@@ -56,7 +62,10 @@ func NewGraph() *Graph {
 		nameToID: make(map[string]StateID),
 		idToName: make(map[StateID]string),
 		labels:   make(map[StateID]map[string]bool),
+		vars:     make(map[StateID]map[string]any),
 		succ:     make(map[StateID][]StateID),
+		prob:     make(map[StateID][]float64),
+		elabels:  make(map[StateID][]EdgeLabel),
 		init:     make([]StateID, 0),
 	}
 }
@@ -92,6 +101,27 @@ func (g *Graph) AddEdge(fromName, toName string) {
 	from := g.ensureState(fromName)
 	to := g.ensureState(toName)
 	g.succ[from] = append(g.succ[from], to)
+	if g.prob[from] != nil {
+		g.prob[from] = append(g.prob[from], 0)
+	}
+	if g.elabels[from] != nil {
+		g.elabels[from] = append(g.elabels[from], EdgeLabel{})
+	}
+}
+
+// AddEdgeProb adds a transition taken with probability p. Edges of a
+// state added without a probability count as probability 0.
+func (g *Graph) AddEdgeProb(fromName, toName string, p float64) {
+	from := g.ensureState(fromName)
+	to := g.ensureState(toName)
+	if g.prob[from] == nil {
+		g.prob[from] = make([]float64, len(g.succ[from]), len(g.succ[from])+1)
+	}
+	g.succ[from] = append(g.succ[from], to)
+	g.prob[from] = append(g.prob[from], p)
+	if g.elabels[from] != nil {
+		g.elabels[from] = append(g.elabels[from], EdgeLabel{})
+	}
 }
 
 // SetInitial marks a named state as initial.
@@ -121,10 +151,58 @@ func (g *Graph) Succ(s StateID) []StateID {
 	return g.succ[s]
 }
 
+// Probs returns the probability of each edge in Succ(s), or nil when no
+// edge of s was added with AddEdgeProb.
+func (g *Graph) Probs(s StateID) []float64 {
+	return g.prob[s]
+}
+
+// IsProbabilistic reports whether any edge carries a probability.
+func (g *Graph) IsProbabilistic() bool {
+	return len(g.prob) > 0
+}
+
 // HasLabel checks if state s has atomic proposition 'prop'.
+// A bool variable of the same name counts as a label.
 func (g *Graph) HasLabel(s StateID, prop string) bool {
 	lbls := g.labels[s]
-	return lbls[prop]
+	if v, ok := lbls[prop]; ok {
+		return v
+	}
+	b, _ := g.vars[s][prop].(bool)
+	return b
+}
+
+// SetVars sets typed variable values on state s. Values must be ints
+// (any integer type, stored as int), strings or bools.
+func (g *Graph) SetVars(s StateID, vars map[string]any) {
+	if g.vars[s] == nil {
+		g.vars[s] = make(map[string]any, len(vars))
+	}
+	for name, v := range vars {
+		nv, ok := normalizeValue(v)
+		if !ok {
+			panic(fmt.Sprintf("SetVars: variable %s has unsupported type %T", name, v))
+		}
+		g.vars[s][name] = nv
+	}
+}
+
+// Var returns the value of variable 'name' in state s.
+func (g *Graph) Var(s StateID, name string) (any, bool) {
+	v, ok := g.vars[s][name]
+	return v, ok
+}
+
+// Vars returns the variable valuation of state s (nil if it has none).
+func (g *Graph) Vars(s StateID) map[string]any {
+	return g.vars[s]
+}
+
+// StateByName returns the ID of the state called name.
+func (g *Graph) StateByName(name string) (StateID, bool) {
+	id, ok := g.nameToID[name]
+	return id, ok
 }
 
 // NameOf returns the human-readable name of a state.
@@ -158,6 +236,28 @@ func (a AtomFormula) Sat(g *Graph) StateSet {
 	return res
 }
 
+// ----- constants -----
+
+type TrueFormula struct{}
+
+// True holds in every state.
+func True() Formula {
+	return TrueFormula{}
+}
+
+func (TrueFormula) Sat(g *Graph) StateSet {
+	res := NewStateSet()
+	for _, s := range g.States() {
+		res.Add(s)
+	}
+	return res
+}
+
+// False holds in no state.
+func False() Formula {
+	return Not(True())
+}
+
 // ----- boolean connectives -----
 
 type NotFormula struct {
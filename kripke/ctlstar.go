@@ -0,0 +1,321 @@
+package kripke
+
+import "fmt"
+
+// ---------- CTL* ----------
+//
+// CTL* lets the path quantifiers E and A govern any LTL path formula built
+// from state formulas with !, &, |, Next, Until and the derived
+// Eventually, Globally and Release, e.g. "there is a path where infinitely
+// often p and eventually always q":
+//
+//	E(AndPath(Globally(Eventually(Now(p))), Eventually(Globally(Now(q)))))
+//
+// Paths are infinite, so E φ is false in a state all of whose paths end in
+// a deadlock, and A φ is true there. (CTL's EX, EF and EU only need a
+// finite prefix; on a graph without deadlocks E(Next(φ)) and EX φ agree.)
+//
+// E φ is checked with the LTL tableau of φ: the state subformulas at its
+// leaves are evaluated first, then every state is paired with a guess at
+// which Next and Until subformulas hold there. Pairs follow the graph's
+// edges where the guesses are consistent, and E φ holds where some pair
+// satisfying φ reaches a strongly connected set of pairs that fulfils
+// every Until it promises. The product has 2^k copies of the graph for k
+// Next and Until subformulas.
+
+// PathFormula is an LTL formula over the state formulas at its leaves.
+type PathFormula interface {
+	isPath()
+}
+
+// PathState holds on a path whose first state satisfies State.
+type PathState struct {
+	State Formula
+}
+
+type PathNot struct {
+	Inner PathFormula
+}
+
+type PathAnd struct {
+	Left, Right PathFormula
+}
+
+type PathOr struct {
+	Left, Right PathFormula
+}
+
+// PathNext holds on a path whose suffix from the second state satisfies
+// Inner.
+type PathNext struct {
+	Inner PathFormula
+}
+
+// PathUntil holds on a path where Right eventually holds and Left holds
+// on every suffix before that.
+type PathUntil struct {
+	Left, Right PathFormula
+}
+
+func (PathState) isPath() {}
+func (PathNot) isPath()   {}
+func (PathAnd) isPath()   {}
+func (PathOr) isPath()    {}
+func (PathNext) isPath()  {}
+func (PathUntil) isPath() {}
+
+// Now lifts a state formula to a path formula about the first state.
+func Now(f Formula) PathFormula { return PathState{State: f} }
+
+func NotPath(p PathFormula) PathFormula { return PathNot{Inner: p} }
+
+func AndPath(l, r PathFormula) PathFormula { return PathAnd{Left: l, Right: r} }
+
+func OrPath(l, r PathFormula) PathFormula { return PathOr{Left: l, Right: r} }
+
+// ImpliesPath(p, q) = OrPath(NotPath(p), q).
+func ImpliesPath(p, q PathFormula) PathFormula { return OrPath(NotPath(p), q) }
+
+// Next is X φ.
+func Next(p PathFormula) PathFormula { return PathNext{Inner: p} }
+
+// Until is φ U ψ.
+func Until(p, q PathFormula) PathFormula { return PathUntil{Left: p, Right: q} }
+
+// Eventually is F φ = true U φ.
+func Eventually(p PathFormula) PathFormula { return Until(Now(True()), p) }
+
+// Globally is G φ = ¬F ¬φ.
+func Globally(p PathFormula) PathFormula { return NotPath(Eventually(NotPath(p))) }
+
+// Release is φ R ψ = ¬(¬φ U ¬ψ): ψ holds up to and including the first
+// state where φ does, or forever.
+func Release(p, q PathFormula) PathFormula { return NotPath(Until(NotPath(p), NotPath(q))) }
+
+// ----- path quantifiers -----
+
+type EFormula struct {
+	Path PathFormula
+}
+
+// E holds in states with some path satisfying p.
+func E(p PathFormula) Formula { return EFormula{Path: p} }
+
+func (f EFormula) Sat(g *Graph) StateSet {
+	return newTableau(f.Path).sat(g)
+}
+
+type AFormula struct {
+	Path PathFormula
+}
+
+// A holds in states all of whose paths satisfy p.
+func A(p PathFormula) Formula { return AFormula{Path: p} }
+
+func (f AFormula) Sat(g *Graph) StateSet {
+	// A φ = ¬E ¬φ
+	return Not(E(NotPath(f.Path))).Sat(g)
+}
+
+// ----- LTL tableau -----
+
+type ltlOp int
+
+const (
+	ltlLeaf ltlOp = iota
+	ltlNot
+	ltlAnd
+	ltlOr
+	ltlNext
+	ltlUntil
+)
+
+// ltlNode is a hash-consed path subformula. leaf indexes tableau.leaves;
+// bit indexes the guess bits for Next and Until nodes.
+type ltlNode struct {
+	op          ltlOp
+	left, right *ltlNode
+	leaf, bit   int
+}
+
+// maxTableauBits bounds the number of Next and Until subformulas, since
+// the product has 2^bits copies of the graph.
+const maxTableauBits = 20
+
+type tableau struct {
+	root   *ltlNode
+	leaves []Formula
+	nodes  map[string]*ltlNode
+	// xs[i] is the formula whose truth in the next state bit i guesses:
+	// the operand of a Next, or an Until itself (φ U ψ = ψ ∨ (φ ∧ X(φ U ψ))).
+	xs     []*ltlNode
+	untils []*ltlNode
+}
+
+func newTableau(p PathFormula) *tableau {
+	t := &tableau{nodes: make(map[string]*ltlNode)}
+	t.root = t.build(p)
+	if len(t.xs) > maxTableauBits {
+		panic(fmt.Sprintf("kripke: path formula has %d Next and Until operators; at most %d are supported", len(t.xs), maxTableauBits))
+	}
+	return t
+}
+
+func (t *tableau) build(p PathFormula) *ltlNode {
+	var n ltlNode
+	switch p := p.(type) {
+	case PathState:
+		key := fmt.Sprintf("%#v", p.State)
+		if m, ok := t.nodes[key]; ok {
+			return m
+		}
+		n = ltlNode{op: ltlLeaf, leaf: len(t.leaves)}
+		t.leaves = append(t.leaves, p.State)
+		t.nodes[key] = &n
+		return &n
+	case PathNot:
+		n = ltlNode{op: ltlNot, left: t.build(p.Inner)}
+	case PathAnd:
+		n = ltlNode{op: ltlAnd, left: t.build(p.Left), right: t.build(p.Right)}
+	case PathOr:
+		n = ltlNode{op: ltlOr, left: t.build(p.Left), right: t.build(p.Right)}
+	case PathNext:
+		n = ltlNode{op: ltlNext, left: t.build(p.Inner)}
+	case PathUntil:
+		n = ltlNode{op: ltlUntil, left: t.build(p.Left), right: t.build(p.Right)}
+	default:
+		panic(fmt.Sprintf("kripke: unknown path formula %T", p))
+	}
+	key := fmt.Sprintf("%d(%p,%p)", n.op, n.left, n.right)
+	if m, ok := t.nodes[key]; ok {
+		return m
+	}
+	switch n.op {
+	case ltlNext:
+		n.bit = len(t.xs)
+		t.xs = append(t.xs, n.left)
+	case ltlUntil:
+		n.bit = len(t.xs)
+		t.xs = append(t.xs, &n)
+		t.untils = append(t.untils, &n)
+	}
+	t.nodes[key] = &n
+	return &n
+}
+
+// eval is the truth of n in state s under the guesses m.
+func (t *tableau) eval(n *ltlNode, leaves []StateSet, s StateID, m int) bool {
+	switch n.op {
+	case ltlLeaf:
+		return leaves[n.leaf].Contains(s)
+	case ltlNot:
+		return !t.eval(n.left, leaves, s, m)
+	case ltlAnd:
+		return t.eval(n.left, leaves, s, m) && t.eval(n.right, leaves, s, m)
+	case ltlOr:
+		return t.eval(n.left, leaves, s, m) || t.eval(n.right, leaves, s, m)
+	case ltlNext:
+		return m>>n.bit&1 == 1
+	}
+	return t.eval(n.right, leaves, s, m) ||
+		(t.eval(n.left, leaves, s, m) && m>>n.bit&1 == 1)
+}
+
+func (t *tableau) sat(g *Graph) StateSet {
+	leaves := make([]StateSet, len(t.leaves))
+	for i, f := range t.leaves {
+		leaves[i] = f.Sat(g)
+	}
+	k := denseOf(g)
+	width := 1 << len(t.xs)
+	node := func(i, m int) int { return i*width + m }
+
+	// (s, m) -> (u, m') when s -> u and m guesses what holds at (u, m'):
+	// m = want[(u, m')].
+	want := make([]int, len(k.ids)*width)
+	for j, u := range k.ids {
+		for m2 := 0; m2 < width; m2++ {
+			for b, x := range t.xs {
+				if t.eval(x, leaves, u, m2) {
+					want[node(j, m2)] |= 1 << b
+				}
+			}
+		}
+	}
+	succ := make([][]int, len(k.ids)*width)
+	for i := range k.ids {
+		for _, j := range k.succ[i] {
+			for m2 := 0; m2 < width; m2++ {
+				v := node(i, want[node(j, m2)])
+				succ[v] = append(succ[v], node(j, m2))
+			}
+		}
+	}
+
+	// A component is fair when it has a cycle and, for every φ U ψ, a pair
+	// where φ U ψ is not promised or ψ holds.
+	comp, ncomp := tarjanSCCs(succ, nil)
+	cyclic := make([]bool, ncomp)
+	fulfilled := make([][]bool, ncomp)
+	for c := range fulfilled {
+		fulfilled[c] = make([]bool, len(t.untils))
+	}
+	for v := range succ {
+		c := comp[v]
+		for _, w := range succ[v] {
+			if comp[w] == c {
+				cyclic[c] = true
+			}
+		}
+		s, m := k.ids[v/width], v%width
+		for i, u := range t.untils {
+			if !t.eval(u, leaves, s, m) || t.eval(u.right, leaves, s, m) {
+				fulfilled[c][i] = true
+			}
+		}
+	}
+	fair := make([]bool, ncomp)
+	for c := range fair {
+		fair[c] = cyclic[c]
+		for _, ok := range fulfilled[c] {
+			fair[c] = fair[c] && ok
+		}
+	}
+
+	// Backward reachability from the fair components.
+	pred := make([][]int, len(succ))
+	for v, ws := range succ {
+		for _, w := range ws {
+			pred[w] = append(pred[w], v)
+		}
+	}
+	good := make([]bool, len(succ))
+	var queue []int
+	for v := range succ {
+		if fair[comp[v]] {
+			good[v] = true
+			queue = append(queue, v)
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, u := range pred[v] {
+			if !good[u] {
+				good[u] = true
+				queue = append(queue, u)
+			}
+		}
+	}
+
+	res := NewStateSet()
+	for i, id := range k.ids {
+		for m := 0; m < width; m++ {
+			if good[node(i, m)] && t.eval(t.root, leaves, id, m) {
+				res.Add(id)
+				break
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,103 @@
+package kripke
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// CTL* formulas that are really CTL agree with the CTL operators once the
+// finite paths of CTL are ruled out with EG true.
+func TestCTLStarMatchesCTL(t *testing.T) {
+	rng := rand.New(rand.NewSource(41))
+	inf := EG(True())
+	for i := 0; i < 300; i++ {
+		g := randomGraph(rng, 1+rng.Intn(8), rng.Intn(16))
+		a, b := randomFormula(rng, 2, true), randomFormula(rng, 2, true)
+		for _, c := range []struct {
+			name      string
+			star, ctl Formula
+		}{
+			{"EF", E(Eventually(Now(a))), EF(And(a, inf))},
+			{"EG", E(Globally(Now(a))), EG(a)},
+			{"EU", E(Until(Now(a), Now(b))), EU(a, And(b, inf))},
+			{"EX", E(Next(Now(a))), EX(And(a, inf))},
+			{"AG", A(Globally(Now(a))), Not(EF(And(Not(a), inf)))},
+			{"AF", A(Eventually(Now(a))), Not(EG(Not(a)))},
+		} {
+			if want, got := c.ctl.Sat(g), c.star.Sat(g); !want.Equal(got) {
+				t.Fatalf("%s %#v: CTL %v, CTL* %v", c.name, a, want, got)
+			}
+		}
+	}
+}
+
+func TestCTLStarFairness(t *testing.T) {
+	// a and b alternate forever, or b moves on to c, which loops on q.
+	g := NewGraph()
+	g.AddState("a", map[string]bool{"p": true})
+	g.AddState("b", map[string]bool{"q": true})
+	g.AddState("c", map[string]bool{"q": true})
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "a")
+	g.AddEdge("b", "c")
+	g.AddEdge("c", "c")
+	g.SetInitial("a")
+	p, q := Now(Atom("p")), Now(Atom("q"))
+	both := E(AndPath(Globally(Eventually(p)), Eventually(Globally(q))))
+	holds := func(f Formula) bool { return f.Sat(g).Contains(g.InitialStates()[0]) }
+
+	if !holds(E(Globally(Eventually(p)))) || !holds(E(Eventually(Globally(q)))) {
+		t.Fatalf("each conjunct holds on its own path")
+	}
+	if holds(both) {
+		t.Fatalf("no single path has GF p and FG q")
+	}
+	if holds(A(Eventually(Globally(q)))) {
+		t.Fatalf("the a-b loop never settles on q")
+	}
+
+	g.AddState("d", map[string]bool{"p": true, "q": true})
+	g.AddEdge("c", "d")
+	g.AddEdge("d", "d")
+	if !holds(both) {
+		t.Fatalf("a b c d d ... has GF p and FG q")
+	}
+	if !holds(MustParseCTL("E(GF p & FG q)")) || holds(MustParseCTL("A(G F p)")) {
+		t.Fatalf("parsed CTL* disagrees")
+	}
+}
+
+func TestParseCTLStar(t *testing.T) {
+	rng := rand.New(rand.NewSource(4141))
+	p, q := Now(Atom("p")), Now(Atom("q"))
+	cases := []struct {
+		src  string
+		want Formula
+	}{
+		{"E(p U q)", E(Until(p, q))},
+		{"A(p R q)", A(Release(p, q))},
+		{"E(X X p & !F q)", E(AndPath(Next(Next(p)), NotPath(Eventually(q))))},
+		{"E(XX p -> (G q | F p))", E(ImpliesPath(Next(Next(p)), OrPath(Globally(q), Eventually(p))))},
+		{"A(p U q U p)", A(Until(p, Until(q, p)))},
+		{"E((p U q) & G EX p)", E(AndPath(Until(p, q), Globally(Now(EX(Atom("p"))))))},
+		{"E(G !(p & q))", E(Globally(NotPath(AndPath(p, q))))},
+		{"AG E(F p)", AG(E(Eventually(p)))},
+	}
+	for i := 0; i < 50; i++ {
+		g := randomGraph(rng, 1+rng.Intn(6), rng.Intn(12))
+		for _, c := range cases {
+			f, err := ParseCTL(c.src)
+			if err != nil {
+				t.Fatalf("%s: %v", c.src, err)
+			}
+			if got, want := f.Sat(g), c.want.Sat(g); !got.Equal(want) {
+				t.Fatalf("%s: got %v, want %v", c.src, stateNames(g, got), stateNames(g, want))
+			}
+		}
+	}
+	for _, src := range []string{"E(p U)", "E(G &)", "A(p", "E(p U q]"} {
+		if _, err := ParseCTL(src); err == nil {
+			t.Fatalf("expected an error for %q", src)
+		}
+	}
+}
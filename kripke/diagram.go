@@ -7,30 +7,61 @@ import (
 )
 
 // NodeID is a simple identifier for states in diagrams.
-// It is intentionally independent from the CTL StateID type.
+// It names a state; ToGraph maps it to a CTL StateID.
 type NodeID string
 
 // SimpleGraph is a minimal explicit graph representation for diagrams.
+// Only States and Succ are required; the other fields let a SimpleGraph
+// carry everything a Graph does (see ToGraph and ToSimpleGraph).
 type SimpleGraph struct {
-    States []NodeID
-    Succ   map[NodeID][]NodeID
+    States     []NodeID
+    Succ       map[NodeID][]NodeID
+    Initial    []NodeID                   // optional
+    Labels     map[NodeID]map[string]bool // optional atomic propositions
+    Vars       map[NodeID]map[string]any  // optional state variables
+    Probs      map[NodeID][]float64       // optional, parallel to Succ
+    EdgeLabels map[NodeID][]EdgeLabel     // optional, parallel to Succ
 }
 
 // WriteMermaidStateDiagram writes a Mermaid stateDiagram-v2 representation
-// of the given graph to w. "initial" is the starting state.
+// of the given graph to w. "initial" is the starting state; when it is
+// empty, g.Initial is used instead. Edges with an action, guard or
+// probability are written as "a --> b: label".
 func WriteMermaidStateDiagram(g *SimpleGraph, initial NodeID, w io.Writer) error {
+    if err := g.Validate(); err != nil {
+        return err
+    }
     fmt.Fprintln(w, "stateDiagram-v2")
-    fmt.Fprintf(w, "  [*] --> %s\n\n", initial)
+    initials := g.Initial
+    if initial != "" {
+        initials = []NodeID{initial}
+    }
+    for _, s := range initials {
+        fmt.Fprintf(w, "  [*] --> %s\n", s)
+    }
+    fmt.Fprintln(w)
 
     seen := make(map[string]bool)
     for _, from := range g.States {
-        for _, to := range g.Succ[from] {
-            key := string(from) + "->" + string(to)
+        for i, to := range g.Succ[from] {
+            var l EdgeLabel
+            if ls := g.EdgeLabels[from]; ls != nil {
+                l = ls[i]
+            }
+            if ps := g.Probs[from]; ps != nil {
+                l.Prob = ps[i]
+            }
+            label := l.String()
+            key := string(from) + "->" + string(to) + ":" + label
             if seen[key] {
                 continue
             }
             seen[key] = true
-            fmt.Fprintf(w, "  %s --> %s\n", from, to)
+            if label != "" {
+                fmt.Fprintf(w, "  %s --> %s: %s\n", from, to, label)
+            } else {
+                fmt.Fprintf(w, "  %s --> %s\n", from, to)
+            }
         }
     }
     return nil
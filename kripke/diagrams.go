@@ -29,16 +29,17 @@ func (g *Graph) GenerateStateDiagram(options ...DiagramOption) string {
 	// Transitions
 	for _, sid := range g.States() {
 		fromName := g.NameOf(sid)
-		for _, tid := range g.Succ(sid) {
-			toName := g.NameOf(tid)
+		for e := range g.EdgesFrom(sid) {
+			toName := g.NameOf(e.To)
 			
+			// Edges are labelled with their action, guard and probability
+			// unless a labeler overrides that
+			label := e.EdgeLabel.String()
 			if opts.edgeLabeler != nil {
-				label := opts.edgeLabeler(sid, tid, g)
-				if label != "" {
-					sb.WriteString(fmt.Sprintf("    %s --> %s: %s\n", fromName, toName, label))
-				} else {
-					sb.WriteString(fmt.Sprintf("    %s --> %s\n", fromName, toName))
-				}
+				label = opts.edgeLabeler(sid, e.To, g)
+			}
+			if label != "" {
+				sb.WriteString(fmt.Sprintf("    %s --> %s: %s\n", fromName, toName, label))
 			} else {
 				sb.WriteString(fmt.Sprintf("    %s --> %s\n", fromName, toName))
 			}
@@ -107,33 +108,22 @@ func (w *World) GenerateSequenceDiagram(maxEvents int) string {
 	return sb.String()
 }
 
-// GenerateCTLTable generates a markdown table of CTL verification results
+// GenerateCTLTable generates a markdown table of CTL verification results.
+// A requirement passes when every initial state satisfies it (see
+// EvaluateRequirement); failures link to a counterexample section after
+// the table.
 func (g *Graph) GenerateCTLTable(requirements []Requirement) string {
+	results := g.EvaluateRequirements(requirements)
 	var sb strings.Builder
-	sb.WriteString("| ID | Requirement | CTL Formula | Result |\n")
-	sb.WriteString("|----|-------------|-------------|--------|\n")
-	
-	for _, req := range requirements {
-		result := "❓ UNKNOWN"
-		if req.Formula != nil {
-			// Evaluate formula - it returns a StateSet
-			satisfying := req.Formula(g)
-			totalStates := len(g.States())
-			satisfyingCount := len(satisfying)  // Use len() instead of Cardinality()
-			
-			if satisfyingCount == totalStates {
-				result = "✅ PASS"
-			} else if satisfyingCount > 0 {
-				result = fmt.Sprintf("⚠️ PARTIAL (%d/%d)", satisfyingCount, totalStates)
-			} else {
-				result = "❌ FAIL"
-			}
-		}
-		
-		sb.WriteString(fmt.Sprintf("| %s | %s | `%s` | %s |\n",
-			req.ID, req.Description, req.FormulaString, result))
+	sb.WriteString("| ID | Requirement | CTL Formula | Result | Satisfying States | Time |\n")
+	sb.WriteString("|----|-------------|-------------|--------|-------------------|------|\n")
+
+	for _, r := range results {
+		sb.WriteString(fmt.Sprintf("| %s | %s | `%s` | %s | %d/%d | %s |\n",
+			r.ID, r.Description, r.Formula, resultCell(r), len(r.Satisfying), r.States, r.Elapsed))
 	}
-	
+	writeCounterexamples(&sb, results)
+
 	return sb.String()
 }
 
@@ -153,7 +143,8 @@ func WithStateDescriber(f func(StateID, *Graph) string) DiagramOption {
 	}
 }
 
-// WithEdgeLabeler sets a custom edge label function
+// WithEdgeLabeler sets a custom edge label function, replacing the
+// default of each edge's EdgeLabel
 func WithEdgeLabeler(f func(StateID, StateID, *Graph) string) DiagramOption {
 	return func(opts *diagramOptions) {
 		opts.edgeLabeler = f
@@ -0,0 +1,317 @@
+package kripke
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ---------- Graph diff ----------
+
+// DiffOption configures DiffGraphs.
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	byLabels bool
+}
+
+// MatchByLabels pairs states with the same set of true labels instead of
+// the same name, for comparing graphs whose state names are not stable
+// (e.g. the w0, w1, ... of Explore). States with equal labels are paired
+// in StateID order.
+func MatchByLabels() DiffOption {
+	return func(opts *diffOptions) { opts.byLabels = true }
+}
+
+// DiffEdge is an edge between two named states.
+type DiffEdge struct {
+	From, To string
+}
+
+// LabelChange records how the labels and variables of a matched state
+// changed. Variables appear as "name=value".
+type LabelChange struct {
+	A, B    string // the state's name in each graph
+	Added   []string
+	Removed []string
+}
+
+// GraphDiff is the difference from graph A to graph B. States and edges
+// are named as in the graph they belong to: removed ones by their name in
+// A, added ones by their name in B.
+type GraphDiff struct {
+	AddedStates   []string
+	RemovedStates []string
+	AddedEdges    []DiffEdge
+	RemovedEdges  []DiffEdge
+	LabelChanges  []LabelChange
+	Matched       map[string]string // A name -> B name
+
+	a, b *Graph
+	aToB map[StateID]StateID
+}
+
+// Empty reports whether the graphs are the same up to the matching.
+func (d *GraphDiff) Empty() bool {
+	return len(d.AddedStates) == 0 && len(d.RemovedStates) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0 && len(d.LabelChanges) == 0
+}
+
+// DiffGraphs compares a to b. By default states are matched by name.
+// Duplicate edges count once; initial-state changes are not reported.
+func DiffGraphs(a, b *Graph, options ...DiffOption) *GraphDiff {
+	opts := &diffOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	d := &GraphDiff{Matched: make(map[string]string), a: a, b: b, aToB: make(map[StateID]StateID)}
+
+	if opts.byLabels {
+		byKey := make(map[string][]StateID)
+		for _, s := range sortedStates(b) {
+			k := labelKey(b, s)
+			byKey[k] = append(byKey[k], s)
+		}
+		for _, s := range sortedStates(a) {
+			k := labelKey(a, s)
+			if q := byKey[k]; len(q) > 0 {
+				d.aToB[s] = q[0]
+				byKey[k] = q[1:]
+			}
+		}
+	} else {
+		for _, s := range sortedStates(a) {
+			if t, ok := b.StateByName(a.NameOf(s)); ok {
+				d.aToB[s] = t
+			}
+		}
+	}
+	matchedB := make(map[StateID]bool, len(d.aToB))
+	for s, t := range d.aToB {
+		matchedB[t] = true
+		d.Matched[a.NameOf(s)] = b.NameOf(t)
+	}
+
+	for _, s := range sortedStates(a) {
+		t, ok := d.aToB[s]
+		if !ok {
+			d.RemovedStates = append(d.RemovedStates, a.NameOf(s))
+			continue
+		}
+		before, after := propTerms(a, s), propTerms(b, t)
+		added, removed := setDiff(after, before), setDiff(before, after)
+		if len(added) > 0 || len(removed) > 0 {
+			d.LabelChanges = append(d.LabelChanges, LabelChange{A: a.NameOf(s), B: b.NameOf(t), Added: added, Removed: removed})
+		}
+	}
+	for _, t := range sortedStates(b) {
+		if !matchedB[t] {
+			d.AddedStates = append(d.AddedStates, b.NameOf(t))
+		}
+	}
+
+	// Compare edges in B's terms; an edge of A with an unmatched end has
+	// no counterpart and is removed.
+	bEdges := edgeSet(b)
+	aEdges := make(map[[2]StateID]bool)
+	for _, e := range sortedEdges(a) {
+		from, ok1 := d.aToB[e[0]]
+		to, ok2 := d.aToB[e[1]]
+		if ok1 && ok2 && bEdges[[2]StateID{from, to}] {
+			aEdges[[2]StateID{from, to}] = true
+			continue
+		}
+		d.RemovedEdges = append(d.RemovedEdges, DiffEdge{a.NameOf(e[0]), a.NameOf(e[1])})
+	}
+	for _, e := range sortedEdges(b) {
+		if !aEdges[e] {
+			d.AddedEdges = append(d.AddedEdges, DiffEdge{b.NameOf(e[0]), b.NameOf(e[1])})
+		}
+	}
+	return d
+}
+
+func sortedStates(g *Graph) []StateID {
+	ss := g.States()
+	sort.Slice(ss, func(i, j int) bool { return ss[i] < ss[j] })
+	return ss
+}
+
+// sortedEdges lists the distinct edges of g ordered by source, then target.
+func sortedEdges(g *Graph) [][2]StateID {
+	set := edgeSet(g)
+	out := make([][2]StateID, 0, len(set))
+	for e := range set {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i][0] != out[j][0] {
+			return out[i][0] < out[j][0]
+		}
+		return out[i][1] < out[j][1]
+	})
+	return out
+}
+
+func edgeSet(g *Graph) map[[2]StateID]bool {
+	set := make(map[[2]StateID]bool)
+	for _, s := range g.States() {
+		for _, t := range g.Succ(s) {
+			set[[2]StateID{s, t}] = true
+		}
+	}
+	return set
+}
+
+// labelKey is the sorted list of true labels of s.
+func labelKey(g *Graph, s StateID) string {
+	var on []string
+	for k, v := range g.labels[s] {
+		if v {
+			on = append(on, k)
+		}
+	}
+	sort.Strings(on)
+	return strings.Join(on, "\x00")
+}
+
+// propTerms lists the true labels and "name=value" variables of s.
+func propTerms(g *Graph, s StateID) map[string]bool {
+	out := make(map[string]bool)
+	for k, v := range g.labels[s] {
+		if v {
+			out[k] = true
+		}
+	}
+	for k, v := range g.vars[s] {
+		out[fmt.Sprintf("%s=%v", k, ConstExpr{Value: v})] = true
+	}
+	return out
+}
+
+func setDiff(a, b map[string]bool) []string {
+	var out []string
+	for k := range a {
+		if !b[k] {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// String lists the differences one per line, diff style.
+func (d *GraphDiff) String() string {
+	var sb strings.Builder
+	for _, s := range d.RemovedStates {
+		fmt.Fprintf(&sb, "- state %s\n", s)
+	}
+	for _, s := range d.AddedStates {
+		fmt.Fprintf(&sb, "+ state %s\n", s)
+	}
+	for _, e := range d.RemovedEdges {
+		fmt.Fprintf(&sb, "- edge %s -> %s\n", e.From, e.To)
+	}
+	for _, e := range d.AddedEdges {
+		fmt.Fprintf(&sb, "+ edge %s -> %s\n", e.From, e.To)
+	}
+	for _, c := range d.LabelChanges {
+		name := c.A
+		if c.A != c.B {
+			name = c.A + " / " + c.B
+		}
+		for _, l := range c.Removed {
+			fmt.Fprintf(&sb, "~ state %s: -%s\n", name, l)
+		}
+		for _, l := range c.Added {
+			fmt.Fprintf(&sb, "~ state %s: +%s\n", name, l)
+		}
+	}
+	return sb.String()
+}
+
+// Mermaid renders the union of both graphs as a Mermaid flowchart: added
+// states and edges in green, removed ones in red and dashed, and states
+// whose labels changed in yellow. A state matched under a different name
+// is shown as "a → b".
+func (d *GraphDiff) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	sb.WriteString("    classDef added fill:#d4f7d4,stroke:#2a2\n")
+	sb.WriteString("    classDef removed fill:#f7d4d4,stroke:#a22,stroke-dasharray:4 3\n")
+	sb.WriteString("    classDef changed fill:#f7f0c0,stroke:#a80\n")
+
+	changed := make(map[string]bool)
+	for _, c := range d.LabelChanges {
+		changed[c.B] = true
+	}
+	added := make(map[string]bool)
+	for _, s := range d.AddedStates {
+		added[s] = true
+	}
+	bNode := func(s StateID) string { return fmt.Sprintf("b%d", s) }
+	aNode := func(s StateID) string {
+		if t, ok := d.aToB[s]; ok {
+			return bNode(t)
+		}
+		return fmt.Sprintf("a%d", s)
+	}
+	bName := make(map[StateID]string)
+	for an, bn := range d.Matched {
+		if an != bn {
+			bName[d.b.nameToID[bn]] = an + " → " + bn
+		}
+	}
+
+	for _, t := range sortedStates(d.b) {
+		label := d.b.NameOf(t)
+		if l, ok := bName[t]; ok {
+			label = l
+		}
+		fmt.Fprintf(&sb, "    %s[%s]\n", bNode(t), mermaidText(label))
+		switch name := d.b.NameOf(t); {
+		case added[name]:
+			fmt.Fprintf(&sb, "    class %s added\n", bNode(t))
+		case changed[name]:
+			fmt.Fprintf(&sb, "    class %s changed\n", bNode(t))
+		}
+	}
+	for _, s := range sortedStates(d.a) {
+		if _, ok := d.aToB[s]; !ok {
+			fmt.Fprintf(&sb, "    %s[%s]\n", aNode(s), mermaidText(d.a.NameOf(s)))
+			fmt.Fprintf(&sb, "    class %s removed\n", aNode(s))
+		}
+	}
+
+	addedEdge := make(map[DiffEdge]bool)
+	for _, e := range d.AddedEdges {
+		addedEdge[e] = true
+	}
+	link := 0
+	var addedLinks, removedLinks []string
+	for _, e := range sortedEdges(d.b) {
+		fmt.Fprintf(&sb, "    %s --> %s\n", bNode(e[0]), bNode(e[1]))
+		if addedEdge[DiffEdge{d.b.NameOf(e[0]), d.b.NameOf(e[1])}] {
+			addedLinks = append(addedLinks, fmt.Sprint(link))
+		}
+		link++
+	}
+	for _, e := range d.RemovedEdges {
+		from, to := d.a.nameToID[e.From], d.a.nameToID[e.To]
+		fmt.Fprintf(&sb, "    %s -.-> %s\n", aNode(from), aNode(to))
+		removedLinks = append(removedLinks, fmt.Sprint(link))
+		link++
+	}
+	if len(addedLinks) > 0 {
+		fmt.Fprintf(&sb, "    linkStyle %s stroke:#2a2,stroke-width:2px\n", strings.Join(addedLinks, ","))
+	}
+	if len(removedLinks) > 0 {
+		fmt.Fprintf(&sb, "    linkStyle %s stroke:#a22,stroke-width:2px\n", strings.Join(removedLinks, ","))
+	}
+	return sb.String()
+}
+
+// mermaidText quotes a node label for a Mermaid flowchart.
+func mermaidText(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,90 @@
+package kripke
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// orderGraphV2 drops cancellation, adds a Returned state after delivery
+// and marks delivered orders as paid.
+func orderGraphV2() *Graph {
+	g := NewGraph()
+	g.AddState("s0", map[string]bool{"accepted": false})
+	g.AddState("s1", map[string]bool{"accepted": true})
+	s2 := g.AddState("s2", map[string]bool{"accepted": true, "delivered": true, "paid": true})
+	g.SetVars(s2, map[string]any{"total": 3})
+	g.AddState("s4", map[string]bool{"returned": true})
+	g.AddEdge("s0", "s1")
+	g.AddEdge("s1", "s2")
+	g.AddEdge("s2", "s4")
+	g.AddEdge("s2", "s4")
+	g.AddEdge("s4", "s4")
+	g.SetInitial("s0")
+	return g
+}
+
+func TestDiffGraphsByName(t *testing.T) {
+	d := DiffGraphs(OrderGraph(), orderGraphV2())
+	if !reflect.DeepEqual(d.RemovedStates, []string{"s3"}) || !reflect.DeepEqual(d.AddedStates, []string{"s4"}) {
+		t.Fatalf("states: -%v +%v", d.RemovedStates, d.AddedStates)
+	}
+	wantRemoved := []DiffEdge{{"s1", "s3"}, {"s2", "s2"}, {"s3", "s3"}}
+	wantAdded := []DiffEdge{{"s2", "s4"}, {"s4", "s4"}}
+	if !reflect.DeepEqual(d.RemovedEdges, wantRemoved) || !reflect.DeepEqual(d.AddedEdges, wantAdded) {
+		t.Fatalf("edges: -%v +%v", d.RemovedEdges, d.AddedEdges)
+	}
+	want := []LabelChange{{A: "s2", B: "s2", Added: []string{"paid", "total=3"}}}
+	if !reflect.DeepEqual(d.LabelChanges, want) {
+		t.Fatalf("label changes %+v", d.LabelChanges)
+	}
+	if d.Empty() || !DiffGraphs(OrderGraph(), OrderGraph()).Empty() {
+		t.Fatalf("Empty is wrong")
+	}
+
+	text := d.String()
+	for _, line := range []string{"- state s3", "+ state s4", "- edge s1 -> s3", "+ edge s2 -> s4", "~ state s2: +paid"} {
+		if !strings.Contains(text, line+"\n") {
+			t.Fatalf("missing %q in\n%s", line, text)
+		}
+	}
+	m := d.Mermaid()
+	for _, line := range []string{"class b3 added", "class a3 removed", "class b2 changed", "b1 -.-> a3", "linkStyle 2,3 stroke:#2a2", "linkStyle 4,5,6 stroke:#a22"} {
+		if !strings.Contains(m, line) {
+			t.Fatalf("missing %q in\n%s", line, m)
+		}
+	}
+}
+
+func TestDiffGraphsByLabels(t *testing.T) {
+	w := producerConsumerWorld(2, 1)
+	a, err := Explore(w, WithVariables(ChannelVariables(w)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same graph with states renamed and labelled by their queue
+	// length matches completely by labels, not at all by name.
+	b := NewGraph()
+	lbl := func(g *Graph, s StateID) map[string]bool {
+		v, _ := g.Var(s, "C.inbox.len")
+		return map[string]bool{"q" + ConstExpr{Value: v}.String(): true, g.NameOf(s)[1:]: true}
+	}
+	al := NewGraph()
+	for _, s := range sortedStates(a) {
+		al.AddState(a.NameOf(s), lbl(a, s))
+		b.AddState("x"+a.NameOf(s), lbl(a, s))
+	}
+	for _, e := range sortedEdges(a) {
+		al.AddEdge(a.NameOf(e[0]), a.NameOf(e[1]))
+		b.AddEdge("x"+a.NameOf(e[0]), "x"+a.NameOf(e[1]))
+	}
+	if d := DiffGraphs(al, b, MatchByLabels()); !d.Empty() || d.Matched["w0"] != "xw0" {
+		t.Fatalf("expected no differences:\n%s", d)
+	}
+	if d := DiffGraphs(al, b); len(d.AddedStates) != len(a.States()) {
+		t.Fatalf("expected every state to differ by name:\n%s", d)
+	}
+	if !strings.Contains(DiffGraphs(al, b, MatchByLabels()).Mermaid(), `"w0 → xw0"`) {
+		t.Fatalf("renamed states should show both names")
+	}
+}
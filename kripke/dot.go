@@ -0,0 +1,486 @@
+package kripke
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ---------- DOT writer ----------
+
+// WriteDOT writes g as a Graphviz digraph. Everything ReadDOT needs to
+// rebuild the graph is kept in kripke_* attributes, so the output renders
+// normally and still round-trips:
+//
+//	"s0" [label="s0\np", kripke_labels="{\"p\":true,\"q\":false}", kripke_vars="{\"x\":1}", kripke_initial=true, peripheries=2];
+//	"s0" -> "s1" [label="0.5", prob=0.5];
+//	"s1" -> "s2" [label="send [n<3]", kripke_action="send", kripke_guard="n<3"];
+func WriteDOT(w io.Writer, g *Graph) error {
+	d := g.data()
+	bw := bufio.NewWriter(w)
+	initial := make(map[StateID]bool)
+	for _, id := range d.Init {
+		initial[id] = true
+	}
+
+	fmt.Fprintln(bw, "digraph kripke {")
+	fmt.Fprintln(bw, "  node [shape=ellipse];")
+	for i, name := range d.Names {
+		var shown []string
+		for _, k := range sortedKeys(d.Labels[i]) {
+			if d.Labels[i][k] {
+				shown = append(shown, k)
+			}
+		}
+		label := name
+		if len(shown) > 0 {
+			label += "\n" + strings.Join(shown, ",")
+		}
+		attrs := []string{"label=" + dotQuote(label)}
+		if len(d.Labels[i]) > 0 {
+			b, err := json.Marshal(d.Labels[i])
+			if err != nil {
+				return err
+			}
+			attrs = append(attrs, "kripke_labels="+dotQuote(string(b)))
+		}
+		if len(d.Vars[i]) > 0 {
+			b, err := json.Marshal(d.Vars[i])
+			if err != nil {
+				return err
+			}
+			attrs = append(attrs, "kripke_vars="+dotQuote(string(b)))
+		}
+		if initial[StateID(i)] {
+			attrs = append(attrs, "kripke_initial=true", "peripheries=2")
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(name), strings.Join(attrs, ", "))
+	}
+	for i, succ := range d.Succ {
+		for j, t := range succ {
+			var attrs []string
+			var l EdgeLabel
+			if d.EdgeLabels[i] != nil {
+				l = d.EdgeLabels[i][j]
+			}
+			if d.Probs[i] != nil {
+				p := strconv.FormatFloat(d.Probs[i][j], 'g', -1, 64)
+				if l == (EdgeLabel{}) {
+					attrs = append(attrs, "label="+dotQuote(p))
+				} else {
+					l.Prob = d.Probs[i][j]
+					attrs = append(attrs, "label="+dotQuote(l.String()))
+				}
+				attrs = append(attrs, "prob="+p)
+			} else if l != (EdgeLabel{}) {
+				attrs = append(attrs, "label="+dotQuote(l.String()))
+			}
+			for _, kv := range [][2]string{{"kripke_action", l.Action}, {"kripke_guard", l.Guard}, {"kripke_process", l.Process}} {
+				if kv[1] != "" {
+					attrs = append(attrs, kv[0]+"="+dotQuote(kv[1]))
+				}
+			}
+			if len(attrs) > 0 {
+				fmt.Fprintf(bw, "  %s -> %s [%s];\n", dotQuote(d.Names[i]), dotQuote(d.Names[t]), strings.Join(attrs, ", "))
+			} else {
+				fmt.Fprintf(bw, "  %s -> %s;\n", dotQuote(d.Names[i]), dotQuote(d.Names[t]))
+			}
+		}
+	}
+	// Initial states are listed in order so multiple initial states keep
+	// their order on a round trip.
+	if len(d.Init) > 1 {
+		names := make([]string, len(d.Init))
+		for i, id := range d.Init {
+			names[i] = d.Names[id]
+		}
+		b, err := json.Marshal(names)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "  kripke_initial_order=%s;\n", dotQuote(string(b)))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func dotQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// ReadDOT reads a graph written by WriteDOT. Plain DOT files are accepted
+// too: nodes become states named by their ID, edges become transitions,
+// and a node is initial if it has kripke_initial=true. Edge labels come
+// from the kripke_action, kripke_guard and kripke_process attributes; a
+// plain "label" is not parsed back.
+func ReadDOT(r io.Reader) (*Graph, error) {
+	dg, err := parseDOT(r)
+	if err != nil {
+		return nil, fmt.Errorf("ReadDOT: %w", err)
+	}
+	g := NewGraph()
+	var initial []string
+	for _, n := range dg.nodes {
+		lbls := make(map[string]bool)
+		if enc := n.attrs["kripke_labels"]; enc != "" {
+			if err := json.Unmarshal([]byte(enc), &lbls); err != nil {
+				return nil, fmt.Errorf("ReadDOT: node %s: bad labels: %v", n.id, err)
+			}
+		}
+		id := g.AddState(n.id, lbls)
+		if enc := n.attrs["kripke_vars"]; enc != "" {
+			dec := json.NewDecoder(strings.NewReader(enc))
+			dec.UseNumber()
+			var raw map[string]any
+			if err := dec.Decode(&raw); err != nil {
+				return nil, fmt.Errorf("ReadDOT: node %s: %v", n.id, err)
+			}
+			for k, v := range raw {
+				if num, ok := v.(json.Number); ok {
+					iv, err := num.Int64()
+					if err != nil {
+						return nil, fmt.Errorf("ReadDOT: node %s: variable %s: %v", n.id, k, err)
+					}
+					raw[k] = int(iv)
+				} else if _, ok := normalizeValue(v); !ok {
+					return nil, fmt.Errorf("ReadDOT: node %s: variable %s has unsupported type %T", n.id, k, v)
+				}
+			}
+			g.SetVars(id, raw)
+		}
+		if n.attrs["kripke_initial"] == "true" {
+			initial = append(initial, n.id)
+		}
+	}
+	for _, e := range dg.edges {
+		l := EdgeLabel{Action: e.attrs["kripke_action"], Guard: e.attrs["kripke_guard"], Process: e.attrs["kripke_process"]}
+		if p, ok := e.attrs["prob"]; ok {
+			f, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ReadDOT: edge %s -> %s: bad prob %q", e.from, e.to, p)
+			}
+			g.addEdgeProbLabel(e.from, e.to, f, l)
+		} else {
+			g.AddLabeledEdge(e.from, e.to, l)
+		}
+	}
+	if order, ok := dg.attrs["kripke_initial_order"]; ok {
+		initial = nil
+		if err := json.Unmarshal([]byte(order), &initial); err != nil {
+			return nil, fmt.Errorf("ReadDOT: bad kripke_initial_order: %v", err)
+		}
+	}
+	for _, name := range initial {
+		if _, ok := g.nameToID[name]; !ok {
+			return nil, fmt.Errorf("ReadDOT: undefined initial state %q", name)
+		}
+		g.SetInitial(name)
+	}
+	return g, nil
+}
+
+// ---------- DOT parser ----------
+
+// dotGraph is the part of a DOT file the importers use: nodes in order of
+// first appearance, edges, and graph-level attributes. Subgraphs are
+// flattened and default attribute statements (node [...]) are applied.
+type dotGraph struct {
+	attrs map[string]string
+	nodes []*dotNode
+	index map[string]*dotNode
+	edges []dotEdge
+}
+
+type dotNode struct {
+	id    string
+	attrs map[string]string
+}
+
+type dotEdge struct {
+	from, to string
+	attrs    map[string]string
+}
+
+func (dg *dotGraph) node(id string) *dotNode {
+	if n, ok := dg.index[id]; ok {
+		return n
+	}
+	n := &dotNode{id: id, attrs: make(map[string]string)}
+	dg.index[id] = n
+	dg.nodes = append(dg.nodes, n)
+	return n
+}
+
+// dotToken is a lexed DOT token; quoted tokens never act as punctuation.
+type dotToken struct {
+	text   string
+	quoted bool
+}
+
+type dotParser struct {
+	toks []dotToken
+	pos  int
+}
+
+// parseDOT parses the common subset of the DOT language: optional strict,
+// graph/digraph, node, edge (including chains a -> b -> c), attribute and
+// subgraph statements, with C and # comments. HTML labels are not supported.
+func parseDOT(r io.Reader) (*dotGraph, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	toks, err := lexDOT(string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &dotParser{toks: toks}
+	dg := &dotGraph{attrs: make(map[string]string), index: make(map[string]*dotNode)}
+
+	if strings.EqualFold(p.peek(), "strict") {
+		p.next()
+	}
+	if kw := strings.ToLower(p.next()); kw != "digraph" && kw != "graph" {
+		return nil, fmt.Errorf("expected digraph, got %q", kw)
+	}
+	if p.peek() != "{" {
+		p.next() // graph name
+	}
+	if err := p.block(dg, map[string]string{}); err != nil {
+		return nil, err
+	}
+	return dg, nil
+}
+
+func (p *dotParser) eof() bool { return p.pos >= len(p.toks) }
+
+// peek returns the next token's text, or "\x00" for punctuation-looking
+// quoted strings and at end of input, so it never matches syntax.
+func (p *dotParser) peek() string {
+	if p.eof() {
+		return "\x00"
+	}
+	if t := p.toks[p.pos]; !t.quoted {
+		return t.text
+	}
+	return "\x00"
+}
+
+// next consumes a token and returns its text.
+func (p *dotParser) next() string {
+	if p.eof() {
+		return ""
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t.text
+}
+
+func (p *dotParser) expect(t string) error {
+	if got := p.next(); got != t {
+		return fmt.Errorf("expected %q, got %q", t, got)
+	}
+	return nil
+}
+
+// block parses '{' stmt* '}' with the given node defaults.
+func (p *dotParser) block(dg *dotGraph, nodeDefaults map[string]string) error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	defaults := make(map[string]string, len(nodeDefaults))
+	for k, v := range nodeDefaults {
+		defaults[k] = v
+	}
+	edgeDefaults := map[string]string{}
+	for {
+		switch t := p.peek(); {
+		case p.eof():
+			return fmt.Errorf("unexpected end of input")
+		case t == "}":
+			p.next()
+			return nil
+		case t == ";" || t == ",":
+			p.next()
+		case strings.EqualFold(t, "subgraph"):
+			p.next()
+			if p.peek() != "{" {
+				p.next()
+			}
+			if err := p.block(dg, defaults); err != nil {
+				return err
+			}
+		case t == "{":
+			if err := p.block(dg, defaults); err != nil {
+				return err
+			}
+		case strings.EqualFold(t, "node") || strings.EqualFold(t, "edge") || strings.EqualFold(t, "graph"):
+			p.next()
+			attrs, err := p.attrList()
+			if err != nil {
+				return err
+			}
+			target := map[string]map[string]string{"node": defaults, "edge": edgeDefaults, "graph": dg.attrs}[strings.ToLower(t)]
+			for k, v := range attrs {
+				target[k] = v
+			}
+		default:
+			id := p.next()
+			if p.peek() == "=" {
+				p.next()
+				dg.attrs[id] = p.next()
+				continue
+			}
+			chain := []string{id}
+			for p.peek() == "->" || p.peek() == "--" {
+				p.next()
+				chain = append(chain, p.next())
+			}
+			attrs, err := p.attrList()
+			if err != nil {
+				return err
+			}
+			if len(chain) == 1 {
+				n := dg.node(id)
+				for k, v := range defaults {
+					if _, ok := n.attrs[k]; !ok {
+						n.attrs[k] = v
+					}
+				}
+				for k, v := range attrs {
+					n.attrs[k] = v
+				}
+				continue
+			}
+			for _, nid := range chain {
+				n := dg.node(nid)
+				for k, v := range defaults {
+					if _, ok := n.attrs[k]; !ok {
+						n.attrs[k] = v
+					}
+				}
+			}
+			for i := 0; i+1 < len(chain); i++ {
+				ea := make(map[string]string, len(edgeDefaults)+len(attrs))
+				for k, v := range edgeDefaults {
+					ea[k] = v
+				}
+				for k, v := range attrs {
+					ea[k] = v
+				}
+				dg.edges = append(dg.edges, dotEdge{from: chain[i], to: chain[i+1], attrs: ea})
+			}
+		}
+	}
+}
+
+// attrList parses zero or more '[' (key '=' value [,;])* ']'.
+func (p *dotParser) attrList() (map[string]string, error) {
+	attrs := make(map[string]string)
+	for p.peek() == "[" {
+		p.next()
+		for p.peek() != "]" {
+			if p.eof() {
+				return nil, fmt.Errorf("unterminated attribute list")
+			}
+			k := p.next()
+			if k == "," || k == ";" {
+				continue
+			}
+			if p.peek() == "=" {
+				p.next()
+				attrs[k] = p.next()
+			} else {
+				attrs[k] = "true"
+			}
+		}
+		p.next()
+	}
+	return attrs, nil
+}
+
+// lexDOT splits DOT source into tokens. Quoted strings are returned
+// unquoted with \" \\ and \n unescaped; other escapes (\l, \r) are kept.
+func lexDOT(src string) ([]dotToken, error) {
+	var toks []dotToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '#' && (i == 0 || src[i-1] == '\n'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			toks = append(toks, dotToken{text: src[i : i+2]})
+			i += 2
+		case strings.ContainsRune("{}[]=;,", rune(c)):
+			toks = append(toks, dotToken{text: string(c)})
+			i++
+		case c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					switch src[j+1] {
+					case '"', '\\':
+						sb.WriteByte(src[j+1])
+						j++
+						continue
+					case 'n':
+						sb.WriteByte('\n')
+						j++
+						continue
+					}
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			toks = append(toks, dotToken{text: sb.String(), quoted: true})
+			i = j + 1
+		case c == '<':
+			return nil, fmt.Errorf("HTML labels are not supported")
+		default:
+			j := i
+			for j < len(src) && !strings.ContainsRune(" \t\r\n{}[]=;,\"", rune(src[j])) &&
+				!strings.HasPrefix(src[j:], "->") && !(strings.HasPrefix(src[j:], "--") && j > i) {
+				j++
+			}
+			toks = append(toks, dotToken{text: src[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
@@ -0,0 +1,177 @@
+package kripke
+
+import (
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// ---------- Edge labels ----------
+
+// EdgeLabel describes what a transition is: the action that caused it,
+// the guard under which it was enabled, its probability and the process
+// that took it. Every field is optional.
+type EdgeLabel struct {
+	Action  string
+	Guard   string
+	Prob    float64 // 0 when the edge has no probability
+	Process string
+}
+
+// String renders the label for diagrams: the action (or the process when
+// there is none), the guard in brackets, and the probability when it is
+// strictly between 0 and 1.
+func (l EdgeLabel) String() string {
+	var parts []string
+	switch {
+	case l.Action != "":
+		parts = append(parts, l.Action)
+	case l.Process != "":
+		parts = append(parts, l.Process)
+	}
+	if l.Guard != "" {
+		parts = append(parts, "["+l.Guard+"]")
+	}
+	if l.Prob > 0 && l.Prob < 1 {
+		parts = append(parts, strconv.FormatFloat(l.Prob, 'g', 4, 64))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Edge is one transition of a Graph. Index is its position in
+// Succ(From), which distinguishes parallel edges.
+type Edge struct {
+	From, To StateID
+	Index    int
+	EdgeLabel
+}
+
+// AddLabeledEdge adds a transition with a label. A non-zero l.Prob makes
+// it a probabilistic edge, as with AddEdgeProb; l.Action is what Parallel
+// synchronizes components on.
+func (g *Graph) AddLabeledEdge(fromName, toName string, l EdgeLabel) {
+	if l.Prob != 0 {
+		g.AddEdgeProb(fromName, toName, l.Prob)
+	} else {
+		g.AddEdge(fromName, toName)
+	}
+	l.Prob = 0
+	if l != (EdgeLabel{}) {
+		g.setLastLabel(g.nameToID[fromName], l)
+	}
+}
+
+// addEdgeProbLabel is AddEdgeProb followed by the label's action, guard
+// and process, for readers whose probabilities may be zero.
+func (g *Graph) addEdgeProbLabel(fromName, toName string, p float64, l EdgeLabel) {
+	g.AddEdgeProb(fromName, toName, p)
+	l.Prob = 0
+	if l != (EdgeLabel{}) {
+		g.setLastLabel(g.nameToID[fromName], l)
+	}
+}
+
+// setLastLabel sets the action, guard and process of the edge of s added
+// last.
+func (g *Graph) setLastLabel(s StateID, l EdgeLabel) {
+	if g.elabels[s] == nil {
+		g.elabels[s] = make([]EdgeLabel, len(g.succ[s]))
+	}
+	l.Prob = 0
+	g.elabels[s][len(g.succ[s])-1] = l
+}
+
+// EdgeLabelOf returns the label of the i-th edge of s, with its
+// probability filled in.
+func (g *Graph) EdgeLabelOf(s StateID, i int) EdgeLabel {
+	var l EdgeLabel
+	if ls := g.elabels[s]; ls != nil {
+		l = ls[i]
+	}
+	if ps := g.prob[s]; ps != nil {
+		l.Prob = ps[i]
+	}
+	return l
+}
+
+// Actions returns the action of each edge in Succ(s), or nil when no edge
+// of s has a label.
+func (g *Graph) Actions(s StateID) []string {
+	ls := g.elabels[s]
+	if ls == nil {
+		return nil
+	}
+	out := make([]string, len(ls))
+	for i, l := range ls {
+		out[i] = l.Action
+	}
+	return out
+}
+
+// EdgesFrom iterates over the edges leaving s in Succ order.
+func (g *Graph) EdgesFrom(s StateID) iter.Seq[Edge] {
+	return func(yield func(Edge) bool) {
+		for i, t := range g.succ[s] {
+			if !yield(Edge{From: s, To: t, Index: i, EdgeLabel: g.EdgeLabelOf(s, i)}) {
+				return
+			}
+		}
+	}
+}
+
+// Edges iterates over every edge of g, by source state ID and then in
+// Succ order.
+func (g *Graph) Edges() iter.Seq[Edge] {
+	return func(yield func(Edge) bool) {
+		for i := 0; i < g.nextID; i++ {
+			for e := range g.EdgesFrom(StateID(i)) {
+				if !yield(e) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// HasEdgeLabels reports whether any edge has an action, guard or process.
+func (g *Graph) HasEdgeLabels() bool {
+	return len(g.elabels) > 0
+}
+
+// StepLabeler is an optional companion to Process.Ready: StepLabels
+// returns one label per step Ready returns, in the same order, naming the
+// action and guard of each. Explore records them on the graph's edges.
+type StepLabeler interface {
+	StepLabels(w *World) []EdgeLabel
+}
+
+// StepLabels returns the label of each step of EnabledSteps, in the same
+// order. Process is always the ID of the process that offers the step;
+// action and guard come from processes that implement StepLabeler and
+// are empty otherwise.
+func (w *World) StepLabels() []EdgeLabel {
+	var labels []EdgeLabel
+	for _, p := range w.Procs {
+		if p == nil {
+			continue
+		}
+		n := len(p.Ready(w))
+		if n == 0 {
+			continue
+		}
+		var named []EdgeLabel
+		if sl, ok := p.(StepLabeler); ok {
+			named = sl.StepLabels(w)
+		}
+		for i := 0; i < n; i++ {
+			var l EdgeLabel
+			if i < len(named) {
+				l = named[i]
+			}
+			l.Process = p.ID()
+			l.Prob = 0
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
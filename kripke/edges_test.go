@@ -0,0 +1,118 @@
+package kripke
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEdgeIterator(t *testing.T) {
+	g := NewGraph()
+	g.AddEdge("a", "b")
+	g.AddLabeledEdge("a", "c", EdgeLabel{Action: "go", Guard: "x > 0"})
+	g.AddLabeledEdge("b", "c", EdgeLabel{Prob: 1})
+	g.AddLabeledEdge("c", "a", EdgeLabel{Action: "reset"})
+
+	var got []string
+	for e := range g.Edges() {
+		got = append(got, g.NameOf(e.From)+"->"+g.NameOf(e.To)+":"+e.String())
+	}
+	want := []string{"a->b:", "a->c:go [x > 0]", "b->c:", "c->a:reset"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("edges %q, want %q", got, want)
+	}
+	if acts := g.Actions(0); !reflect.DeepEqual(acts, []string{"", "go"}) {
+		t.Fatalf("actions of a: %q", acts)
+	}
+	if g.Actions(1) != nil {
+		t.Fatalf("b has no edge labels, got %q", g.Actions(1))
+	}
+	if l := g.EdgeLabelOf(1, 0); l.Prob != 1 || l.Action != "" {
+		t.Fatalf("label of b->c: %+v", l)
+	}
+
+	// Stopping early must not visit further edges.
+	n := 0
+	for range g.Edges() {
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Fatalf("visited %d edges", n)
+	}
+}
+
+func TestEdgeLabelString(t *testing.T) {
+	for _, c := range []struct {
+		l    EdgeLabel
+		want string
+	}{
+		{EdgeLabel{}, ""},
+		{EdgeLabel{Process: "P"}, "P"},
+		{EdgeLabel{Action: "send", Process: "P"}, "send"},
+		{EdgeLabel{Action: "send", Guard: "n < 3", Prob: 0.5}, "send [n < 3] 0.5"},
+		{EdgeLabel{Prob: 1}, ""},
+	} {
+		if got := c.l.String(); got != c.want {
+			t.Fatalf("%+v: got %q, want %q", c.l, got, c.want)
+		}
+	}
+}
+
+// labeledProducer is a testProducer that names its step.
+type labeledProducer struct{ testProducer }
+
+func (p *labeledProducer) Clone() Process { cp := *p; return &cp }
+
+func (p *labeledProducer) StepLabels(w *World) []EdgeLabel {
+	return []EdgeLabel{{Action: "send", Guard: "inbox not full"}}
+}
+
+func TestExploreRecordsEdgeLabels(t *testing.T) {
+	w := producerConsumerWorld(2, 1)
+	w.Procs[0] = &labeledProducer{*w.Procs[0].(*testProducer)}
+	g, err := Explore(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processes := make(map[string]int)
+	for e := range g.Edges() {
+		processes[e.Process]++
+		switch e.Process {
+		case "P":
+			if e.Action != "send" || e.Guard != "inbox not full" {
+				t.Fatalf("producer edge %+v", e)
+			}
+		case "C":
+			if e.Action != "" {
+				t.Fatalf("consumer edge has an action: %+v", e)
+			}
+		}
+		if e.Prob != 0 {
+			t.Fatalf("explored edge has a probability: %+v", e)
+		}
+	}
+	if processes["P"] != 2 || processes["C"] != 2 || len(processes) != 2 {
+		t.Fatalf("edges per process %v", processes)
+	}
+	if got := g.Alphabet(); !reflect.DeepEqual(got, []string{"send"}) {
+		t.Fatalf("alphabet %v", got)
+	}
+	if d := g.GenerateStateDiagram(); !strings.Contains(d, "send [inbox not full]") {
+		t.Fatalf("state diagram lacks edge labels:\n%s", d)
+	}
+}
+
+func TestMermaidEdgeLabels(t *testing.T) {
+	sg := richGraph().ToSimpleGraph()
+	var buf bytes.Buffer
+	if err := WriteMermaidStateDiagram(sg, "", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "  a --> c d: go 0.75\n") {
+		t.Fatalf("missing labelled edge:\n%s", buf.String())
+	}
+}
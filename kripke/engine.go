@@ -11,7 +11,7 @@ package kripke
 
 import (
 	"fmt"
-	"math/rand"
+	"math/rand/v2"
 	"time"
 )
 
@@ -143,12 +143,19 @@ type World struct {
 	Procs     []Process
 	Channels  map[string]*Channel // key = Address.String()
 	Events    []Event
+	src       *rand.PCG // copied by Clone, so clones draw independently
 	rng       *rand.Rand
 	nextMsgID uint64
+
+	invariants []namedInvariant
+	trace      []int // enabled-step index chosen at each tick
 }
 
 // NewWorld constructs a world with the given processes and channels.
 // rngSeed can be fixed for reproducible runs (if 0, uses current time).
+// The seed feeds a math/rand/v2 PCG; before that it fed math/rand, so a
+// fixed seed now gives a different schedule than it used to, and the
+// output of cmd/demo changed with it.
 func NewWorld(procs []Process, chans []*Channel, rngSeed int64) *World {
 	m := make(map[string]*Channel, len(chans))
 	for _, ch := range chans {
@@ -164,12 +171,14 @@ func NewWorld(procs []Process, chans []*Channel, rngSeed int64) *World {
 	if rngSeed == 0 {
 		rngSeed = time.Now().UnixNano()
 	}
+	src := rand.NewPCG(uint64(rngSeed), 0)
 	return &World{
 		Time:      0,
 		Procs:     procs,
 		Channels:  m,
 		Events:    make([]Event, 0),
-		rng:       rand.New(rand.NewSource(rngSeed)),
+		src:       src,
+		rng:       rand.New(src),
 		nextMsgID: 1,
 	}
 }
@@ -209,20 +218,39 @@ func (w *World) StepRandom() bool {
 	if len(enabled) == 0 {
 		return false
 	}
-	idx := w.rng.Intn(len(enabled))
+	idx := w.rng.IntN(len(enabled))
 	step := enabled[idx]
 	step(w)
 	w.Time++
+	w.trace = append(w.trace, idx)
 	return true
 }
 
 // RunSteps executes up to maxSteps steps or until there are no enabled steps.
-func (w *World) RunSteps(maxSteps int) {
+//
+// Registered invariants are checked before the first step and after every
+// tick; the run stops at the first violation and returns it as a *Violation.
+func (w *World) RunSteps(maxSteps int) error {
+	if v := w.checkInvariants(); v != nil {
+		return v
+	}
 	for i := 0; i < maxSteps; i++ {
 		if !w.StepRandom() {
-			return
+			return nil
+		}
+		if v := w.checkInvariants(); v != nil {
+			return v
 		}
 	}
+	return nil
+}
+
+// Trace returns the enabled-step index chosen at each tick so far.
+// Replay(initial, w.Trace()) rebuilds w from the world it started as.
+func (w *World) Trace() []int {
+	out := make([]int, len(w.trace))
+	copy(out, w.trace)
+	return out
 }
 
 // SendMessage enqueues a message into the receiver's channel and assigns IDs.
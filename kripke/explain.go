@@ -0,0 +1,588 @@
+package kripke
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------- English rendering ----------
+
+// Explain renders f as an English sentence for readers who do not know
+// CTL, e.g. AF(Atom("delivered")) reads "on every path, eventually
+// delivered". Propositions and variables keep their names; operands that
+// are themselves conjunctions, disjunctions or implications are put in
+// parentheses.
+func Explain(f Formula) string {
+	switch f := f.(type) {
+	case TrueFormula:
+		return "true"
+	case AtomFormula:
+		return f.Prop
+	case CompareFormula:
+		return f.Left.String() + " " + compareWords[f.Op] + " " + f.Right.String()
+	case NotFormula:
+		return explainNot(f.Inner)
+	case AndFormula:
+		return clause(f.Left) + " and " + clause(f.Right)
+	case OrFormula:
+		if a, ok := f.Left.(NotFormula); ok {
+			return "if " + clause(a.Inner) + ", then " + clause(f.Right)
+		}
+		return clause(f.Left) + " or " + clause(f.Right)
+	case EXFormula:
+		return "on some path, next " + clause(f.Inner)
+	case AXFormula:
+		return "on every path, next " + clause(f.Inner)
+	case EFFormula:
+		return "on some path, eventually " + clause(f.Inner)
+	case AFFormula:
+		return "on every path, eventually " + clause(f.Inner)
+	case EGFormula:
+		return "on some path, always " + clause(f.Inner)
+	case AGFormula:
+		return "on every path, always " + clause(f.Inner)
+	case EUFormula:
+		return "on some path, " + clause(f.Phi) + " until " + clause(f.Psi)
+	case DiamondFormula:
+		return "after some " + explainAction(f.Action) + "step, " + clause(f.Inner)
+	case BoxFormula:
+		return "after every " + explainAction(f.Action) + "step, " + clause(f.Inner)
+	case MuFormula:
+		return "the least " + f.Var + " such that " + f.Var + " means " + clause(f.Body)
+	case NuFormula:
+		return "the greatest " + f.Var + " such that " + f.Var + " means " + clause(f.Body)
+	case VarFormula:
+		return f.Name
+	case EFormula:
+		return "on some path, " + explainPath(f.Path)
+	case AFormula:
+		return "on every path, " + explainPath(f.Path)
+	case YFormula:
+		return "in the previous state, " + clause(f.Inner)
+	case OFormula:
+		return "at some point so far, " + clause(f.Inner)
+	case HFormula:
+		return "at every point so far, " + clause(f.Inner)
+	case SFormula:
+		return clause(f.Left) + " since " + clause(f.Right)
+	case HistoryFormula:
+		return Explain(f.Inner)
+	case setFormula:
+		return "one of a fixed set of states"
+	}
+	return fmt.Sprintf("%T", f)
+}
+
+var compareWords = map[string]string{
+	"==": "equals", "!=": "differs from", "<": "is less than",
+	"<=": "is at most", ">": "is greater than", ">=": "is at least",
+}
+
+// clause is Explain(f), in parentheses when f is a connective that would
+// otherwise run into the surrounding sentence.
+func clause(f Formula) string {
+	switch f.(type) {
+	case AndFormula, OrFormula:
+		return "(" + Explain(f) + ")"
+	}
+	return Explain(f)
+}
+
+func explainNot(inner Formula) string {
+	if phi, psi, ok := matchAU(NotFormula{Inner: inner}); ok {
+		return "on every path, " + clause(phi) + " until " + clause(psi)
+	}
+	switch inner := inner.(type) {
+	case TrueFormula:
+		return "false"
+	case AtomFormula:
+		return "not " + inner.Prop
+	case NotFormula:
+		return Explain(inner.Inner)
+	case EFFormula:
+		return "on every path, never " + clause(inner.Inner)
+	}
+	return "not (" + Explain(inner) + ")"
+}
+
+// matchAU recognizes the expansion AU builds:
+//
+//	¬(E[¬ψ U (¬φ ∧ ¬ψ)] ∨ EG ¬ψ)
+func matchAU(f Formula) (phi, psi Formula, ok bool) {
+	n, ok := f.(NotFormula)
+	if !ok {
+		return nil, nil, false
+	}
+	or, ok := n.Inner.(OrFormula)
+	if !ok {
+		return nil, nil, false
+	}
+	eu, ok1 := or.Left.(EUFormula)
+	eg, ok2 := or.Right.(EGFormula)
+	if !ok1 || !ok2 {
+		return nil, nil, false
+	}
+	notPsi, ok1 := eu.Phi.(NotFormula)
+	both, ok2 := eu.Psi.(AndFormula)
+	if !ok1 || !ok2 {
+		return nil, nil, false
+	}
+	notPhi, ok := both.Left.(NotFormula)
+	if !ok {
+		return nil, nil, false
+	}
+	k := fmt.Sprintf("%#v", notPsi)
+	if fmt.Sprintf("%#v", both.Right) != k || fmt.Sprintf("%#v", eg.Inner) != k {
+		return nil, nil, false
+	}
+	return notPhi.Inner, notPsi.Inner, true
+}
+
+// explainAction renders an action formula followed by a space, or
+// nothing for any action.
+func explainAction(a ActionFormula) string {
+	var rec func(a ActionFormula) string
+	rec = func(a ActionFormula) string {
+		switch a := a.(type) {
+		case ActName:
+			return a.Name
+		case ActAny:
+			return "any"
+		case ActNot:
+			return "non-" + rec(a.Inner)
+		case ActOr:
+			return rec(a.Left) + " or " + rec(a.Right)
+		case ActAnd:
+			return rec(a.Left) + " and " + rec(a.Right)
+		}
+		return fmt.Sprintf("%T", a)
+	}
+	if _, ok := a.(ActAny); ok {
+		return ""
+	}
+	return rec(a) + " "
+}
+
+func explainPath(p PathFormula) string {
+	pathClause := func(p PathFormula) string {
+		switch p.(type) {
+		case PathAnd, PathOr:
+			return "(" + explainPath(p) + ")"
+		}
+		return explainPath(p)
+	}
+	switch p := p.(type) {
+	case PathState:
+		return clause(p.State)
+	case PathNext:
+		return "next " + pathClause(p.Inner)
+	case PathUntil:
+		if s, ok := p.Left.(PathState); ok {
+			if _, ok := s.State.(TrueFormula); ok {
+				return "eventually " + pathClause(p.Right)
+			}
+		}
+		return pathClause(p.Left) + " until " + pathClause(p.Right)
+	case PathAnd:
+		return pathClause(p.Left) + " and " + pathClause(p.Right)
+	case PathOr:
+		if a, ok := p.Left.(PathNot); ok {
+			return "if " + pathClause(a.Inner) + ", then " + pathClause(p.Right)
+		}
+		return pathClause(p.Left) + " or " + pathClause(p.Right)
+	case PathNot:
+		if u, ok := p.Inner.(PathUntil); ok {
+			l, ok1 := u.Left.(PathState)
+			r, ok2 := u.Right.(PathNot)
+			if ok1 && ok2 {
+				if _, ok := l.State.(TrueFormula); ok {
+					return "always " + pathClause(r.Inner)
+				}
+			}
+			nl, ok1 := u.Left.(PathNot)
+			if ok1 && ok2 {
+				return pathClause(r.Inner) + " until and including " + pathClause(nl.Inner) + ", or forever"
+			}
+		}
+		return "not " + pathClause(p.Inner)
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// ---------- Evidence ----------
+
+// Evidence explains in words why a formula holds or fails in one state.
+type Evidence struct {
+	State StateID
+	Holds bool
+	// Path is the witness or counterexample path starting at State, when
+	// the explanation has one. If Loop is not -1 the path goes on forever
+	// by stepping from its last state back to Path[Loop].
+	Path []StateID
+	Loop int
+	// Narrative is a few sentences built from the path and state names.
+	Narrative string
+}
+
+// EvidenceAt explains f in state s. A failing formula is explained by
+// the evidence for its negation: a reachable bad state for AG, a path
+// that never gets there for AF, and so on. Operators without a path-based
+// explanation (CTL*, past and fixpoint formulas) get a one-line
+// statement of the result.
+func (g *Graph) EvidenceAt(f Formula, s StateID) Evidence {
+	holds := f.Sat(g).Contains(s)
+	e := &evidence{g: g, out: Evidence{State: s, Holds: holds, Loop: -1}}
+	target := f
+	if !holds {
+		target = negate(f)
+	}
+	e.out.Narrative = strings.Join(e.show(target, s), " ")
+	return e.out
+}
+
+// Counterexamples returns the evidence for every initial state where f
+// fails, in the order of InitialStates.
+func (g *Graph) Counterexamples(f Formula) []Evidence {
+	sat := f.Sat(g)
+	var out []Evidence
+	for _, s := range g.InitialStates() {
+		if !sat.Contains(s) {
+			out = append(out, g.EvidenceAt(f, s))
+		}
+	}
+	return out
+}
+
+type evidence struct {
+	g       *Graph
+	out     Evidence
+	hasPath bool
+}
+
+// record keeps the first path found, which belongs to the outermost
+// operator, as the evidence's path.
+func (e *evidence) record(path []StateID, loop int) {
+	if !e.hasPath {
+		e.hasPath = true
+		e.out.Path, e.out.Loop = path, loop
+	}
+}
+
+func (e *evidence) name(s StateID) string { return e.g.NameOf(s) }
+
+// show returns sentences explaining why f holds in s; f must hold there.
+func (e *evidence) show(f Formula, s StateID) []string {
+	g := e.g
+	switch f := f.(type) {
+	case TrueFormula:
+		return nil
+	case AtomFormula:
+		return []string{fmt.Sprintf("%s is %s.", e.name(s), f.Prop)}
+	case CompareFormula:
+		return []string{fmt.Sprintf("In %s, %s%s.", e.name(s), Explain(f), e.values(f, s))}
+	case NotFormula:
+		return e.showNot(f.Inner, s)
+	case AndFormula:
+		return append(e.show(f.Left, s), e.show(f.Right, s)...)
+	case OrFormula:
+		if a, ok := f.Left.(NotFormula); ok && !a.Inner.Sat(g).Contains(s) {
+			return e.showNot(a.Inner, s)
+		}
+		if f.Left.Sat(g).Contains(s) {
+			return e.show(f.Left, s)
+		}
+		return e.show(f.Right, s)
+	case EXFormula:
+		t := e.firstSucc(s, f.Inner.Sat(g))
+		e.record([]StateID{s, t}, -1)
+		return append([]string{fmt.Sprintf("%s can step to %s.", e.name(s), e.name(t))}, e.show(f.Inner, t)...)
+	case AXFormula:
+		if len(g.Succ(s)) == 0 {
+			return []string{fmt.Sprintf("%s has no successors.", e.name(s))}
+		}
+		return []string{fmt.Sprintf("Every successor of %s (%s) satisfies %s.", e.name(s), e.names(g.Succ(s)), Explain(f.Inner))}
+	case EFFormula:
+		return e.reach(s, nil, f.Inner)
+	case EUFormula:
+		return e.reach(s, f.Phi, f.Psi)
+	case EGFormula:
+		path, loop := e.lasso(s, f.Sat(g))
+		e.record(path, loop)
+		if n, ok := f.Inner.(NotFormula); ok {
+			return []string{fmt.Sprintf("The path %s never reaches a state where %s.", e.lassoText(path, loop), Explain(n.Inner))}
+		}
+		return []string{fmt.Sprintf("The path %s stays where %s forever.", e.lassoText(path, loop), Explain(f.Inner))}
+	case AGFormula:
+		n := len(e.reachable(s))
+		return []string{fmt.Sprintf("All %d states reachable from %s satisfy %s.", n, e.name(s), Explain(f.Inner))}
+	case AFFormula:
+		return []string{fmt.Sprintf("Every path from %s eventually reaches a state where %s.", e.name(s), Explain(f.Inner))}
+	}
+	return []string{fmt.Sprintf("%s satisfies: %s.", e.name(s), Explain(f))}
+}
+
+// showNot returns sentences explaining why f fails in s.
+func (e *evidence) showNot(f Formula, s StateID) []string {
+	g := e.g
+	if phi, psi, ok := matchAU(NotFormula{Inner: f}); ok {
+		// f = ¬A[φ U ψ]: either ψ is avoided until φ breaks, or forever.
+		escape := EU(Not(psi), And(Not(phi), Not(psi)))
+		if escape.Sat(g).Contains(s) {
+			return e.show(escape, s)
+		}
+		return e.show(EG(Not(psi)), s)
+	}
+	switch f := f.(type) {
+	case TrueFormula:
+		return nil
+	case AtomFormula:
+		return []string{fmt.Sprintf("%s is not %s.", e.name(s), f.Prop)}
+	case CompareFormula:
+		return []string{fmt.Sprintf("In %s, it is not the case that %s%s.", e.name(s), Explain(f), e.values(f, s))}
+	case NotFormula:
+		return e.show(f.Inner, s)
+	case AndFormula:
+		if !f.Left.Sat(g).Contains(s) {
+			return e.showNot(f.Left, s)
+		}
+		return e.showNot(f.Right, s)
+	case OrFormula:
+		if a, ok := f.Left.(NotFormula); ok {
+			return append(e.show(a.Inner, s), e.showNot(f.Right, s)...)
+		}
+		return append(e.showNot(f.Left, s), e.showNot(f.Right, s)...)
+	case EXFormula:
+		if len(g.Succ(s)) == 0 {
+			return []string{fmt.Sprintf("%s has no successors.", e.name(s))}
+		}
+		return []string{fmt.Sprintf("No successor of %s (%s) satisfies %s.", e.name(s), e.names(g.Succ(s)), Explain(f.Inner))}
+	case AXFormula:
+		if len(g.Succ(s)) == 0 {
+			return []string{fmt.Sprintf("%s has no successors.", e.name(s))}
+		}
+		return e.show(EX(negate(f.Inner)), s)
+	case EFFormula:
+		n := len(e.reachable(s))
+		return []string{fmt.Sprintf("None of the %d states reachable from %s satisfies %s.", n, e.name(s), Explain(f.Inner))}
+	case EUFormula:
+		return []string{fmt.Sprintf("No path from %s reaches a state where %s while %s holds on the way.", e.name(s), Explain(f.Psi), Explain(f.Phi))}
+	case AGFormula:
+		return e.show(EF(negate(f.Inner)), s)
+	case AFFormula:
+		return e.show(EG(negate(f.Inner)), s)
+	case EGFormula:
+		return []string{fmt.Sprintf("Every path from %s eventually reaches a state where not %s, or deadlocks.", e.name(s), clause(f.Inner))}
+	}
+	return []string{fmt.Sprintf("%s does not satisfy: %s.", e.name(s), Explain(f))}
+}
+
+// reach explains E[φ U ψ] (EF ψ when phi is nil) with a shortest path
+// through φ states to a ψ state.
+func (e *evidence) reach(s StateID, phi, psi Formula) []string {
+	g := e.g
+	var through StateSet
+	if phi != nil {
+		through = phi.Sat(g)
+	}
+	goal := psi.Sat(g)
+	path := e.shortestPath(s, through, goal)
+	e.record(path, -1)
+	end := path[len(path)-1]
+	sentence := fmt.Sprintf("The path %s reaches %s, where %s.", e.pathText(path), e.name(end), where(psi))
+	if len(path) == 1 {
+		sentence = fmt.Sprintf("In %s itself, %s.", e.name(s), where(psi))
+	}
+	out := []string{sentence}
+	if phi != nil && len(path) > 1 {
+		out = append(out, fmt.Sprintf("Every state before it satisfies %s.", Explain(phi)))
+	}
+	if isLiteral(psi) {
+		return out
+	}
+	return append(out, e.show(psi, end)...)
+}
+
+// where phrases "f holds", turning a negated compound formula into
+// "... does not hold" rather than "not (...)".
+func where(f Formula) string {
+	if n, ok := f.(NotFormula); ok && !isLiteral(n.Inner) {
+		return "\"" + Explain(n.Inner) + "\" does not hold"
+	}
+	return Explain(f)
+}
+
+// negate is Not(f) without a double negation.
+func negate(f Formula) Formula {
+	if n, ok := f.(NotFormula); ok {
+		return n.Inner
+	}
+	return Not(f)
+}
+
+// isLiteral reports whether f is true, a proposition, a comparison or the
+// negation of one, which need no further explanation.
+func isLiteral(f Formula) bool {
+	if n, ok := f.(NotFormula); ok {
+		f = n.Inner
+	}
+	switch f.(type) {
+	case TrueFormula, AtomFormula, CompareFormula:
+		return true
+	}
+	return false
+}
+
+// values lists the values of the variables a comparison reads in s.
+func (e *evidence) values(f CompareFormula, s StateID) string {
+	var parts []string
+	for _, x := range []Expr{f.Left, f.Right} {
+		if _, ok := x.(ConstExpr); ok {
+			continue
+		}
+		if v, ok := x.Eval(e.g, s); ok {
+			parts = append(parts, fmt.Sprintf("%s is %v", x, v))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s is undefined", x))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// ----- paths -----
+
+func (e *evidence) firstSucc(s StateID, in StateSet) StateID {
+	for _, t := range e.g.Succ(s) {
+		if in.Contains(t) {
+			return t
+		}
+	}
+	panic("kripke: no successor in set")
+}
+
+// shortestPath is a breadth-first path from s to a goal state whose
+// states before the goal are all in through (any state if through is
+// nil).
+func (e *evidence) shortestPath(s StateID, through, goal StateSet) []StateID {
+	parent := map[StateID]StateID{s: s}
+	queue := []StateID{s}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		if goal.Contains(u) {
+			path := []StateID{u}
+			for u != s {
+				u = parent[u]
+				path = append([]StateID{u}, path...)
+			}
+			return path
+		}
+		if through != nil && !through.Contains(u) {
+			continue
+		}
+		for _, t := range e.g.Succ(u) {
+			if _, ok := parent[t]; !ok {
+				parent[t] = u
+				queue = append(queue, t)
+			}
+		}
+	}
+	panic("kripke: goal not reachable")
+}
+
+// lasso follows the first successor inside in from s until a state
+// repeats. Every state of in must have a successor in in, as the states
+// satisfying an EG formula do.
+func (e *evidence) lasso(s StateID, in StateSet) ([]StateID, int) {
+	seen := make(map[StateID]int)
+	var path []StateID
+	for {
+		if i, ok := seen[s]; ok {
+			return path, i
+		}
+		seen[s] = len(path)
+		path = append(path, s)
+		s = e.firstSucc(s, in)
+	}
+}
+
+func (e *evidence) reachable(s StateID) StateSet {
+	seen := NewStateSet()
+	seen.Add(s)
+	stack := []StateID{s}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, t := range e.g.Succ(u) {
+			if !seen.Contains(t) {
+				seen.Add(t)
+				stack = append(stack, t)
+			}
+		}
+	}
+	return seen
+}
+
+// pathText renders a path as "a -> b -send-> c", naming the action of
+// each step that has one.
+func (e *evidence) pathText(path []StateID) string {
+	var sb strings.Builder
+	sb.WriteString(e.name(path[0]))
+	for i := 1; i < len(path); i++ {
+		sb.WriteString(e.step(path[i-1], path[i]))
+		sb.WriteString(e.name(path[i]))
+	}
+	return sb.String()
+}
+
+func (e *evidence) lassoText(path []StateID, loop int) string {
+	return e.pathText(append(path[:len(path):len(path)], path[loop])) + " -> ..."
+}
+
+func (e *evidence) step(from, to StateID) string {
+	for edge := range e.g.EdgesFrom(from) {
+		if edge.To == to && edge.Action != "" {
+			return " -" + edge.Action + "-> "
+		}
+	}
+	return " -> "
+}
+
+func (e *evidence) names(ids []StateID) string {
+	names := make([]string, len(ids))
+	for i, s := range ids {
+		names[i] = e.name(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ---------- Report ----------
+
+// GenerateEvidenceReport generates a markdown section per requirement
+// with its formula in English and, for each initial state where the
+// formula fails, the evidence why. Formulas are read from FormulaString
+// with ParseCTL.
+func (g *Graph) GenerateEvidenceReport(requirements []Requirement) string {
+	var sb strings.Builder
+	for _, req := range requirements {
+		fmt.Fprintf(&sb, "### %s: %s\n\n", req.ID, req.Description)
+		f, err := ParseCTL(req.FormulaString)
+		if err != nil {
+			fmt.Fprintf(&sb, "`%s` could not be parsed: %v\n\n", req.FormulaString, err)
+			continue
+		}
+		fmt.Fprintf(&sb, "`%s`: %s.\n\n", req.FormulaString, Explain(f))
+		cex := g.Counterexamples(f)
+		if len(cex) == 0 {
+			sb.WriteString("✅ Holds in every initial state.\n\n")
+			continue
+		}
+		for _, ev := range cex {
+			fmt.Fprintf(&sb, "- ❌ **%s**: %s\n", g.NameOf(ev.State), ev.Narrative)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,106 @@
+package kripke
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	for src, want := range map[string]string{
+		"AF delivered": "on every path, eventually delivered",
+		"AG(accepted -> AF(delivered | cancelled))": "on every path, always (if accepted, then on every path, eventually (delivered or cancelled))",
+		"AG !(delivered & cancelled)":               "on every path, always not (delivered and cancelled)",
+		"!EF error":                                 "on every path, never error",
+		"A[waiting U served]":                       "on every path, waiting until served",
+		"E[!done U stock >= 1]":                     "on some path, not done until stock is at least 1",
+		"EX false":                                  "on some path, next false",
+		"E(GF p & FG q)":                            "on some path, always eventually p and eventually always q",
+		"AG(reply -> O request)":                    "on every path, always (if reply, then at some point so far, request)",
+	} {
+		if got := Explain(MustParseCTL(src)); got != want {
+			t.Fatalf("%s:\n got %q\nwant %q", src, got, want)
+		}
+	}
+	if got, want := Explain(MustParseMu("<send_order>[!cancel]Idle")), "after some send_order step, after every non-cancel step, Idle"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCounterexamples(t *testing.T) {
+	g := orderProcess(true)
+	name := func(ids []StateID) []string {
+		var out []string
+		for _, s := range ids {
+			out = append(out, g.NameOf(s))
+		}
+		return out
+	}
+
+	cex := g.Counterexamples(MustParseCTL("AG !Stuck"))
+	if len(cex) != 1 {
+		t.Fatalf("got %d counterexamples", len(cex))
+	}
+	ev := cex[0]
+	if got := name(ev.Path); !slices.Equal(got, []string{"Idle", "Sent", "Packed", "Stuck"}) || ev.Loop != -1 {
+		t.Fatalf("path %v loop %d", got, ev.Loop)
+	}
+	if !strings.Contains(ev.Narrative, "-lose-> Stuck") {
+		t.Fatalf("narrative %q does not name the lose step", ev.Narrative)
+	}
+
+	ev = g.Counterexamples(MustParseCTL("AF Packed"))[0]
+	if got := name(ev.Path); !slices.Equal(got, []string{"Idle", "Sent"}) || ev.Loop != 0 {
+		t.Fatalf("AF Packed: path %v loop %d, want the cancel loop", got, ev.Loop)
+	}
+	if !strings.Contains(ev.Narrative, "never reaches a state where Packed") {
+		t.Fatalf("narrative %q", ev.Narrative)
+	}
+
+	if cex := g.Counterexamples(MustParseCTL("EF Stuck")); len(cex) != 0 {
+		t.Fatalf("EF Stuck holds, got %v", cex)
+	}
+
+	report := g.GenerateEvidenceReport([]Requirement{
+		{ID: "R1", Description: "orders are never lost", FormulaString: "AG !Stuck"},
+		{ID: "R2", Description: "orders can be lost", FormulaString: "EF Stuck"},
+	})
+	for _, want := range []string{"on every path, always not Stuck", "❌ **Idle**", "✅ Holds"} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
+
+// Every evidence path is a real path from its state, and a loop closes
+// with a real edge.
+func TestEvidencePaths(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	edge := func(g *Graph, s, t StateID) bool { return slices.Contains(g.Succ(s), t) }
+	for i := 0; i < 300; i++ {
+		g := randomGraph(rng, 1+rng.Intn(8), rng.Intn(16))
+		f := randomFormula(rng, 3, true)
+		sat := f.Sat(g)
+		for _, s := range g.States() {
+			ev := g.EvidenceAt(f, s)
+			if ev.Holds != sat.Contains(s) || !ev.Holds && ev.Narrative == "" {
+				t.Fatalf("%#v at %d: %+v", f, s, ev)
+			}
+			if len(ev.Path) == 0 {
+				continue
+			}
+			if ev.Path[0] != s {
+				t.Fatalf("%#v at %d: path %v does not start there", f, s, ev.Path)
+			}
+			for j := 1; j < len(ev.Path); j++ {
+				if !edge(g, ev.Path[j-1], ev.Path[j]) {
+					t.Fatalf("%#v at %d: %v is not a path", f, s, ev.Path)
+				}
+			}
+			if ev.Loop >= 0 && !edge(g, ev.Path[len(ev.Path)-1], ev.Path[ev.Loop]) {
+				t.Fatalf("%#v at %d: loop of %v to %d is not an edge", f, s, ev.Path, ev.Loop)
+			}
+		}
+	}
+}
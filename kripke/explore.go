@@ -0,0 +1,361 @@
+package kripke
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ---------- World snapshots ----------
+
+// Cloner is implemented by processes that can be copied, so the explorer can
+// branch one World into each of its enabled successors. Clone must return a
+// deep copy: mutating the clone must not affect the original.
+type Cloner interface {
+	Clone() Process
+}
+
+// StateKeyer lets a process supply its own canonical state encoding.
+// Processes that don't implement it are keyed by their %+v rendering,
+// which is fine for plain value fields but not for pointers or maps
+// with unstable ordering.
+type StateKeyer interface {
+	StateKey() string
+}
+
+// Clone returns a deep copy of the world. Every process must implement
+// Cloner. The clone gets a copy of the parent's random source, in its
+// current state: both go on to draw the same numbers, independently.
+func (w *World) Clone() (*World, error) {
+	procs := make([]Process, len(w.Procs))
+	for i, p := range w.Procs {
+		if p == nil {
+			continue
+		}
+		c, ok := p.(Cloner)
+		if !ok {
+			return nil, fmt.Errorf("Clone: process %s (%T) does not implement Cloner", p.ID(), p)
+		}
+		procs[i] = c.Clone()
+	}
+	chans := make(map[string]*Channel, len(w.Channels))
+	for k, ch := range w.Channels {
+		cp := *ch
+		cp.buf = append([]Message(nil), ch.buf...)
+		chans[k] = &cp
+	}
+	src := *w.src
+	return &World{
+		Time:      w.Time,
+		Procs:     procs,
+		Channels:  chans,
+		Events:    append([]Event(nil), w.Events...),
+		src:       &src,
+		rng:       rand.New(&src),
+		nextMsgID: w.nextMsgID,
+
+		invariants: w.invariants,
+		trace:      append([]int(nil), w.trace...),
+	}, nil
+}
+
+// StateKey returns a canonical encoding of the semantic state of the world:
+// every process state plus the payloads queued on every channel.
+//
+// Time, the event log and message IDs are deliberately left out; they grow
+// without bound and would make every World distinct.
+func (w *World) StateKey() string {
+	var sb strings.Builder
+	for _, p := range w.Procs {
+		if p == nil {
+			continue
+		}
+		sb.WriteString(p.ID())
+		sb.WriteString("{")
+		sb.WriteString(processKey(p))
+		sb.WriteString("}\n")
+	}
+	names := make([]string, 0, len(w.Channels))
+	for k := range w.Channels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		sb.WriteString(k)
+		sb.WriteString("[")
+		for i, m := range w.Channels[k].buf {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			fmt.Fprintf(&sb, "%s:%v", m.From, m.Payload)
+		}
+		sb.WriteString("]\n")
+	}
+	return sb.String()
+}
+
+func processKey(p Process) string {
+	if k, ok := p.(StateKeyer); ok {
+		return k.StateKey()
+	}
+	v := reflect.ValueOf(p)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// ---------- Explorer ----------
+
+// ErrStateLimit is returned when exploration stops at the WithMaxStates bound.
+// The graph built so far is still returned alongside it.
+var ErrStateLimit = errors.New("explore: state limit reached")
+
+// ExploreOption configures Explore and Resume.
+type ExploreOption func(*exploreOptions)
+
+type exploreOptions struct {
+	store           StateStore
+	props           Propositions
+	vars            Variables
+	maxStates       int
+	checkpointPath  string
+	checkpointEvery int
+}
+
+// WithStateStore sets the visited-set implementation. The default is a
+// fresh MemoryStore.
+func WithStateStore(s StateStore) ExploreOption {
+	return func(opts *exploreOptions) {
+		opts.store = s
+	}
+}
+
+// WithMaxStates bounds the number of distinct states explored (0 = no bound).
+func WithMaxStates(n int) ExploreOption {
+	return func(opts *exploreOptions) {
+		opts.maxStates = n
+	}
+}
+
+// WithCheckpoint writes a checkpoint to path after every 'every' expanded
+// states, so an interrupted exploration can be continued with Resume.
+func WithCheckpoint(path string, every int) ExploreOption {
+	return func(opts *exploreOptions) {
+		opts.checkpointPath = path
+		opts.checkpointEvery = every
+	}
+}
+
+// frontierItem is an explored-but-not-expanded state. The trace records
+// which enabled step was taken at each tick from the initial World; the
+// state is rebuilt from it by Replay when it is expanded, so the frontier
+// holds no Worlds.
+type frontierItem struct {
+	id    StateID
+	trace []int
+}
+
+// explorer holds the state of one breadth-first exploration.
+type explorer struct {
+	opts     *exploreOptions
+	root     *World
+	g        *Graph
+	queue    []frontierItem
+	expanded int
+}
+
+func newExplorer(options []ExploreOption) *explorer {
+	opts := &exploreOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	if opts.store == nil {
+		opts.store = NewMemoryStore()
+	}
+	return &explorer{opts: opts, g: NewGraph()}
+}
+
+// Explore enumerates every World reachable from w by breadth-first search
+// and returns the resulting Kripke graph. States are named "w0", "w1", ...
+// in discovery order, with w0 the (only) initial state, and labelled with
+// the propositions and variables given by WithPropositions and WithVariables.
+// Each edge is labelled with the process that took the step and, when that
+// process is a StepLabeler, the step's action and guard.
+//
+// Two Worlds are the same state when their StateKey matches. The original
+// w is not modified.
+//
+// Invariants registered on w are checked on every new state; exploration
+// stops at the first failure and returns the partial graph with a *Violation.
+func Explore(w *World, options ...ExploreOption) (*Graph, error) {
+	e := newExplorer(options)
+	root, err := w.Clone()
+	if err != nil {
+		return nil, err
+	}
+	e.root = root
+	id, _, err := e.visit(root)
+	if err != nil {
+		return nil, err
+	}
+	e.g.SetInitial(e.g.NameOf(id))
+	if v := root.checkInvariants(); v != nil {
+		return e.g, e.violation(v, nil)
+	}
+	e.queue = []frontierItem{{id: id}}
+	return e.g, e.run()
+}
+
+func (e *explorer) run() error {
+	opts := e.opts
+	for len(e.queue) > 0 {
+		if opts.checkpointEvery > 0 && e.expanded > 0 && e.expanded%opts.checkpointEvery == 0 {
+			if err := e.checkpoint(); err != nil {
+				return err
+			}
+		}
+		item := e.queue[0]
+		e.queue = e.queue[1:]
+
+		world, err := Replay(e.root, item.trace)
+		if err != nil {
+			return err
+		}
+		// Steps are bound to the processes of the World that offered
+		// them, so each successor asks its own clone for them again;
+		// only the labels are taken from world.
+		labels := world.StepLabels()
+		n := len(labels)
+		for i := 0; i < n; i++ {
+			next, err := successor(world, i)
+			if err != nil {
+				return err
+			}
+			if opts.maxStates > 0 && e.g.nextID >= opts.maxStates {
+				if _, ok, err := e.lookup(next.StateKey()); err != nil {
+					return err
+				} else if !ok {
+					return ErrStateLimit
+				}
+			}
+			to, isNew, err := e.visit(next)
+			if err != nil {
+				return err
+			}
+			e.g.AddLabeledEdge(e.g.NameOf(item.id), e.g.NameOf(to), labels[i])
+			if isNew {
+				trace := append(append([]int(nil), item.trace...), i)
+				if v := next.checkInvariants(); v != nil {
+					return e.violation(v, trace)
+				}
+				e.queue = append(e.queue, frontierItem{id: to, trace: trace})
+			}
+		}
+		e.expanded++
+	}
+	return nil
+}
+
+// lookup consults the store, ignoring entries for states the graph does not
+// have yet. Those are left behind in a persistent store by a run that was
+// interrupted after its last checkpoint.
+func (e *explorer) lookup(key string) (StateID, bool, error) {
+	id, ok, err := e.opts.store.Lookup(key)
+	if err != nil || !ok || int(id) < e.g.nextID {
+		return id, ok, err
+	}
+	return id, false, nil
+}
+
+// visit looks up w in the store, adding it to both the store and the graph
+// if it has not been seen before.
+func (e *explorer) visit(w *World) (StateID, bool, error) {
+	key := w.StateKey()
+	stale, found, err := e.opts.store.Lookup(key)
+	if err != nil {
+		return 0, false, err
+	}
+	if found && int(stale) < e.g.nextID {
+		return stale, false, nil
+	}
+	id := e.g.AddState(fmt.Sprintf("w%d", e.g.nextID), e.opts.props.Label(w))
+	if len(e.opts.vars) > 0 {
+		e.g.SetVars(id, e.opts.vars.Eval(w))
+	}
+	if found {
+		// Exploration order is deterministic, so a resumed run rediscovers
+		// the interrupted run's states with the same IDs.
+		if stale != id {
+			return id, false, fmt.Errorf("explore: state store out of sync with checkpoint (state %d recorded as %d)", id, stale)
+		}
+		return id, true, nil
+	}
+	if err := e.opts.store.Insert(key, id); err != nil {
+		return id, false, err
+	}
+	return id, true, nil
+}
+
+// violation rebases v onto the exploration root and names the graph states
+// along its trace.
+func (e *explorer) violation(v *Violation, trace []int) *Violation {
+	v.Trace = trace
+	cur := e.root
+	v.States = []string{e.g.NameOf(e.g.init[0])}
+	for _, i := range trace {
+		next, err := successor(cur, i)
+		if err != nil {
+			break
+		}
+		cur = next
+		id, ok, err := e.lookup(cur.StateKey())
+		if err != nil || !ok {
+			break
+		}
+		v.States = append(v.States, e.g.NameOf(id))
+	}
+	return v
+}
+
+// successor clones w and fires its i-th enabled step on the clone.
+func successor(w *World, i int) (*World, error) {
+	next, err := w.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if err := next.fire(i); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// fire takes the i-th enabled step of w.
+func (w *World) fire(i int) error {
+	steps := w.EnabledSteps()
+	if i >= len(steps) {
+		return fmt.Errorf("explore: step %d not enabled (%d enabled)", i, len(steps))
+	}
+	steps[i](w)
+	w.Time++
+	w.trace = append(w.trace, i)
+	return nil
+}
+
+// Replay rebuilds the World reached from w by taking the given enabled-step
+// indices in order. The original w is not modified.
+func Replay(w *World, trace []int) (*World, error) {
+	cur, err := w.Clone()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range trace {
+		if err := cur.fire(i); err != nil {
+			return nil, err
+		}
+	}
+	return cur, nil
+}
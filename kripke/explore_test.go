@@ -0,0 +1,295 @@
+package kripke
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testProducer sends 1..max to target, one message per step.
+type testProducer struct {
+	id     string
+	target Address
+	next   int
+	max    int
+}
+
+func (p *testProducer) ID() string       { return p.id }
+func (p *testProducer) Clone() Process   { cp := *p; return &cp }
+func (p *testProducer) StateKey() string { return fmt.Sprintf("next=%d", p.next) }
+
+func (p *testProducer) Ready(w *World) []Step {
+	ch := w.ChannelByAddress(p.target)
+	if p.next > p.max || !ch.CanSend() {
+		return nil
+	}
+	return []Step{func(w *World) {
+		SendMessage(w, Message{From: Address{p.id, "out"}, To: p.target, Payload: p.next})
+		p.next++
+	}}
+}
+
+// testConsumer drains its inbox and counts what it received.
+type testConsumer struct {
+	id    string
+	inbox Address
+	count int
+}
+
+func (c *testConsumer) ID() string     { return c.id }
+func (c *testConsumer) Clone() Process { cp := *c; return &cp }
+
+func (c *testConsumer) Ready(w *World) []Step {
+	ch := w.ChannelByAddress(c.inbox)
+	if !ch.CanRecv() {
+		return nil
+	}
+	return []Step{func(w *World) {
+		RecvAndLog(w, ch)
+		c.count++
+	}}
+}
+
+// producerConsumerWorld sends n messages through a channel of capacity cap.
+func producerConsumerWorld(n, cap int) *World {
+	inbox := NewChannel("C", "inbox", cap)
+	p := &testProducer{id: "P", target: inbox.Address(), next: 1, max: n}
+	c := &testConsumer{id: "C", inbox: inbox.Address()}
+	return NewWorld([]Process{p, c}, []*Channel{inbox}, 1)
+}
+
+func TestExploreProducerConsumer(t *testing.T) {
+	w := producerConsumerWorld(3, 2)
+	g, err := Explore(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// States are (sent, received) with 0 <= sent-received <= 2 and
+	// received <= sent <= 3: 10 pairs, minus (3,0) which would need 3 queued.
+	if got := len(g.States()); got != 9 {
+		t.Fatalf("expected 9 states, got %d", got)
+	}
+	if w.Time != 0 || len(w.Events) != 0 {
+		t.Fatalf("Explore must not modify the input world")
+	}
+
+	// Exactly one terminal state: everything sent and received.
+	terminal := 0
+	for _, s := range g.States() {
+		if len(g.Succ(s)) == 0 {
+			terminal++
+		}
+	}
+	if terminal != 1 {
+		t.Fatalf("expected 1 terminal state, got %d", terminal)
+	}
+}
+
+func TestCloneRandomRuns(t *testing.T) {
+	// A clone continues the original's random sequence without sharing it,
+	// so both take the same steps.
+	w := producerConsumerWorld(5, 2)
+	if err := w.RunSteps(3); err != nil {
+		t.Fatal(err)
+	}
+	clone, err := w.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.RunSteps(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.RunSteps(100); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(w.Trace()) != fmt.Sprint(clone.Trace()) {
+		t.Fatalf("original took %v, clone %v", w.Trace(), clone.Trace())
+	}
+}
+
+func TestExploreMaxStates(t *testing.T) {
+	g, err := Explore(producerConsumerWorld(3, 2), WithMaxStates(4))
+	if err != ErrStateLimit {
+		t.Fatalf("expected ErrStateLimit, got %v", err)
+	}
+	if got := len(g.States()); got != 4 {
+		t.Fatalf("expected 4 states, got %d", got)
+	}
+}
+
+func TestExploreDiskStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enough states to force the on-disk table to grow several times.
+	g, err := Explore(producerConsumerWorld(60, 40), WithStateStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem, err := Explore(producerConsumerWorld(60, 40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.States()) != len(mem.States()) || store.Len() != len(g.States()) {
+		t.Fatalf("disk store explored %d states (store has %d), memory store %d",
+			len(g.States()), store.Len(), len(mem.States()))
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening keeps every key.
+	store, err = OpenDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if store.Len() != len(g.States()) {
+		t.Fatalf("reopened store has %d keys, want %d", store.Len(), len(g.States()))
+	}
+	key := producerConsumerWorld(60, 40).StateKey()
+	if id, ok, err := store.Lookup(key); err != nil || !ok || id != 0 {
+		t.Fatalf("initial state lookup = %v %v %v", id, ok, err)
+	}
+}
+
+// sameGraph reports whether two graphs have identical states, edges and
+// initial states, comparing by state name.
+func sameGraph(a, b *Graph) bool {
+	if len(a.States()) != len(b.States()) || len(a.InitialStates()) != len(b.InitialStates()) {
+		return false
+	}
+	for _, s := range a.States() {
+		t, ok := b.nameToID[a.NameOf(s)]
+		if !ok || len(a.Succ(s)) != len(b.Succ(t)) {
+			return false
+		}
+		for i, u := range a.Succ(s) {
+			if a.NameOf(u) != b.NameOf(b.Succ(t)[i]) {
+				return false
+			}
+		}
+	}
+	for i, s := range a.InitialStates() {
+		if a.NameOf(s) != b.NameOf(b.InitialStates()[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	want, err := Explore(producerConsumerWorld(8, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, disk := range []bool{false, true} {
+		dir := t.TempDir()
+		path := dir + "/explore.ckpt"
+		open := func() StateStore {
+			if !disk {
+				return NewMemoryStore()
+			}
+			s, err := OpenDiskStore(dir + "/visited")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}
+
+		// Interrupt the run part-way; the store is left holding states
+		// discovered after the last checkpoint.
+		store := open()
+		_, err := Explore(producerConsumerWorld(8, 3),
+			WithStateStore(store), WithCheckpoint(path, 4), WithMaxStates(20))
+		if err != ErrStateLimit {
+			t.Fatalf("disk=%v: expected ErrStateLimit, got %v", disk, err)
+		}
+		store.Close()
+
+		store = open()
+		got, err := Resume(producerConsumerWorld(8, 3), path,
+			WithStateStore(store), WithCheckpoint(path, 4))
+		store.Close()
+		if err != nil {
+			t.Fatalf("disk=%v: %v", disk, err)
+		}
+		if !sameGraph(got, want) {
+			t.Fatalf("disk=%v: resumed graph (%d states) differs from uninterrupted run (%d states)",
+				disk, len(got.States()), len(want.States()))
+		}
+	}
+}
+
+func TestResumeWithOtherStore(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/explore.ckpt"
+	store, err := OpenDiskStore(dir + "/visited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Explore(producerConsumerWorld(8, 3), WithStateStore(store), WithCheckpoint(path, 4), WithMaxStates(20))
+	store.Close()
+	if err != ErrStateLimit {
+		t.Fatalf("expected ErrStateLimit, got %v", err)
+	}
+	if _, err := Resume(producerConsumerWorld(8, 3), path); !errors.Is(err, ErrStoreMismatch) {
+		t.Error("resumed a persistent-store checkpoint with a memory store")
+	}
+	fresh, err := OpenDiskStore(dir + "/fresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+	if _, err := Resume(producerConsumerWorld(8, 3), path, WithStateStore(fresh)); !errors.Is(err, ErrStoreMismatch) {
+		t.Error("resumed with an empty persistent store")
+	}
+
+	path = dir + "/memory.ckpt"
+	if _, err := Explore(producerConsumerWorld(8, 3), WithCheckpoint(path, 4), WithMaxStates(20)); err != ErrStateLimit {
+		t.Fatalf("expected ErrStateLimit, got %v", err)
+	}
+	if _, err := Resume(producerConsumerWorld(8, 3), path, WithStateStore(fresh)); !errors.Is(err, ErrStoreMismatch) {
+		t.Error("resumed a memory-store checkpoint with a persistent store")
+	}
+}
+
+func TestExplorePropositions(t *testing.T) {
+	w := producerConsumerWorld(3, 2)
+	inbox := Address{"C", "inbox"}
+	g, err := Explore(w,
+		WithPropositions(ChannelPropositions(w)),
+		WithPropositions(Propositions{
+			"done": func(w *World) bool {
+				return w.Procs[1].(*testConsumer).count == 3
+			},
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full := Atom(inbox.String() + ".full")
+	empty := Atom(inbox.String() + ".empty")
+	holdsInitially := func(f Formula) bool {
+		return f.Sat(g).Contains(g.InitialStates()[0])
+	}
+
+	if !holdsInitially(empty) || holdsInitially(full) {
+		t.Fatalf("initial state should be empty and not full")
+	}
+	if !holdsInitially(EF(full)) {
+		t.Fatalf("expected the inbox to fill up on some path")
+	}
+	// Every run ends in the single terminal state, where everything has
+	// been received and nothing is enabled.
+	if !holdsInitially(AF(And(Atom("done"), Atom("deadlock")))) {
+		t.Fatalf("expected AF(done & deadlock)")
+	}
+	if !holdsInitially(AG(Implies(Atom("deadlock"), And(Atom("done"), empty)))) {
+		t.Fatalf("expected every deadlock to be the completed, empty state")
+	}
+}
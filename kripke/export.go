@@ -0,0 +1,566 @@
+package kripke
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Exporters for PRISM and Storm. A Graph with edge probabilities is
+// exported as a DTMC, in which a state whose edges were added without
+// probabilities moves to each successor with equal probability; a Graph
+// without any (such as one built by Explore) as an MDP in which every
+// successor is its own choice. States without successors get a
+// self-loop, as PRISM does when it fixes deadlocks, and the labels "init"
+// and "deadlock" are computed from the graph (a state label of either
+// name is replaced). Label and variable names are turned
+// into PRISM identifiers by replacing other characters with '_', so
+// "consumer.inbox.full" becomes consumer_inbox_full.
+
+// StateReward assigns a reward to every state.
+type StateReward func(g *Graph, s StateID) float64
+
+// VarReward is the value of int variable 'name' as a reward, 0 where the
+// state has no such variable.
+func VarReward(name string) StateReward {
+	return func(g *Graph, s StateID) float64 {
+		v, _ := g.Var(s, name)
+		n, _ := v.(int)
+		return float64(n)
+	}
+}
+
+// LabelReward is 1 in states with label 'prop' and 0 elsewhere.
+func LabelReward(prop string) StateReward {
+	return func(g *Graph, s StateID) float64 {
+		if g.HasLabel(s, prop) {
+			return 1
+		}
+		return 0
+	}
+}
+
+// ---------- explicit model ----------
+
+type explicitTrans struct {
+	to StateID
+	p  float64
+}
+
+// explicitModel is g in the shape the explicit formats want: states
+// 0..n-1, each with one or more choices of transitions sorted by target.
+type explicitModel struct {
+	g        *Graph
+	n        int
+	mdp      bool
+	choices  [][][]explicitTrans
+	deadlock []bool
+	init     []bool
+	labels   []string            // sanitized user label names, sorted
+	labelOf  map[string]string   // sanitized -> original
+	vars     []string            // sanitized variable names, sorted
+	varOf    map[string]string   // sanitized -> original
+	varBool  map[string]bool     // sanitized name -> is a bool
+	values   []map[string]string // per state: sanitized var -> PRISM literal
+}
+
+func newExplicitModel(g *Graph) (*explicitModel, error) {
+	d := g.data()
+	m := &explicitModel{
+		g:        g,
+		n:        len(d.Names),
+		mdp:      !g.IsProbabilistic(),
+		choices:  make([][][]explicitTrans, len(d.Names)),
+		deadlock: make([]bool, len(d.Names)),
+		init:     make([]bool, len(d.Names)),
+		labelOf:  make(map[string]string),
+		varOf:    make(map[string]string),
+		varBool:  make(map[string]bool),
+		values:   make([]map[string]string, len(d.Names)),
+	}
+	if len(d.Init) == 0 {
+		return nil, fmt.Errorf("graph has no initial state")
+	}
+	for _, s := range d.Init {
+		m.init[s] = true
+	}
+
+	for i, succ := range d.Succ {
+		s := StateID(i)
+		if len(succ) == 0 {
+			m.deadlock[i] = true
+			m.choices[i] = [][]explicitTrans{{{to: s, p: 1}}}
+			continue
+		}
+		merged := make(map[StateID]float64)
+		for j, t := range succ {
+			switch {
+			case m.mdp:
+				merged[t] = 1
+			case d.Probs[i] == nil:
+				merged[t] += 1 / float64(len(succ))
+			default:
+				merged[t] += d.Probs[i][j]
+			}
+		}
+		targets := make([]StateID, 0, len(merged))
+		for t := range merged {
+			targets = append(targets, t)
+		}
+		sort.Slice(targets, func(a, b int) bool { return targets[a] < targets[b] })
+		if m.mdp {
+			for _, t := range targets {
+				m.choices[i] = append(m.choices[i], []explicitTrans{{to: t, p: 1}})
+			}
+			continue
+		}
+		var row []explicitTrans
+		sum := 0.0
+		for _, t := range targets {
+			row = append(row, explicitTrans{to: t, p: merged[t]})
+			sum += merged[t]
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			return nil, fmt.Errorf("state %q: outgoing probabilities sum to %g", d.Names[i], sum)
+		}
+		m.choices[i] = [][]explicitTrans{row}
+	}
+
+	for _, lbls := range d.Labels {
+		for name := range lbls {
+			if id := prismIdent(name); id == "init" || id == "deadlock" {
+				continue
+			}
+			if err := m.addName(m.labelOf, name, "label"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	m.labels = sortedKeys(m.labelOf)
+
+	for _, vs := range d.Vars {
+		for name := range vs {
+			if err := m.addName(m.varOf, name, "variable"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	m.vars = sortedKeys(m.varOf)
+	for _, v := range m.vars {
+		orig := m.varOf[v]
+		kind := ""
+		for i, vs := range d.Vars {
+			val, ok := vs[orig]
+			if !ok {
+				return nil, fmt.Errorf("variable %s is not set in state %q", orig, d.Names[i])
+			}
+			var lit, k string
+			switch x := val.(type) {
+			case int:
+				lit, k = strconv.Itoa(x), "int"
+			case bool:
+				lit, k = strconv.FormatBool(x), "bool"
+			default:
+				return nil, fmt.Errorf("variable %s has type %T; PRISM supports only int and bool", orig, val)
+			}
+			if kind != "" && k != kind {
+				return nil, fmt.Errorf("variable %s is both %s and %s", orig, kind, k)
+			}
+			kind = k
+			if m.values[i] == nil {
+				m.values[i] = make(map[string]string)
+			}
+			m.values[i][v] = lit
+		}
+		m.varBool[v] = kind == "bool"
+	}
+	return m, nil
+}
+
+// addName records name under its PRISM identifier, rejecting two names
+// that sanitize to the same identifier.
+func (m *explicitModel) addName(names map[string]string, name, what string) error {
+	id := prismIdent(name)
+	if prev, ok := names[id]; ok && prev != name {
+		return fmt.Errorf("%ss %q and %q both export as %s", what, prev, name, id)
+	}
+	names[id] = name
+	return nil
+}
+
+func (m *explicitModel) hasLabel(s int, label string) bool {
+	switch label {
+	case "init":
+		return m.init[s]
+	case "deadlock":
+		return m.deadlock[s]
+	}
+	return m.g.HasLabel(StateID(s), m.labelOf[label])
+}
+
+// allLabels is "init", "deadlock" and then the user labels.
+func (m *explicitModel) allLabels() []string {
+	return append([]string{"init", "deadlock"}, m.labels...)
+}
+
+func (m *explicitModel) transitions() (choices, trans int) {
+	for _, cs := range m.choices {
+		choices += len(cs)
+		for _, c := range cs {
+			trans += len(c)
+		}
+	}
+	return
+}
+
+// prismIdent maps s onto [A-Za-z_][A-Za-z0-9_]*.
+func prismIdent(s string) string {
+	var sb strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "_"
+	}
+	return sb.String()
+}
+
+func formatProb(p float64) string {
+	return strconv.FormatFloat(p, 'g', -1, 64)
+}
+
+// ---------- PRISM explicit ----------
+
+// WritePRISMTra writes the transition matrix (.tra): "n m" and then
+// "src dst p" lines for a DTMC, or "n choices m" and "src choice dst p"
+// lines for an MDP.
+func WritePRISMTra(w io.Writer, g *Graph) error {
+	m, err := newExplicitModel(g)
+	if err != nil {
+		return fmt.Errorf("WritePRISMTra: %w", err)
+	}
+	bw := bufio.NewWriter(w)
+	choices, trans := m.transitions()
+	if m.mdp {
+		fmt.Fprintf(bw, "%d %d %d\n", m.n, choices, trans)
+	} else {
+		fmt.Fprintf(bw, "%d %d\n", m.n, trans)
+	}
+	for s, cs := range m.choices {
+		for c, row := range cs {
+			for _, t := range row {
+				if m.mdp {
+					fmt.Fprintf(bw, "%d %d %d %s\n", s, c, t.to, formatProb(t.p))
+				} else {
+					fmt.Fprintf(bw, "%d %d %s\n", s, t.to, formatProb(t.p))
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// WritePRISMLab writes the labelling (.lab): a header of numbered label
+// names, then "state: label..." for every state with a label.
+func WritePRISMLab(w io.Writer, g *Graph) error {
+	m, err := newExplicitModel(g)
+	if err != nil {
+		return fmt.Errorf("WritePRISMLab: %w", err)
+	}
+	bw := bufio.NewWriter(w)
+	labels := m.allLabels()
+	for i, l := range labels {
+		if i > 0 {
+			bw.WriteByte(' ')
+		}
+		fmt.Fprintf(bw, "%d=%q", i, l)
+	}
+	bw.WriteByte('\n')
+	for s := 0; s < m.n; s++ {
+		var idx []string
+		for i, l := range labels {
+			if m.hasLabel(s, l) {
+				idx = append(idx, strconv.Itoa(i))
+			}
+		}
+		if len(idx) > 0 {
+			fmt.Fprintf(bw, "%d: %s\n", s, strings.Join(idx, " "))
+		}
+	}
+	return bw.Flush()
+}
+
+// WritePRISMSta writes the state valuations (.sta). Variables must be
+// ints or bools and set in every state; a graph without variables gets a
+// single variable s holding the state number.
+func WritePRISMSta(w io.Writer, g *Graph) error {
+	m, err := newExplicitModel(g)
+	if err != nil {
+		return fmt.Errorf("WritePRISMSta: %w", err)
+	}
+	bw := bufio.NewWriter(w)
+	if len(m.vars) == 0 {
+		fmt.Fprintln(bw, "(s)")
+		for s := 0; s < m.n; s++ {
+			fmt.Fprintf(bw, "%d:(%d)\n", s, s)
+		}
+		return bw.Flush()
+	}
+	fmt.Fprintf(bw, "(%s)\n", strings.Join(m.vars, ","))
+	for s := 0; s < m.n; s++ {
+		vals := make([]string, len(m.vars))
+		for i, v := range m.vars {
+			vals[i] = m.values[s][v]
+		}
+		fmt.Fprintf(bw, "%d:(%s)\n", s, strings.Join(vals, ","))
+	}
+	return bw.Flush()
+}
+
+// WritePRISMSrew writes one state reward structure (.srew): "n m" and
+// then "state reward" for the m states with a non-zero reward.
+func WritePRISMSrew(w io.Writer, g *Graph, r StateReward) error {
+	bw := bufio.NewWriter(w)
+	var lines []string
+	for s := 0; s < g.nextID; s++ {
+		if v := r(g, StateID(s)); v != 0 {
+			lines = append(lines, fmt.Sprintf("%d %s\n", s, formatProb(v)))
+		}
+	}
+	fmt.Fprintf(bw, "%d %d\n", g.nextID, len(lines))
+	for _, l := range lines {
+		bw.WriteString(l)
+	}
+	return bw.Flush()
+}
+
+// ExportPRISM writes base.tra, base.lab and base.sta, plus
+// base.<name>.srew for every reward structure, ready for
+//
+//	prism -importmodel base.tra,lab,sta -importstaterewards base.<name>.srew -dtmc
+//
+// when g is probabilistic. Any other graph, such as one built by Explore,
+// is written as an MDP and is imported with -mdp instead of -dtmc.
+func ExportPRISM(base string, g *Graph, rewards map[string]StateReward) error {
+	create := func(path string, write func(io.Writer) error) error {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := write(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	type file struct {
+		ext   string
+		write func(io.Writer) error
+	}
+	files := []file{
+		{".tra", func(w io.Writer) error { return WritePRISMTra(w, g) }},
+		{".lab", func(w io.Writer) error { return WritePRISMLab(w, g) }},
+		{".sta", func(w io.Writer) error { return WritePRISMSta(w, g) }},
+	}
+	for _, name := range sortedKeys(rewards) {
+		r := rewards[name]
+		files = append(files, file{"." + prismIdent(name) + ".srew", func(w io.Writer) error {
+			return WritePRISMSrew(w, g, r)
+		}})
+	}
+	for _, f := range files {
+		if err := create(base+f.ext, f.write); err != nil {
+			return fmt.Errorf("ExportPRISM: %w", err)
+		}
+	}
+	return nil
+}
+
+// ---------- Storm DRN ----------
+
+// WriteDRN writes g in Storm's explicit DRN format, for
+//
+//	storm --explicit-drn model.drn --prop 'P=? [F "done"]'
+//
+// Rewards become state reward models, listed in name order.
+func WriteDRN(w io.Writer, g *Graph, rewards map[string]StateReward) error {
+	m, err := newExplicitModel(g)
+	if err != nil {
+		return fmt.Errorf("WriteDRN: %w", err)
+	}
+	bw := bufio.NewWriter(w)
+	names := sortedKeys(rewards)
+	typ := "DTMC"
+	if m.mdp {
+		typ = "MDP"
+	}
+	choices, _ := m.transitions()
+	rewardNames := make([]string, len(names))
+	for i, n := range names {
+		rewardNames[i] = prismIdent(n)
+	}
+	fmt.Fprintln(bw, "// Exported by kripke-ctl")
+	fmt.Fprintf(bw, "@type: %s\n", typ)
+	fmt.Fprintln(bw, "@parameters")
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "@reward_models")
+	fmt.Fprintln(bw, strings.Join(rewardNames, " "))
+	fmt.Fprintf(bw, "@nr_states\n%d\n", m.n)
+	fmt.Fprintf(bw, "@nr_choices\n%d\n", choices)
+	fmt.Fprintln(bw, "@model")
+	labels := m.allLabels()
+	for s := 0; s < m.n; s++ {
+		fmt.Fprintf(bw, "state %d", s)
+		if len(names) > 0 {
+			vals := make([]string, len(names))
+			for i, n := range names {
+				vals[i] = formatProb(rewards[n](g, StateID(s)))
+			}
+			fmt.Fprintf(bw, " [%s]", strings.Join(vals, ", "))
+		}
+		for _, l := range labels {
+			if m.hasLabel(s, l) {
+				fmt.Fprintf(bw, " %s", l)
+			}
+		}
+		bw.WriteByte('\n')
+		for c, row := range m.choices[s] {
+			fmt.Fprintf(bw, "\taction %d\n", c)
+			for _, t := range row {
+				fmt.Fprintf(bw, "\t\t%d : %s\n", t.to, formatProb(t.p))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// ---------- PRISM language ----------
+
+// WritePRISMModel writes g as a PRISM-language model (.pm): one module
+// whose variable s is the state number, one command per state (per choice
+// for an MDP), formulas for the state variables, a label per state label
+// and a rewards block per reward structure. The guards list states
+// explicitly, so this is meant for models small enough to read.
+func WritePRISMModel(w io.Writer, g *Graph, rewards map[string]StateReward) error {
+	m, err := newExplicitModel(g)
+	if err != nil {
+		return fmt.Errorf("WritePRISMModel: %w", err)
+	}
+	sv := "s"
+	for m.varOf[sv] != "" {
+		sv += "_"
+	}
+	states := func(pred func(s int) bool) string {
+		var parts []string
+		for s := 0; s < m.n; s++ {
+			if pred(s) {
+				parts = append(parts, fmt.Sprintf("%s=%d", sv, s))
+			}
+		}
+		if len(parts) == 0 {
+			return "false"
+		}
+		return strings.Join(parts, " | ")
+	}
+
+	bw := bufio.NewWriter(w)
+	if m.mdp {
+		fmt.Fprintln(bw, "mdp")
+	} else {
+		fmt.Fprintln(bw, "dtmc")
+	}
+	fmt.Fprintln(bw)
+	var inits []int
+	for s, ok := range m.init {
+		if ok {
+			inits = append(inits, s)
+		}
+	}
+	fmt.Fprintln(bw, "module kripke")
+	if len(inits) == 1 {
+		fmt.Fprintf(bw, "\t%s : [0..%d] init %d;\n", sv, m.n-1, inits[0])
+	} else {
+		fmt.Fprintf(bw, "\t%s : [0..%d];\n", sv, m.n-1)
+	}
+	fmt.Fprintln(bw)
+	for s := 0; s < m.n; s++ {
+		fmt.Fprintf(bw, "\t// %s\n", strings.ReplaceAll(g.NameOf(StateID(s)), "\n", " "))
+		for _, row := range m.choices[s] {
+			updates := make([]string, len(row))
+			for i, t := range row {
+				if m.mdp {
+					updates[i] = fmt.Sprintf("(%s'=%d)", sv, t.to)
+				} else {
+					updates[i] = fmt.Sprintf("%s:(%s'=%d)", formatProb(t.p), sv, t.to)
+				}
+			}
+			fmt.Fprintf(bw, "\t[] %s=%d -> %s;\n", sv, s, strings.Join(updates, " + "))
+		}
+	}
+	fmt.Fprintln(bw, "endmodule")
+
+	if len(inits) > 1 {
+		fmt.Fprintf(bw, "\ninit\n\t%s\nendinit\n", states(func(s int) bool { return m.init[s] }))
+	}
+
+	if len(m.vars) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, v := range m.vars {
+		if m.varBool[v] {
+			isTrue := func(s int) bool { return m.values[s][v] == "true" }
+			fmt.Fprintf(bw, "formula %s = %s;\n", v, states(isTrue))
+			continue
+		}
+		// Group states by value; the most recent group is the default.
+		var order []string
+		byValue := make(map[string][]int)
+		for s := 0; s < m.n; s++ {
+			val := m.values[s][v]
+			if byValue[val] == nil {
+				order = append(order, val)
+			}
+			byValue[val] = append(byValue[val], s)
+		}
+		expr := order[len(order)-1]
+		for i := len(order) - 2; i >= 0; i-- {
+			in := make(map[int]bool)
+			for _, s := range byValue[order[i]] {
+				in[s] = true
+			}
+			expr = fmt.Sprintf("(%s ? %s : %s)", states(func(s int) bool { return in[s] }), order[i], expr)
+		}
+		fmt.Fprintf(bw, "formula %s = %s;\n", v, expr)
+	}
+
+	fmt.Fprintln(bw)
+	for _, l := range m.labels {
+		fmt.Fprintf(bw, "label %q = %s;\n", l, states(func(s int) bool { return m.hasLabel(s, l) }))
+	}
+
+	for _, name := range sortedKeys(rewards) {
+		fmt.Fprintf(bw, "\nrewards %q\n", prismIdent(name))
+		for s := 0; s < m.n; s++ {
+			if r := rewards[name](g, StateID(s)); r != 0 {
+				fmt.Fprintf(bw, "\t%s=%d : %s;\n", sv, s, formatProb(r))
+			}
+		}
+		fmt.Fprintln(bw, "endrewards")
+	}
+	return bw.Flush()
+}
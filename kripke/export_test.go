@@ -0,0 +1,184 @@
+package kripke
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportPRISMRoundTrip(t *testing.T) {
+	w := producerConsumerWorld(3, 2)
+	g, err := Explore(w, WithPropositions(ChannelPropositions(w)), WithVariables(ChannelVariables(w)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(t.TempDir(), "pc")
+	inbox := "C.inbox"
+	rewards := map[string]StateReward{"queued": VarReward(inbox + ".len")}
+	if err := ExportPRISM(base, g, rewards); err != nil {
+		t.Fatal(err)
+	}
+	open := func(ext string) *os.File {
+		f, err := os.Open(base + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+	back, err := ReadPRISM(open(".tra"), open(".lab"), open(".sta"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back.States()) != len(g.States()) || len(back.InitialStates()) != 1 || back.InitialStates()[0] != g.InitialStates()[0] {
+		t.Fatalf("states or initial state changed")
+	}
+	if back.IsProbabilistic() {
+		t.Fatalf("explored graph exported as a DTMC")
+	}
+	for _, s := range g.States() {
+		if want := max(len(g.Succ(s)), 1); len(back.Succ(s)) != want {
+			t.Fatalf("state %d: %d successors, want %d", s, len(back.Succ(s)), want)
+		}
+		if g.HasLabel(s, inbox+".full") != back.HasLabel(s, "C_inbox_full") ||
+			g.HasLabel(s, "deadlock") != back.HasLabel(s, "deadlock") {
+			t.Fatalf("state %d: labels differ", s)
+		}
+		want, ok := g.Var(s, inbox+".len")
+		if got, _ := back.Var(s, "C_inbox_len"); !ok || got != want {
+			t.Fatalf("state %d: len %v, want %v", s, got, want)
+		}
+	}
+
+	srew, err := os.ReadFile(base + ".queued.srew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(srew)), "\n")
+	nonzero := 0
+	for _, s := range g.States() {
+		if v, _ := g.Var(s, inbox+".len"); v != 0 {
+			nonzero++
+		}
+	}
+	if len(lines) != nonzero+1 {
+		t.Fatalf(".srew has %d lines, want %d:\n%s", len(lines), nonzero+1, srew)
+	}
+}
+
+// coinGraph is a fair coin flipped until it shows heads.
+func coinGraph() *Graph {
+	g := NewGraph()
+	g.AddState("flip", nil)
+	g.AddState("heads", map[string]bool{"done": true})
+	g.SetVars(0, map[string]any{"n": 0, "h": false})
+	g.SetVars(1, map[string]any{"n": 1, "h": true})
+	g.AddEdgeProb("flip", "flip", 0.5)
+	g.AddEdgeProb("flip", "heads", 0.5)
+	g.SetInitial("flip")
+	return g
+}
+
+func TestWriteDRN(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDRN(&buf, coinGraph(), map[string]StateReward{"steps": LabelReward("done")}); err != nil {
+		t.Fatal(err)
+	}
+	want := `// Exported by kripke-ctl
+@type: DTMC
+@parameters
+
+@reward_models
+steps
+@nr_states
+2
+@nr_choices
+2
+@model
+state 0 [0] init
+	action 0
+		0 : 0.5
+		1 : 0.5
+state 1 [1] deadlock done
+	action 0
+		1 : 1
+`
+	if buf.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteDRN(&buf, OrderGraph(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "@type: MDP") {
+		t.Fatalf("a graph without probabilities should export as an MDP:\n%s", buf.String())
+	}
+}
+
+func TestWritePRISMModel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePRISMModel(&buf, coinGraph(), map[string]StateReward{"heads": LabelReward("done")}); err != nil {
+		t.Fatal(err)
+	}
+	want := `dtmc
+
+module kripke
+	s : [0..1] init 0;
+
+	// flip
+	[] s=0 -> 0.5:(s'=0) + 0.5:(s'=1);
+	// heads
+	[] s=1 -> 1:(s'=1);
+endmodule
+
+formula h = s=1;
+formula n = (s=0 ? 0 : 1);
+
+label "done" = s=1;
+
+rewards "heads"
+	s=1 : 1;
+endrewards
+`
+	if buf.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestExportRejectsUnsupportedGraphs(t *testing.T) {
+	strs := coinGraph()
+	strs.SetVars(0, map[string]any{"mode": "x"})
+	unbalanced := NewGraph()
+	unbalanced.AddEdgeProb("a", "b", 0.3)
+	unbalanced.SetInitial("a")
+	noInit := NewGraph()
+	noInit.AddEdge("a", "b")
+	for name, g := range map[string]*Graph{"string var": strs, "probabilities": unbalanced, "no initial state": noInit} {
+		if err := WritePRISMTra(&bytes.Buffer{}, g); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestExportMixedGraph(t *testing.T) {
+	// heads has edges without probabilities: each successor gets half.
+	g := coinGraph()
+	g.AddEdge("heads", "flip")
+	g.AddEdge("heads", "heads")
+	var tra, drn bytes.Buffer
+	if err := WritePRISMTra(&tra, g); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2 4\n0 0 0.5\n0 1 0.5\n1 0 0.5\n1 1 0.5\n"; tra.String() != want {
+		t.Fatalf("tra:\n%s\nwant\n%s", tra.String(), want)
+	}
+	if err := WriteDRN(&drn, g, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(drn.String(), "state 1 done\n\taction 0\n\t\t0 : 0.5\n\t\t1 : 0.5\n") {
+		t.Fatalf("drn:\n%s", drn.String())
+	}
+}
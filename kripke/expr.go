@@ -0,0 +1,277 @@
+package kripke
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ---------- Variable expressions ----------
+
+// Expr is an integer, string or bool valued expression over the variables
+// of one state. Eval reports false when a variable is missing or the
+// operand types don't fit the operator.
+type Expr interface {
+	Eval(g *Graph, s StateID) (any, bool)
+	String() string
+}
+
+// normalizeValue maps every integer type onto int and rejects anything
+// other than int, string and bool.
+func normalizeValue(v any) (any, bool) {
+	switch x := v.(type) {
+	case int:
+		return x, true
+	case int8:
+		return int(x), true
+	case int16:
+		return int(x), true
+	case int32:
+		return int(x), true
+	case int64:
+		return int(x), true
+	case uint:
+		return int(x), true
+	case uint8:
+		return int(x), true
+	case uint16:
+		return int(x), true
+	case uint32:
+		return int(x), true
+	case uint64:
+		return int(x), true
+	case string, bool:
+		return x, true
+	}
+	return nil, false
+}
+
+// ----- variables and constants -----
+
+type VarExpr struct {
+	Name string
+}
+
+// Var refers to a state variable by name.
+func Var(name string) Expr {
+	return VarExpr{Name: name}
+}
+
+func (v VarExpr) Eval(g *Graph, s StateID) (any, bool) {
+	return g.Var(s, v.Name)
+}
+
+func (v VarExpr) String() string { return v.Name }
+
+type ConstExpr struct {
+	Value any
+}
+
+// Const is a literal int, string or bool.
+func Const(v any) Expr {
+	nv, ok := normalizeValue(v)
+	if !ok {
+		panic(fmt.Sprintf("Const: unsupported type %T", v))
+	}
+	return ConstExpr{Value: nv}
+}
+
+func (c ConstExpr) Eval(*Graph, StateID) (any, bool) { return c.Value, true }
+
+func (c ConstExpr) String() string {
+	if s, ok := c.Value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(c.Value)
+}
+
+// ----- len -----
+
+type LenExpr struct {
+	Name string
+}
+
+// Len is the length of a variable: the value of "<name>.len" when the
+// state has one (ChannelVariables provides these for queues), otherwise
+// the length of the string variable 'name'.
+func Len(name string) Expr {
+	return LenExpr{Name: name}
+}
+
+func (l LenExpr) Eval(g *Graph, s StateID) (any, bool) {
+	if v, ok := g.Var(s, l.Name+".len"); ok {
+		return v, true
+	}
+	if v, ok := g.Var(s, l.Name); ok {
+		if str, ok := v.(string); ok {
+			return len(str), true
+		}
+	}
+	return nil, false
+}
+
+func (l LenExpr) String() string { return "len(" + l.Name + ")" }
+
+// ----- arithmetic -----
+
+type ArithExpr struct {
+	Op          string // + - * / %
+	Left, Right Expr
+}
+
+// Arith applies an arithmetic operator to two integer expressions.
+// "+" also concatenates strings.
+func Arith(op string, l, r Expr) Expr {
+	switch op {
+	case "+", "-", "*", "/", "%":
+	default:
+		panic("Arith: unknown operator " + op)
+	}
+	return ArithExpr{Op: op, Left: l, Right: r}
+}
+
+func Add(l, r Expr) Expr { return Arith("+", l, r) }
+func Sub(l, r Expr) Expr { return Arith("-", l, r) }
+func Mul(l, r Expr) Expr { return Arith("*", l, r) }
+
+func (a ArithExpr) Eval(g *Graph, s StateID) (any, bool) {
+	lv, ok := a.Left.Eval(g, s)
+	if !ok {
+		return nil, false
+	}
+	rv, ok := a.Right.Eval(g, s)
+	if !ok {
+		return nil, false
+	}
+	if ls, ok := lv.(string); ok && a.Op == "+" {
+		rs, ok := rv.(string)
+		return ls + rs, ok
+	}
+	li, lok := lv.(int)
+	ri, rok := rv.(int)
+	if !lok || !rok {
+		return nil, false
+	}
+	switch a.Op {
+	case "+":
+		return li + ri, true
+	case "-":
+		return li - ri, true
+	case "*":
+		return li * ri, true
+	case "/":
+		if ri == 0 {
+			return nil, false
+		}
+		return li / ri, true
+	case "%":
+		if ri == 0 {
+			return nil, false
+		}
+		return li % ri, true
+	}
+	return nil, false
+}
+
+func (a ArithExpr) String() string {
+	return "(" + a.Left.String() + " " + a.Op + " " + a.Right.String() + ")"
+}
+
+// ---------- Relational atomic propositions ----------
+
+type CompareFormula struct {
+	Op          string // == != < <= > >=
+	Left, Right Expr
+}
+
+// Compare is the atomic proposition "l op r". It holds in a state when both
+// sides evaluate there and the comparison is true; ints and strings are
+// ordered, bools only support == and !=.
+func Compare(op string, l, r Expr) Formula {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		panic("Compare: unknown operator " + op)
+	}
+	return CompareFormula{Op: op, Left: l, Right: r}
+}
+
+func Eq(l, r Expr) Formula { return Compare("==", l, r) }
+func Lt(l, r Expr) Formula { return Compare("<", l, r) }
+func Le(l, r Expr) Formula { return Compare("<=", l, r) }
+func Gt(l, r Expr) Formula { return Compare(">", l, r) }
+func Ge(l, r Expr) Formula { return Compare(">=", l, r) }
+
+func (c CompareFormula) Sat(g *Graph) StateSet {
+	res := NewStateSet()
+	for _, s := range g.States() {
+		if c.holds(g, s) {
+			res.Add(s)
+		}
+	}
+	return res
+}
+
+func (c CompareFormula) holds(g *Graph, s StateID) bool {
+	lv, ok := c.Left.Eval(g, s)
+	if !ok {
+		return false
+	}
+	rv, ok := c.Right.Eval(g, s)
+	if !ok {
+		return false
+	}
+	var cmp int
+	switch l := lv.(type) {
+	case int:
+		r, ok := rv.(int)
+		if !ok {
+			return false
+		}
+		cmp = compareOrdered(l, r)
+	case string:
+		r, ok := rv.(string)
+		if !ok {
+			return false
+		}
+		cmp = compareOrdered(l, r)
+	case bool:
+		r, ok := rv.(bool)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		}
+		return false
+	default:
+		return false
+	}
+	switch c.Op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func compareOrdered[T int | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
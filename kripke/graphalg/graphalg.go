@@ -0,0 +1,426 @@
+// Package graphalg answers structural questions about a kripke.Graph that
+// CTL does not phrase directly: strongly connected components, states no
+// initial state reaches, shortest and bounded simple paths between named
+// states, and dominators ("every path to Delivered passes Accepted").
+//
+// Results list states in ascending StateID order unless noted otherwise,
+// so they are deterministic for a given graph.
+package graphalg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+)
+
+// ErrNoPath is returned when the target state cannot be reached.
+var ErrNoPath = errors.New("graphalg: no path")
+
+// states returns the states of g in ascending order.
+func states(g *kripke.Graph) []kripke.StateID {
+	ss := g.States()
+	sort.Slice(ss, func(i, j int) bool { return ss[i] < ss[j] })
+	return ss
+}
+
+func lookup(g *kripke.Graph, name string) (kripke.StateID, error) {
+	id, ok := g.StateByName(name)
+	if !ok {
+		return 0, fmt.Errorf("graphalg: unknown state %q", name)
+	}
+	return id, nil
+}
+
+// ---------- SCCs ----------
+
+// SCCs returns the strongly connected components of g in reverse
+// topological order: every edge leaving a component goes to one listed
+// before it. Each component is sorted.
+func SCCs(g *kripke.Graph) [][]kripke.StateID {
+	ss := states(g)
+	index := make(map[kripke.StateID]int, len(ss))
+	low := make(map[kripke.StateID]int, len(ss))
+	onStack := make(map[kripke.StateID]bool)
+	var stack []kripke.StateID
+	var out [][]kripke.StateID
+	next := 0
+
+	type frame struct {
+		s kripke.StateID
+		i int
+	}
+	for _, root := range ss {
+		if _, seen := index[root]; seen {
+			continue
+		}
+		// Iterative Tarjan, so deep graphs don't overflow the stack.
+		call := []frame{{s: root}}
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+		for len(call) > 0 {
+			f := &call[len(call)-1]
+			succ := g.Succ(f.s)
+			if f.i < len(succ) {
+				t := succ[f.i]
+				f.i++
+				if _, seen := index[t]; !seen {
+					index[t], low[t] = next, next
+					next++
+					stack = append(stack, t)
+					onStack[t] = true
+					call = append(call, frame{s: t})
+				} else if onStack[t] && index[t] < low[f.s] {
+					low[f.s] = index[t]
+				}
+				continue
+			}
+			s := f.s
+			call = call[:len(call)-1]
+			if len(call) > 0 {
+				if p := call[len(call)-1].s; low[s] < low[p] {
+					low[p] = low[s]
+				}
+			}
+			if low[s] != index[s] {
+				continue
+			}
+			var comp []kripke.StateID
+			for {
+				t := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[t] = false
+				comp = append(comp, t)
+				if t == s {
+					break
+				}
+			}
+			sort.Slice(comp, func(i, j int) bool { return comp[i] < comp[j] })
+			out = append(out, comp)
+		}
+	}
+	return out
+}
+
+// BottomSCCs returns the components no edge leaves: the places every
+// infinite run eventually stays in. A state without successors is a
+// bottom component of its own.
+func BottomSCCs(g *kripke.Graph) [][]kripke.StateID {
+	var out [][]kripke.StateID
+	for _, comp := range SCCs(g) {
+		in := make(map[kripke.StateID]bool, len(comp))
+		for _, s := range comp {
+			in[s] = true
+		}
+		bottom := true
+		for _, s := range comp {
+			for _, t := range g.Succ(s) {
+				if !in[t] {
+					bottom = false
+				}
+			}
+		}
+		if bottom {
+			out = append(out, comp)
+		}
+	}
+	return out
+}
+
+// ---------- Reachability ----------
+
+// Reachable returns the states reachable from the given states, including
+// the states themselves.
+func Reachable(g *kripke.Graph, from ...kripke.StateID) map[kripke.StateID]bool {
+	seen := make(map[kripke.StateID]bool)
+	queue := make([]kripke.StateID, 0, len(from))
+	for _, s := range from {
+		if !seen[s] {
+			seen[s] = true
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, t := range g.Succ(s) {
+			if !seen[t] {
+				seen[t] = true
+				queue = append(queue, t)
+			}
+		}
+	}
+	return seen
+}
+
+// Unreachable returns the states no initial state reaches.
+func Unreachable(g *kripke.Graph) []kripke.StateID {
+	seen := Reachable(g, g.InitialStates()...)
+	var out []kripke.StateID
+	for _, s := range states(g) {
+		if !seen[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ---------- Paths ----------
+
+// ShortestPath returns a path with the fewest edges from state 'from' to
+// state 'to', both ends included, or ErrNoPath. Ties are broken in favour
+// of earlier successors.
+func ShortestPath(g *kripke.Graph, from, to string) ([]kripke.StateID, error) {
+	src, err := lookup(g, from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := lookup(g, to)
+	if err != nil {
+		return nil, err
+	}
+	parent := map[kripke.StateID]kripke.StateID{src: src}
+	queue := []kripke.StateID{src}
+	for len(queue) > 0 && !hasKey(parent, dst) {
+		s := queue[0]
+		queue = queue[1:]
+		for _, t := range g.Succ(s) {
+			if !hasKey(parent, t) {
+				parent[t] = s
+				queue = append(queue, t)
+			}
+		}
+	}
+	if !hasKey(parent, dst) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrNoPath, from, to)
+	}
+	path := []kripke.StateID{dst}
+	for s := dst; s != src; {
+		s = parent[s]
+		path = append(path, s)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
+
+func hasKey(m map[kripke.StateID]kripke.StateID, k kripke.StateID) bool {
+	_, ok := m[k]
+	return ok
+}
+
+// SimplePaths returns every path from 'from' to 'to' with at most maxLen
+// edges that visits no state twice, in depth-first order. A path from a
+// state to itself is the single state; cycles back to 'from' are not
+// simple paths.
+func SimplePaths(g *kripke.Graph, from, to string, maxLen int) ([][]kripke.StateID, error) {
+	src, err := lookup(g, from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := lookup(g, to)
+	if err != nil {
+		return nil, err
+	}
+	var out [][]kripke.StateID
+	onPath := map[kripke.StateID]bool{src: true}
+	path := []kripke.StateID{src}
+	var dfs func(s kripke.StateID)
+	dfs = func(s kripke.StateID) {
+		if s == dst {
+			out = append(out, append([]kripke.StateID(nil), path...))
+			return
+		}
+		if len(path)-1 >= maxLen {
+			return
+		}
+		seen := make(map[kripke.StateID]bool)
+		for _, t := range g.Succ(s) {
+			if onPath[t] || seen[t] {
+				continue
+			}
+			seen[t] = true
+			onPath[t] = true
+			path = append(path, t)
+			dfs(t)
+			path = path[:len(path)-1]
+			onPath[t] = false
+		}
+	}
+	if maxLen >= 0 {
+		dfs(src)
+	}
+	return out, nil
+}
+
+// ---------- Dominators ----------
+
+// DomTree is the dominator tree of the states reachable from the initial
+// states. State a dominates b when every path from an initial state to b
+// passes through a; every state dominates itself.
+type DomTree struct {
+	idom  map[kripke.StateID]kripke.StateID // absent for roots
+	depth map[kripke.StateID]int            // reachable states only
+}
+
+// Dominators computes the dominator tree of g with the iterative algorithm
+// of Cooper, Harvey and Kennedy. With several initial states a virtual
+// root precedes them all, so an initial state has no immediate dominator.
+func Dominators(g *kripke.Graph) *DomTree {
+	// Number reachable states in reverse postorder, with the virtual root
+	// as 0 and states as 1..n.
+	const root = -1
+	num := make(map[kripke.StateID]int)
+	preds := make(map[kripke.StateID][]kripke.StateID)
+	succ := func(s kripke.StateID) []kripke.StateID {
+		if s == root {
+			return g.InitialStates()
+		}
+		return g.Succ(s)
+	}
+	var post []kripke.StateID
+	visited := map[kripke.StateID]bool{root: true}
+	type frame struct {
+		s kripke.StateID
+		i int
+	}
+	call := []frame{{s: root}}
+	for len(call) > 0 {
+		f := &call[len(call)-1]
+		ss := succ(f.s)
+		if f.i < len(ss) {
+			t := ss[f.i]
+			f.i++
+			preds[t] = append(preds[t], f.s)
+			if !visited[t] {
+				visited[t] = true
+				call = append(call, frame{s: t})
+			}
+			continue
+		}
+		post = append(post, f.s)
+		call = call[:len(call)-1]
+	}
+	var order []kripke.StateID
+	for i := len(post) - 1; i >= 0; i-- {
+		num[post[i]] = len(order)
+		order = append(order, post[i])
+	}
+
+	idom := make([]int, len(order))
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[0] = 0
+	intersect := func(a, b int) int {
+		for a != b {
+			for a > b {
+				a = idom[a]
+			}
+			for b > a {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := 1; i < len(order); i++ {
+			newIdom := -1
+			for _, p := range preds[order[i]] {
+				pi := num[p]
+				if idom[pi] < 0 {
+					continue
+				}
+				if newIdom < 0 {
+					newIdom = pi
+				} else {
+					newIdom = intersect(pi, newIdom)
+				}
+			}
+			if newIdom != idom[i] {
+				idom[i] = newIdom
+				changed = true
+			}
+		}
+	}
+
+	dt := &DomTree{
+		idom:  make(map[kripke.StateID]kripke.StateID),
+		depth: make(map[kripke.StateID]int),
+	}
+	for i := 1; i < len(order); i++ {
+		if idom[i] != 0 {
+			dt.idom[order[i]] = order[idom[i]]
+		}
+	}
+	// Depths follow reverse postorder: a dominator is numbered first.
+	for i := 1; i < len(order); i++ {
+		s := order[i]
+		if d, ok := dt.idom[s]; ok {
+			dt.depth[s] = dt.depth[d] + 1
+		} else {
+			dt.depth[s] = 0
+		}
+	}
+	return dt
+}
+
+// IDom returns the immediate dominator of s. It reports false for initial
+// states that nothing else dominates and for unreachable states.
+func (dt *DomTree) IDom(s kripke.StateID) (kripke.StateID, bool) {
+	d, ok := dt.idom[s]
+	return d, ok
+}
+
+// Dominates reports whether every path from an initial state to b passes
+// through a. It is false when b is unreachable.
+func (dt *DomTree) Dominates(a, b kripke.StateID) bool {
+	if _, ok := dt.depth[b]; !ok {
+		return false
+	}
+	for {
+		if a == b {
+			return true
+		}
+		d, ok := dt.idom[b]
+		if !ok {
+			return false
+		}
+		b = d
+	}
+}
+
+// DominatorsOf returns the dominators of s from the outermost down to s
+// itself, or nil when s is unreachable.
+func (dt *DomTree) DominatorsOf(s kripke.StateID) []kripke.StateID {
+	if _, ok := dt.depth[s]; !ok {
+		return nil
+	}
+	chain := []kripke.StateID{s}
+	for d, ok := dt.idom[s]; ok; d, ok = dt.idom[d] {
+		chain = append(chain, d)
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// AlwaysPasses reports whether every path from an initial state to the
+// state named 'target' passes through the state named 'via'.
+func AlwaysPasses(g *kripke.Graph, via, target string) (bool, error) {
+	a, err := lookup(g, via)
+	if err != nil {
+		return false, err
+	}
+	b, err := lookup(g, target)
+	if err != nil {
+		return false, err
+	}
+	return Dominators(g).Dominates(a, b), nil
+}
@@ -0,0 +1,207 @@
+package graphalg
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+)
+
+func randomGraph(rng *rand.Rand, n, edges, inits int) *kripke.Graph {
+	g := kripke.NewGraph()
+	name := func(i int) string { return fmt.Sprintf("s%d", i) }
+	for i := 0; i < n; i++ {
+		g.AddState(name(i), nil)
+	}
+	for i := 0; i < edges; i++ {
+		g.AddEdge(name(rng.Intn(n)), name(rng.Intn(n)))
+	}
+	for i := 0; i < inits; i++ {
+		g.SetInitial(name(rng.Intn(n)))
+	}
+	return g
+}
+
+// reachableWithout is reachability from the initial states that never
+// enters 'skip'.
+func reachableWithout(g *kripke.Graph, skip kripke.StateID) map[kripke.StateID]bool {
+	var from []kripke.StateID
+	for _, s := range g.InitialStates() {
+		if s != skip {
+			from = append(from, s)
+		}
+	}
+	seen := make(map[kripke.StateID]bool)
+	queue := from
+	for _, s := range from {
+		seen[s] = true
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, t := range g.Succ(s) {
+			if t != skip && !seen[t] {
+				seen[t] = true
+				queue = append(queue, t)
+			}
+		}
+	}
+	return seen
+}
+
+func TestOrderGraph(t *testing.T) {
+	g := kripke.OrderGraph()
+	if ok, err := AlwaysPasses(g, "s1", "s2"); err != nil || !ok {
+		t.Fatalf("every path to Delivered should pass Accepted: %v %v", ok, err)
+	}
+	if ok, _ := AlwaysPasses(g, "s3", "s2"); ok {
+		t.Fatalf("Cancelled does not dominate Delivered")
+	}
+	if got := BottomSCCs(g); !reflect.DeepEqual(got, [][]kripke.StateID{{2}, {3}}) {
+		t.Fatalf("bottom SCCs %v", got)
+	}
+	if got := Unreachable(g); len(got) != 0 {
+		t.Fatalf("unreachable %v", got)
+	}
+	if p, err := ShortestPath(g, "s0", "s3"); err != nil || !reflect.DeepEqual(p, []kripke.StateID{0, 1, 3}) {
+		t.Fatalf("shortest path %v %v", p, err)
+	}
+	if _, err := ShortestPath(g, "s2", "s0"); !errors.Is(err, ErrNoPath) {
+		t.Fatalf("expected ErrNoPath, got %v", err)
+	}
+	if _, err := ShortestPath(g, "s0", "nope"); err == nil {
+		t.Fatalf("expected an error for an unknown state")
+	}
+	if got := Dominators(g).DominatorsOf(2); !reflect.DeepEqual(got, []kripke.StateID{0, 1, 2}) {
+		t.Fatalf("dominators of s2: %v", got)
+	}
+}
+
+func TestSimplePaths(t *testing.T) {
+	g := kripke.NewGraph()
+	for _, e := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "c"}, {"c", "d"}, {"b", "d"}, {"d", "a"}} {
+		g.AddEdge(e[0], e[1])
+	}
+	names := func(paths [][]kripke.StateID) []string {
+		var out []string
+		for _, p := range paths {
+			s := ""
+			for _, id := range p {
+				s += g.NameOf(id)
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+	for _, c := range []struct {
+		max  int
+		want []string
+	}{
+		{0, nil},
+		{1, nil},
+		{2, []string{"abd", "acd"}},
+		{3, []string{"abcd", "abd", "acd"}},
+	} {
+		paths, err := SimplePaths(g, "a", "d", c.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(paths); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("max %d: got %v, want %v", c.max, got, c.want)
+		}
+	}
+	if paths, _ := SimplePaths(g, "a", "a", 5); !reflect.DeepEqual(names(paths), []string{"a"}) {
+		t.Fatalf("a to a: %v", names(paths))
+	}
+}
+
+func TestAgainstBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(36))
+	for iter := 0; iter < 300; iter++ {
+		n := 1 + rng.Intn(12)
+		g := randomGraph(rng, n, rng.Intn(3*n), 1+rng.Intn(2))
+		reach := make([]map[kripke.StateID]bool, n)
+		for s := 0; s < n; s++ {
+			reach[s] = Reachable(g, kripke.StateID(s))
+		}
+
+		// SCCs: same component iff mutually reachable; reverse topological.
+		comp := make(map[kripke.StateID]int)
+		for i, c := range SCCs(g) {
+			for _, s := range c {
+				comp[s] = i
+			}
+		}
+		if len(comp) != n {
+			t.Fatalf("SCCs cover %d of %d states", len(comp), n)
+		}
+		for a := 0; a < n; a++ {
+			for b := 0; b < n; b++ {
+				sa, sb := kripke.StateID(a), kripke.StateID(b)
+				mutual := reach[a][sb] && reach[b][sa]
+				if mutual != (comp[sa] == comp[sb]) {
+					t.Fatalf("states %d and %d: mutual %v, components %d %d", a, b, mutual, comp[sa], comp[sb])
+				}
+			}
+			for _, t2 := range g.Succ(kripke.StateID(a)) {
+				if comp[t2] > comp[kripke.StateID(a)] {
+					t.Fatalf("edge %d -> %d goes to a later component", a, t2)
+				}
+			}
+		}
+
+		// Dominators: a dominates reachable b iff b is unreachable without a.
+		dt := Dominators(g)
+		fromInit := Reachable(g, g.InitialStates()...)
+		for a := 0; a < n; a++ {
+			without := reachableWithout(g, kripke.StateID(a))
+			for b := 0; b < n; b++ {
+				sa, sb := kripke.StateID(a), kripke.StateID(b)
+				want := fromInit[sb] && (a == b || !without[sb])
+				if got := dt.Dominates(sa, sb); got != want {
+					t.Fatalf("Dominates(%d, %d) = %v, want %v", a, b, got, want)
+				}
+			}
+		}
+
+		// Shortest paths are valid and no longer than any simple path.
+		for a := 0; a < n; a++ {
+			for b := 0; b < n; b++ {
+				from, to := g.NameOf(kripke.StateID(a)), g.NameOf(kripke.StateID(b))
+				p, err := ShortestPath(g, from, to)
+				if (err == nil) != reach[a][kripke.StateID(b)] {
+					t.Fatalf("ShortestPath(%s, %s): %v", from, to, err)
+				}
+				if err != nil {
+					continue
+				}
+				for i := 0; i+1 < len(p); i++ {
+					if !contains(g.Succ(p[i]), p[i+1]) {
+						t.Fatalf("path %v uses a missing edge", p)
+					}
+				}
+				paths, _ := SimplePaths(g, from, to, n)
+				if len(paths) == 0 || len(paths[0]) == 0 {
+					t.Fatalf("no simple path from %s to %s", from, to)
+				}
+				for _, sp := range paths {
+					if len(sp) < len(p) {
+						t.Fatalf("simple path %v shorter than shortest %v", sp, p)
+					}
+				}
+			}
+		}
+	}
+}
+
+func contains(ss []kripke.StateID, s kripke.StateID) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
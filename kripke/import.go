@@ -0,0 +1,445 @@
+package kripke
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ---------- TLC -dump dot ----------
+
+// ReadTLCDot builds a Graph from the state graph TLC writes with
+// "-dump dot <file>". States are named by their TLC fingerprint; nodes
+// drawn with style=filled are the initial states. Each line "/\ x = v" of
+// a node label becomes variable x: integers, TRUE/FALSE and strings are
+// typed, any other TLA+ value (sets, records, sequences) is kept as its
+// source text in a string. Legend nodes of "-dump dot,colorize" are
+// skipped. An edge label (the action name, with -dump dot,actionlabels)
+// becomes the edge's action.
+func ReadTLCDot(r io.Reader) (*Graph, error) {
+	dg, err := parseDOT(r)
+	if err != nil {
+		return nil, fmt.Errorf("ReadTLCDot: %w", err)
+	}
+	isState := func(id string) bool {
+		_, err := strconv.ParseInt(id, 10, 64)
+		return err == nil
+	}
+	g := NewGraph()
+	var initial []string
+	for _, n := range dg.nodes {
+		if !isState(n.id) {
+			continue
+		}
+		id := g.AddState(n.id, nil)
+		vars, err := parseTLCState(n.attrs["label"])
+		if err != nil {
+			return nil, fmt.Errorf("ReadTLCDot: state %s: %v", n.id, err)
+		}
+		if len(vars) > 0 {
+			g.SetVars(id, vars)
+		}
+		if strings.Contains(n.attrs["style"], "filled") {
+			initial = append(initial, n.id)
+		}
+	}
+	for _, e := range dg.edges {
+		if !isState(e.from) || !isState(e.to) {
+			continue
+		}
+		g.AddLabeledEdge(e.from, e.to, EdgeLabel{Action: e.attrs["label"]})
+	}
+	for _, name := range initial {
+		g.SetInitial(name)
+	}
+	return g, nil
+}
+
+// parseTLCState reads a TLC state label: one "/\ name = value" per line,
+// or a single "name = value" for a one-variable spec. A value may span
+// several lines; continuation lines are appended to the previous one.
+func parseTLCState(label string) (map[string]any, error) {
+	vars := make(map[string]any)
+	var name string
+	var value strings.Builder
+	flush := func() {
+		if name != "" {
+			vars[name] = tlcValue(strings.TrimSpace(value.String()))
+		}
+		name = ""
+		value.Reset()
+	}
+	for _, line := range strings.Split(label, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rest, conj := strings.CutPrefix(line, `/\`)
+		if conj || name == "" {
+			k, v, ok := strings.Cut(rest, "=")
+			if !ok {
+				return nil, fmt.Errorf("bad assignment %q", line)
+			}
+			flush()
+			name = strings.TrimSpace(k)
+			value.WriteString(v)
+			continue
+		}
+		value.WriteByte(' ')
+		value.WriteString(line)
+	}
+	flush()
+	return vars, nil
+}
+
+// tlcValue types a TLA+ value the way the checker can compare it.
+func tlcValue(s string) any {
+	switch s {
+	case "TRUE":
+		return true
+	case "FALSE":
+		return false
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
+
+// ---------- PRISM explicit ----------
+
+// ReadPRISM builds a Graph from PRISM's explicit export files
+// (-exporttrans, -exportlabels, -exportstates). lab and sta may be nil.
+//
+// The .tra file may describe a DTMC or CTMC ("src dst p" lines, whose
+// probabilities or rates are kept on the edges) or an MDP ("src choice dst
+// p" lines, with a three-number header); the choices of an MDP are merged
+// into plain nondeterministic edges without probabilities. States are
+// named s0, s1, ...; labels from the .lab file become atomic propositions
+// and the "init" label marks the initial states (state 0 when there is no
+// .lab file). Values in the .sta file become variables; doubles are stored
+// as strings.
+func ReadPRISM(tra, lab, sta io.Reader) (*Graph, error) {
+	lines, err := readLines(tra)
+	if err != nil {
+		return nil, fmt.Errorf("ReadPRISM: %w", err)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("ReadPRISM: empty .tra file")
+	}
+	header := strings.Fields(lines[0])
+	if len(header) != 2 && len(header) != 3 {
+		return nil, fmt.Errorf("ReadPRISM: bad .tra header %q", lines[0])
+	}
+	n, err := strconv.Atoi(header[0])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("ReadPRISM: bad state count %q", header[0])
+	}
+	mdp := len(header) == 3
+
+	g := NewGraph()
+	name := func(i int) string { return "s" + strconv.Itoa(i) }
+	for i := 0; i < n; i++ {
+		g.AddState(name(i), nil)
+	}
+	state := func(field string, line int) (int, error) {
+		i, err := strconv.Atoi(field)
+		if err != nil || i < 0 || i >= n {
+			return 0, fmt.Errorf("ReadPRISM: .tra line %d: bad state %q", line, field)
+		}
+		return i, nil
+	}
+	seen := make(map[[2]int]bool)
+	for ln, line := range lines[1:] {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		src, dstField, pField := f[0], "", ""
+		switch {
+		case mdp && len(f) >= 4:
+			dstField, pField = f[2], f[3]
+		case !mdp && len(f) >= 3:
+			dstField, pField = f[1], f[2]
+		default:
+			return nil, fmt.Errorf("ReadPRISM: .tra line %d: too few fields", ln+2)
+		}
+		from, err := state(src, ln+2)
+		if err != nil {
+			return nil, err
+		}
+		to, err := state(dstField, ln+2)
+		if err != nil {
+			return nil, err
+		}
+		p, err := strconv.ParseFloat(pField, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ReadPRISM: .tra line %d: bad probability %q", ln+2, pField)
+		}
+		if mdp {
+			if !seen[[2]int{from, to}] {
+				seen[[2]int{from, to}] = true
+				g.AddEdge(name(from), name(to))
+			}
+			continue
+		}
+		g.AddEdgeProb(name(from), name(to), p)
+	}
+
+	if sta != nil {
+		if err := readPRISMStates(g, n, sta); err != nil {
+			return nil, err
+		}
+	}
+	if lab == nil {
+		if n > 0 {
+			g.SetInitial(name(0))
+		}
+		return g, nil
+	}
+	if err := readPRISMLabels(g, n, lab); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// readPRISMLabels reads a .lab file:
+//
+//	0="init" 1="deadlock" 2="goal"
+//	0: 0
+//	4: 1 2
+func readPRISMLabels(g *Graph, n int, r io.Reader) error {
+	lines, err := readLines(r)
+	if err != nil {
+		return fmt.Errorf("ReadPRISM: %w", err)
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("ReadPRISM: empty .lab file")
+	}
+	names := make(map[string]string)
+	for _, def := range strings.Fields(lines[0]) {
+		idx, q, ok := strings.Cut(def, "=")
+		label, err := strconv.Unquote(q)
+		if !ok || err != nil {
+			return fmt.Errorf("ReadPRISM: bad label definition %q", def)
+		}
+		names[idx] = label
+	}
+	for ln, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		s, rest, ok := strings.Cut(line, ":")
+		i, err := strconv.Atoi(strings.TrimSpace(s))
+		if !ok || err != nil || i < 0 || i >= n {
+			return fmt.Errorf("ReadPRISM: .lab line %d: bad state %q", ln+2, s)
+		}
+		id := StateID(i)
+		for _, idx := range strings.Fields(rest) {
+			label, ok := names[idx]
+			if !ok {
+				return fmt.Errorf("ReadPRISM: .lab line %d: undefined label %s", ln+2, idx)
+			}
+			g.labels[id][label] = true
+			if label == "init" {
+				g.init = append(g.init, id)
+			}
+		}
+	}
+	return nil
+}
+
+// readPRISMStates reads a .sta file:
+//
+//	(x,y,b)
+//	0:(0,1,false)
+func readPRISMStates(g *Graph, n int, r io.Reader) error {
+	lines, err := readLines(r)
+	if err != nil {
+		return fmt.Errorf("ReadPRISM: %w", err)
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("ReadPRISM: empty .sta file")
+	}
+	vars := strings.Split(strings.Trim(strings.TrimSpace(lines[0]), "()"), ",")
+	for ln, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		s, tuple, ok := strings.Cut(line, ":")
+		i, err := strconv.Atoi(strings.TrimSpace(s))
+		if !ok || err != nil || i < 0 || i >= n {
+			return fmt.Errorf("ReadPRISM: .sta line %d: bad state %q", ln+2, s)
+		}
+		vals := strings.Split(strings.Trim(strings.TrimSpace(tuple), "()"), ",")
+		if len(vals) != len(vars) {
+			return fmt.Errorf("ReadPRISM: .sta line %d: %d values for %d variables", ln+2, len(vals), len(vars))
+		}
+		m := make(map[string]any, len(vars))
+		for k, v := range vals {
+			switch v {
+			case "true":
+				m[vars[k]] = true
+			case "false":
+				m[vars[k]] = false
+			default:
+				if iv, err := strconv.Atoi(v); err == nil {
+					m[vars[k]] = iv
+				} else {
+					m[vars[k]] = v
+				}
+			}
+		}
+		g.SetVars(StateID(i), m)
+	}
+	return nil
+}
+
+// ---------- Aldebaran (.aut) ----------
+
+// ReadAUT builds a Graph from a CADP/mCRL2 Aldebaran file:
+//
+//	des (0, 3, 3)
+//	(0, "send", 1)
+//	(1, tau, 2)
+//	(2, "recv(1)", 0)
+//
+// An .aut file labels transitions, while CTL is checked against state
+// labels, so each Kripke state is an LTS state paired with the action that
+// entered it, named "<state>/<action>"; the initial state is named by its
+// number alone. The entering action is both an atomic proposition and the
+// string variable "action", and the LTS state number is the variable
+// "state", so EX action == "send" asks whether send is enabled. The action
+// is also kept as the label of each edge, for action-based logics. Only the
+// part of the LTS reachable by some transition, plus the initial state,
+// appears in the Graph.
+func ReadAUT(r io.Reader) (*Graph, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("ReadAUT: %w", err)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("ReadAUT: empty file")
+	}
+	hdr := strings.TrimSpace(lines[0])
+	rest, ok := strings.CutPrefix(hdr, "des")
+	fields := strings.Split(strings.Trim(strings.TrimSpace(rest), "()"), ",")
+	if !ok || len(fields) != 3 {
+		return nil, fmt.Errorf("ReadAUT: bad header %q", hdr)
+	}
+	var nums [3]int
+	for i, f := range fields {
+		if nums[i], err = strconv.Atoi(strings.TrimSpace(f)); err != nil || nums[i] < 0 {
+			return nil, fmt.Errorf("ReadAUT: bad header %q", hdr)
+		}
+	}
+	init, nStates := nums[0], nums[2]
+	if init >= nStates {
+		return nil, fmt.Errorf("ReadAUT: initial state %d out of range", init)
+	}
+
+	var ts []autTransition
+	for ln, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		t, err := parseAUTLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("ReadAUT: line %d: %v", ln+2, err)
+		}
+		if t.from >= nStates || t.to >= nStates {
+			return nil, fmt.Errorf("ReadAUT: line %d: state out of range", ln+2)
+		}
+		ts = append(ts, t)
+	}
+
+	g := NewGraph()
+	copies := make(map[int][]string) // LTS state -> Kripke states
+	add := func(name string, state int, action string) {
+		if _, ok := g.nameToID[name]; ok {
+			return
+		}
+		var lbls map[string]bool
+		vars := map[string]any{"state": state}
+		if action != "" {
+			lbls = map[string]bool{action: true}
+			vars["action"] = action
+		}
+		g.SetVars(g.AddState(name, lbls), vars)
+		copies[state] = append(copies[state], name)
+	}
+	initName := strconv.Itoa(init)
+	add(initName, init, "")
+	for _, t := range ts {
+		add(fmt.Sprintf("%d/%s", t.to, t.action), t.to, t.action)
+	}
+	type edge struct{ from, to string }
+	seen := make(map[edge]bool)
+	for _, t := range ts {
+		to := fmt.Sprintf("%d/%s", t.to, t.action)
+		for _, from := range copies[t.from] {
+			if e := (edge{from, to}); !seen[e] {
+				seen[e] = true
+				g.AddLabeledEdge(from, to, EdgeLabel{Action: t.action})
+			}
+		}
+	}
+	g.SetInitial(initName)
+	return g, nil
+}
+
+type autTransition struct {
+	from, to int
+	action   string
+}
+
+// parseAUTLine parses "(from, label, to)"; a quoted label may contain
+// commas and parentheses.
+func parseAUTLine(line string) (autTransition, error) {
+	var t autTransition
+	if !strings.HasPrefix(line, "(") || !strings.HasSuffix(line, ")") {
+		return t, fmt.Errorf("bad transition %q", line)
+	}
+	body := line[1 : len(line)-1]
+	first := strings.Index(body, ",")
+	last := strings.LastIndex(body, ",")
+	if first < 0 || first == last {
+		return t, fmt.Errorf("bad transition %q", line)
+	}
+	var err error
+	if t.from, err = strconv.Atoi(strings.TrimSpace(body[:first])); err != nil || t.from < 0 {
+		return t, fmt.Errorf("bad source state in %q", line)
+	}
+	if t.to, err = strconv.Atoi(strings.TrimSpace(body[last+1:])); err != nil || t.to < 0 {
+		return t, fmt.Errorf("bad target state in %q", line)
+	}
+	t.action = strings.TrimSpace(body[first+1 : last])
+	if strings.HasPrefix(t.action, `"`) {
+		if t.action, err = strconv.Unquote(t.action); err != nil {
+			return t, fmt.Errorf("bad label in %q", line)
+		}
+	}
+	if t.action == "" {
+		return t, fmt.Errorf("empty label in %q", line)
+	}
+	return t, nil
+}
+
+// readLines returns the lines of r without their line endings.
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		lines = append(lines, strings.TrimRight(sc.Text(), "\r"))
+	}
+	return lines, sc.Err()
+}
@@ -0,0 +1,151 @@
+package kripke
+
+import (
+	"strings"
+	"testing"
+)
+
+// holdsInitially reports whether the parsed formula holds in every
+// initial state of g.
+func holdsInitially(t *testing.T, g *Graph, src string) bool {
+	t.Helper()
+	sat := MustParseCTL(src).Sat(g)
+	for _, s := range g.InitialStates() {
+		if !sat.Contains(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// tlcDump is what TLC 2.18 writes for a two-bit counter with
+// "-dump dot,colorize,actionlabels".
+const tlcDump = `strict digraph DiskGraph {
+nodesep=0.35
+subgraph cluster_graph {
+color="white"
+-2519627432813426497 [label="/\\ b = FALSE\n/\\ n = 0\n/\\ s = \"idle\"",style = filled]
+-2519627432813426497 -> 6009498343592133120 [label="Inc",color="2",fontcolor="2"];
+6009498343592133120 [label="/\\ b = TRUE\n/\\ n = 1\n/\\ s = \"busy\""];
+6009498343592133120 -> 1185217396213012393 [label="Inc",color="2",fontcolor="2"];
+1185217396213012393 [label="/\\ b = FALSE\n/\\ n = 2\n/\\ s = {1, 2}"];
+1185217396213012393 -> -2519627432813426497 [label="Reset",color="3",fontcolor="3"];
+1185217396213012393 -> 1185217396213012393 [label="Stay",color="4",fontcolor="4"];
+}
+subgraph cluster_legend {graph[style=bold];label = "Next State Actions" style=solid
+node [ fontsize = 12, shape = record, color = white, fontcolor = black]
+"Inc" [color="2",fontcolor="2"]
+"Reset" [color="3",fontcolor="3"]
+"Stay" [color="4",fontcolor="4"]
+}}
+`
+
+func TestReadTLCDot(t *testing.T) {
+	g, err := ReadTLCDot(strings.NewReader(tlcDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(g.States()); n != 3 {
+		t.Fatalf("got %d states, want 3", n)
+	}
+	if init := g.InitialStates(); len(init) != 1 || g.NameOf(init[0]) != "-2519627432813426497" {
+		t.Fatalf("unexpected initial states %v", init)
+	}
+	s2 := g.nameToID["1185217396213012393"]
+	if v, _ := g.Var(s2, "s"); v != "{1, 2}" {
+		t.Fatalf("set value kept as %q", v)
+	}
+	for _, src := range []string{
+		`n == 0 & !b & s == "idle"`,
+		"EX (b & n == 1)",
+		"AG (n <= 2)",
+		"AG EF n == 0",
+		"EF EG n == 2",
+		"AF n == 2",
+	} {
+		if !holdsInitially(t, g, src) {
+			t.Fatalf("%s does not hold", src)
+		}
+	}
+	// Action labels are kept on the edges.
+	if f := MustParseMu("[Inc]<Inc>[Inc](n == 2 & <Reset>n == 0 & <Stay>true)"); !f.Sat(g).Contains(g.InitialStates()[0]) {
+		t.Fatalf("edge actions not kept")
+	}
+}
+
+func TestReadPRISM(t *testing.T) {
+	// A die-like DTMC: from 0 go to 1 or 2 with probability 1/2 each; 1
+	// and 2 are absorbing.
+	tra := "3 4\n0 1 0.5\n0 2 0.5\n1 1 1\n2 2 1\n"
+	lab := `0="init" 1="deadlock" 2="heads"` + "\n0: 0\n1: 2\n"
+	sta := "(x,done,r)\n0:(0,false,0.5)\n1:(1,true,0.5)\n2:(2,true,0.25)\n"
+	g, err := ReadPRISM(strings.NewReader(tra), strings.NewReader(lab), strings.NewReader(sta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.IsProbabilistic() || len(g.Probs(0)) != 2 || g.Probs(0)[1] != 0.5 {
+		t.Fatalf("probabilities not kept: %v", g.Probs(0))
+	}
+	if v, _ := g.Var(2, "r"); v != "0.25" {
+		t.Fatalf("double value kept as %v", v)
+	}
+	for _, src := range []string{"init & x == 0", "EF heads", "!AF heads", "AF done", "AG (heads -> x == 1)"} {
+		if !holdsInitially(t, g, src) {
+			t.Fatalf("%s does not hold", src)
+		}
+	}
+
+	// The same chain as an MDP with two choices in state 0, no .lab file.
+	mdp := "3 4 5\n0 0 1 1 a\n0 1 1 0.5 b\n0 1 2 0.5 b\n1 0 1 1\n2 0 2 1\n"
+	g, err = ReadPRISM(strings.NewReader(mdp), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.IsProbabilistic() || len(g.Succ(0)) != 2 || len(g.InitialStates()) != 1 {
+		t.Fatalf("unexpected MDP graph %+v", g.data())
+	}
+
+	for _, bad := range []string{"", "x 1\n", "2 1\n0 5 1\n", "2 1\n0 1 x\n", "2 1\n0\n"} {
+		if _, err := ReadPRISM(strings.NewReader(bad), nil, nil); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestReadAUT(t *testing.T) {
+	src := `des (0, 5, 3)
+(0, "send(1, 2)", 1)
+(1, tau, 2)
+(2, "recv", 0)
+(2, "recv", 0)
+(1, "send(1, 2)", 1)
+`
+	g, err := ReadAUT(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0, 1/send(1, 2), 2/tau, 0/recv
+	if n := len(g.States()); n != 4 {
+		t.Fatalf("got %d states, want 4: %+v", n, g.data())
+	}
+	for _, src := range []string{
+		`state == 0 & !EX action == "recv"`,
+		`EX action == "send(1, 2)"`,
+		"AG (tau -> AX recv)",
+		"AG AF state == 0 | EF EG state == 1",
+		`AG (action == "recv" -> state == 0)`,
+	} {
+		if !holdsInitially(t, g, src) {
+			t.Fatalf("%s does not hold", src)
+		}
+	}
+	if f := MustParseMu(`<"send(1, 2)"><tau>[true]<"send(1, 2)">true`); !f.Sat(g).Contains(g.InitialStates()[0]) {
+		t.Fatalf("edge actions not kept")
+	}
+
+	for _, bad := range []string{"", "des (0, 1)\n", "des (3, 0, 2)\n", "des (0, 1, 2)\n(0, a, 5)\n", "des (0, 1, 2)\n(0, 1)\n"} {
+		if _, err := ReadAUT(strings.NewReader(bad)); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
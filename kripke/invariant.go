@@ -0,0 +1,100 @@
+package kripke
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Invariant is a safety condition over a World. It returns nil when the
+// world is fine and an error describing the problem otherwise.
+type Invariant func(*World) error
+
+// Predicate is a boolean property of a World. The same predicate can be
+// registered as an invariant with Always.
+type Predicate func(*World) bool
+
+// ErrPredicateFalse is the error of an invariant made by Always.
+var ErrPredicateFalse = errors.New("predicate is false")
+
+// Always turns a predicate into an invariant that fails with
+// ErrPredicateFalse whenever the predicate is false. The name it is
+// reported under is the one given to AddInvariant.
+func Always(p Predicate) Invariant {
+	return func(w *World) error {
+		if !p(w) {
+			return ErrPredicateFalse
+		}
+		return nil
+	}
+}
+
+type namedInvariant struct {
+	name  string
+	check Invariant
+}
+
+// AddInvariant registers an invariant that RunSteps and Explore check after
+// every tick. Worlds cloned from w inherit it.
+func (w *World) AddInvariant(name string, inv Invariant) {
+	w.invariants = append(w.invariants, namedInvariant{name: name, check: inv})
+}
+
+// checkInvariants returns the first failing invariant as a Violation, or nil.
+// The Violation's Trace is the world's own trace from NewWorld.
+func (w *World) checkInvariants() *Violation {
+	for _, inv := range w.invariants {
+		if err := inv.check(w); err != nil {
+			return &Violation{
+				Invariant: inv.name,
+				Err:       err,
+				Time:      w.Time,
+				Trace:     w.Trace(),
+				Events:    append([]Event(nil), w.Events...),
+			}
+		}
+	}
+	return nil
+}
+
+// Violation reports the first invariant found to fail, together with how
+// the failing World was reached.
+type Violation struct {
+	Invariant string
+	Err       error
+	Time      int
+
+	// Trace is the enabled-step index chosen at each tick. For RunSteps it
+	// starts at NewWorld; for Explore and Resume it starts at the world
+	// passed in, so Replay(w, Trace) rebuilds the failing World.
+	Trace []int
+
+	// States names the graph states along the trace (exploration only).
+	States []string
+
+	// Events is the event log of the failing World: the prefix of messages
+	// received on the way to the violation.
+	Events []Event
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("invariant %s violated at t=%d: %v", v.Invariant, v.Time, v.Err)
+}
+
+func (v *Violation) Unwrap() error { return v.Err }
+
+// String renders the violation and its trace for humans.
+func (v *Violation) String() string {
+	var sb strings.Builder
+	sb.WriteString(v.Error())
+	sb.WriteString("\n")
+	if len(v.States) > 0 {
+		sb.WriteString("  path: ")
+		sb.WriteString(strings.Join(v.States, " -> "))
+		sb.WriteString("\n")
+	}
+	for _, ev := range v.Events {
+		sb.WriteString(fmt.Sprintf("  t=%d %s -> %s payload=%v\n", ev.Time, ev.From, ev.To, ev.Payload))
+	}
+	return sb.String()
+}
@@ -0,0 +1,69 @@
+package kripke
+
+import (
+	"errors"
+	"testing"
+)
+
+// queueBelow fails once the inbox holds n or more messages.
+func queueBelow(n int) Predicate {
+	return func(w *World) bool {
+		return w.ChannelByAddress(Address{"C", "inbox"}).Len() < n
+	}
+}
+
+func TestRunStepsStopsAtViolation(t *testing.T) {
+	w := producerConsumerWorld(10, 5)
+	w.AddInvariant("queue_below_2", Always(queueBelow(2)))
+
+	// With seed 1 the random schedule sends twice in a row sooner or later;
+	// whichever tick that is, the run must stop right there.
+	err := w.RunSteps(1000)
+	var v *Violation
+	if !errors.As(err, &v) {
+		t.Fatalf("expected a *Violation, got %v", err)
+	}
+	if v.Invariant != "queue_below_2" || !errors.Is(err, ErrPredicateFalse) || v.Time != w.Time || len(v.Trace) != w.Time {
+		t.Fatalf("unexpected violation %+v at t=%d", v, w.Time)
+	}
+	if w.ChannelByAddress(Address{"C", "inbox"}).Len() != 2 {
+		t.Fatalf("run did not stop at the first violating tick")
+	}
+	if len(v.Events) != len(w.Events) {
+		t.Fatalf("violation carries %d events, world has %d", len(v.Events), len(w.Events))
+	}
+
+	// The trace replays to the same failing state.
+	again, err := Replay(producerConsumerWorld(10, 5), v.Trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.StateKey() != w.StateKey() {
+		t.Fatalf("replayed trace reached a different state")
+	}
+}
+
+func TestExploreStopsAtViolation(t *testing.T) {
+	w := producerConsumerWorld(5, 3)
+	w.AddInvariant("queue_below_3", Always(queueBelow(3)))
+
+	_, err := Explore(w)
+	var v *Violation
+	if !errors.As(err, &v) {
+		t.Fatalf("expected a *Violation, got %v", err)
+	}
+	// Breadth-first search finds the shortest counterexample: three sends.
+	if len(v.Trace) != 3 || len(v.States) != 4 || v.States[0] != "w0" {
+		t.Fatalf("expected a 3-step trace through 4 states, got %v / %v", v.Trace, v.States)
+	}
+	if len(v.Events) != 0 {
+		t.Fatalf("no messages were received on the way, got %d events", len(v.Events))
+	}
+	bad, err := Replay(w, v.Trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queueBelow(3)(bad) {
+		t.Fatalf("replayed trace does not violate the invariant")
+	}
+}
@@ -0,0 +1,591 @@
+package kripke
+
+import (
+	"sort"
+	"strings"
+)
+
+// Quotient is the result of minimizing a Graph: the quotient graph plus the
+// mapping between original states and their equivalence classes.
+type Quotient struct {
+	Graph *Graph
+
+	// Block maps every original state to its state in Graph.
+	Block map[StateID]StateID
+
+	// Members lists the original states in each quotient state, sorted.
+	Members map[StateID][]StateID
+}
+
+// Minimize computes the coarsest strong bisimulation of g that respects the
+// given propositions, using Paige–Tarjan partition refinement, and returns
+// the quotient. Every CTL formula over props has the same truth value in a
+// state and in its block.
+//
+// Quotient states are named after their lowest-numbered member and are
+// labelled with props only.
+func Minimize(g *Graph, props []string) *Quotient {
+	k := denseOf(g)
+	pt := newPaigeTarjan(k, labelClasses(g, k, props))
+	pt.refine()
+	return k.quotient(g, props, pt.blockOf, nil)
+}
+
+// MinimizeStuttering computes the coarsest divergence-sensitive stuttering
+// bisimulation of g that respects props. The quotient preserves CTL without
+// the next-time operators (CTL-X): EX and AX are not preserved.
+//
+// Steps that stay inside a block are invisible in the quotient; a block in
+// which a path can stay forever gets a self-loop.
+func MinimizeStuttering(g *Graph, props []string) *Quotient {
+	k := denseOf(g)
+
+	// States on a cycle of equally-labelled states are stutter equivalent,
+	// and each of them can diverge. Contract those cycles first so the
+	// refinement below only sees inert steps that make progress.
+	classes := labelClasses(g, k, props)
+	comp, ncomp := k.sccs(func(x, y int) bool { return classes[x] == classes[y] })
+	divergent := make([]bool, ncomp)
+	size := make([]int, ncomp)
+	for x := range k.ids {
+		size[comp[x]]++
+	}
+	for x, succ := range k.succ {
+		for _, y := range succ {
+			if comp[x] == comp[y] && (x == y || size[comp[x]] > 1) {
+				divergent[comp[x]] = true
+			}
+		}
+	}
+
+	// Divergence is observable: give every divergent component a step to a
+	// sink with a label of its own, so the refinement separates states that
+	// can stutter forever from those that cannot.
+	sink := ncomp
+	c := &dense{succ: make([][]int, ncomp+1), ids: make([]StateID, ncomp+1)}
+	initial := make([]int, ncomp+1)
+	for x := range k.ids {
+		initial[comp[x]] = classes[x]
+		if classes[x] >= initial[sink] {
+			initial[sink] = classes[x] + 1
+		}
+	}
+	seen := make(map[[2]int]bool)
+	for x, succ := range k.succ {
+		for _, y := range succ {
+			e := [2]int{comp[x], comp[y]}
+			if e[0] != e[1] && !seen[e] {
+				seen[e] = true
+				c.succ[e[0]] = append(c.succ[e[0]], e[1])
+			}
+		}
+	}
+	for i, d := range divergent {
+		if d {
+			c.succ[i] = append(c.succ[i], sink)
+		}
+	}
+
+	blockOfComp := stutterRefine(c, initial)
+	blockOf := make([]int, len(k.ids))
+	for x := range k.ids {
+		blockOf[x] = blockOfComp[comp[x]]
+	}
+	divBlock := make(map[int]bool)
+	for i, d := range divergent {
+		if d {
+			divBlock[blockOfComp[i]] = true
+		}
+	}
+	return k.quotient(g, props, blockOf, divBlock)
+}
+
+// ---------- dense graph view ----------
+
+// dense is g with states renumbered 0..n-1 in StateID order and duplicate
+// edges removed.
+type dense struct {
+	ids  []StateID
+	idx  map[StateID]int
+	succ [][]int
+}
+
+func denseOf(g *Graph) *dense {
+	ids := g.States()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	k := &dense{ids: ids, idx: make(map[StateID]int, len(ids)), succ: make([][]int, len(ids))}
+	for i, id := range ids {
+		k.idx[id] = i
+	}
+	for i, id := range ids {
+		seen := make(map[int]bool)
+		for _, t := range g.Succ(id) {
+			j := k.idx[t]
+			if !seen[j] {
+				seen[j] = true
+				k.succ[i] = append(k.succ[i], j)
+			}
+		}
+	}
+	return k
+}
+
+// labelClasses numbers states by their valuation of props.
+func labelClasses(g *Graph, k *dense, props []string) []int {
+	classOf := make(map[string]int)
+	out := make([]int, len(k.ids))
+	for i, id := range k.ids {
+		var sb strings.Builder
+		for _, p := range props {
+			if g.HasLabel(id, p) {
+				sb.WriteByte('1')
+			} else {
+				sb.WriteByte('0')
+			}
+		}
+		key := sb.String()
+		c, ok := classOf[key]
+		if !ok {
+			c = len(classOf)
+			classOf[key] = c
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// sccs returns the strongly connected components of the subgraph keeping
+// only edges accepted by keep.
+func (k *dense) sccs(keep func(x, y int) bool) ([]int, int) {
+	return tarjanSCCs(k.succ, keep)
+}
+
+// tarjanSCCs numbers the strongly connected components of the graph with
+// adjacency lists succ, keeping only edges accepted by keep (all of them
+// when keep is nil). It returns each vertex's component and the number of
+// components (Tarjan's algorithm, iterative).
+func tarjanSCCs(succ [][]int, keep func(x, y int) bool) ([]int, int) {
+	n := len(succ)
+	index := make([]int, n)
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	comp := make([]int, n)
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int
+	next, ncomp := 0, 0
+
+	type frame struct{ v, i int }
+	for root := 0; root < n; root++ {
+		if index[root] >= 0 {
+			continue
+		}
+		call := []frame{{root, 0}}
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+		for len(call) > 0 {
+			f := &call[len(call)-1]
+			if f.i < len(succ[f.v]) {
+				w := succ[f.v][f.i]
+				f.i++
+				if keep != nil && !keep(f.v, w) {
+					continue
+				}
+				if index[w] < 0 {
+					index[w], low[w] = next, next
+					next++
+					stack = append(stack, w)
+					onStack[w] = true
+					call = append(call, frame{w, 0})
+				} else if onStack[w] && index[w] < low[f.v] {
+					low[f.v] = index[w]
+				}
+				continue
+			}
+			v := f.v
+			call = call[:len(call)-1]
+			if len(call) > 0 {
+				if u := call[len(call)-1].v; low[v] < low[u] {
+					low[u] = low[v]
+				}
+			}
+			if low[v] == index[v] {
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					comp[w] = ncomp
+					if w == v {
+						break
+					}
+				}
+				ncomp++
+			}
+		}
+	}
+	return comp, ncomp
+}
+
+// quotient builds the block graph. With divergent == nil (strong
+// bisimulation) every edge is kept; otherwise edges inside a block are
+// dropped and divergent blocks get a self-loop.
+func (k *dense) quotient(g *Graph, props []string, blockOf []int, divergent map[int]bool) *Quotient {
+	// Number blocks by their lowest member so the result is deterministic.
+	rank := make(map[int]int)
+	var reps []int
+	for x := range k.ids {
+		if _, ok := rank[blockOf[x]]; !ok {
+			rank[blockOf[x]] = len(reps)
+			reps = append(reps, x)
+		}
+	}
+
+	q := &Quotient{
+		Graph:   NewGraph(),
+		Block:   make(map[StateID]StateID, len(k.ids)),
+		Members: make(map[StateID][]StateID, len(reps)),
+	}
+	for _, x := range reps {
+		lbls := make(map[string]bool, len(props))
+		for _, p := range props {
+			lbls[p] = g.HasLabel(k.ids[x], p)
+		}
+		q.Graph.AddState(g.NameOf(k.ids[x]), lbls)
+	}
+	for x, id := range k.ids {
+		b := StateID(rank[blockOf[x]])
+		q.Block[id] = b
+		q.Members[b] = append(q.Members[b], id)
+	}
+
+	seen := make(map[[2]int]bool)
+	addEdge := func(from, to int) {
+		e := [2]int{from, to}
+		if !seen[e] {
+			seen[e] = true
+			q.Graph.AddEdge(q.Graph.NameOf(StateID(from)), q.Graph.NameOf(StateID(to)))
+		}
+	}
+	for x, succ := range k.succ {
+		for _, y := range succ {
+			from, to := rank[blockOf[x]], rank[blockOf[y]]
+			if divergent != nil && from == to {
+				continue
+			}
+			addEdge(from, to)
+		}
+	}
+	var loops []int
+	for b := range divergent {
+		loops = append(loops, rank[b])
+	}
+	sort.Ints(loops)
+	for _, b := range loops {
+		addEdge(b, b)
+	}
+
+	initSeen := make(map[StateID]bool)
+	for _, s := range g.InitialStates() {
+		if b := q.Block[s]; !initSeen[b] {
+			initSeen[b] = true
+			q.Graph.SetInitial(q.Graph.NameOf(b))
+		}
+	}
+	return q
+}
+
+// ---------- Paige–Tarjan ----------
+
+// paigeTarjan holds the state of the relational coarsest partition
+// algorithm. P-blocks partition the states; X-blocks ("compound" when they
+// hold more than one P-block) are unions of P-blocks the partition is
+// already stable with respect to. count records store |succ(x) ∩ S| for a
+// state x and X-block S, shared by every edge from x into S.
+type paigeTarjan struct {
+	k    *dense
+	pred [][]int // pred[y] = edge indices x->y
+
+	edgeFrom  []int
+	edgeCount []int // edge -> count record
+	counts    []int
+
+	blockOf []int   // state -> P-block
+	elems   [][]int // P-block -> states
+	pos     []int   // state -> index in elems[blockOf[state]]
+	xOf     []int   // P-block -> X-block
+	xBlocks [][]int // X-block -> P-blocks
+	xPos    []int   // P-block -> index in xBlocks[xOf[block]]
+	size    []int   // X-block -> number of states
+
+	compound []int // X-blocks with two or more P-blocks
+	inC      []bool
+}
+
+func newPaigeTarjan(k *dense, initial []int) *paigeTarjan {
+	n := len(k.ids)
+	pt := &paigeTarjan{k: k, pred: make([][]int, n), blockOf: make([]int, n), pos: make([]int, n)}
+
+	// One count record per state for the initial X-block U = all states.
+	pt.counts = make([]int, n)
+	for x, succ := range k.succ {
+		for _, y := range succ {
+			e := len(pt.edgeFrom)
+			pt.edgeFrom = append(pt.edgeFrom, x)
+			pt.edgeCount = append(pt.edgeCount, x)
+			pt.pred[y] = append(pt.pred[y], e)
+		}
+		pt.counts[x] = len(succ)
+	}
+
+	// Initial partition: label class, split by whether a state has any
+	// successor (stability with respect to U).
+	classOf := make(map[[2]int]int)
+	for x := 0; x < n; x++ {
+		key := [2]int{initial[x], 0}
+		if len(k.succ[x]) > 0 {
+			key[1] = 1
+		}
+		b, ok := classOf[key]
+		if !ok {
+			b = len(pt.elems)
+			classOf[key] = b
+			pt.elems = append(pt.elems, nil)
+		}
+		pt.blockOf[x] = b
+		pt.pos[x] = len(pt.elems[b])
+		pt.elems[b] = append(pt.elems[b], x)
+	}
+
+	pt.xBlocks = [][]int{nil}
+	pt.size = []int{n}
+	for b := range pt.elems {
+		pt.xOf = append(pt.xOf, 0)
+		pt.xPos = append(pt.xPos, len(pt.xBlocks[0]))
+		pt.xBlocks[0] = append(pt.xBlocks[0], b)
+	}
+	pt.inC = []bool{false}
+	pt.markCompound(0)
+	return pt
+}
+
+func (pt *paigeTarjan) markCompound(s int) {
+	if !pt.inC[s] && len(pt.xBlocks[s]) > 1 {
+		pt.inC[s] = true
+		pt.compound = append(pt.compound, s)
+	}
+}
+
+func (pt *paigeTarjan) refine() {
+	for len(pt.compound) > 0 {
+		s := pt.compound[len(pt.compound)-1]
+		pt.compound = pt.compound[:len(pt.compound)-1]
+		pt.inC[s] = false
+		if len(pt.xBlocks[s]) < 2 {
+			// A split that moved a whole block left S simple again.
+			continue
+		}
+
+		// Take the smaller of two P-blocks of S as the splitter B and move
+		// it into an X-block of its own.
+		b := pt.xBlocks[s][0]
+		if other := pt.xBlocks[s][1]; len(pt.elems[other]) < len(pt.elems[b]) {
+			b = other
+		}
+		pt.removeFromX(b)
+		sb := len(pt.xBlocks)
+		pt.xBlocks = append(pt.xBlocks, []int{b})
+		pt.size = append(pt.size, len(pt.elems[b]))
+		pt.size[s] -= len(pt.elems[b])
+		pt.inC = append(pt.inC, false)
+		pt.xOf[b] = sb
+		pt.xPos[b] = 0
+		pt.markCompound(s)
+
+		// count(x, B) for every x in pre(B), and the count(x, S) record
+		// its edges into B currently share. B itself may be split below,
+		// so remember its states.
+		bElems := append([]int(nil), pt.elems[b]...)
+		countB := make(map[int]int)
+		countS := make(map[int]int)
+		var preB []int
+		for _, y := range bElems {
+			for _, e := range pt.pred[y] {
+				x := pt.edgeFrom[e]
+				if _, ok := countB[x]; !ok {
+					preB = append(preB, x)
+					countS[x] = pt.edgeCount[e]
+				}
+				countB[x]++
+			}
+		}
+
+		// Three-way split of every block D touched by pre(B):
+		// D \ pre(B), D ∩ pre(B) ∩ pre(S\B), and D ∩ pre(B) \ pre(S\B).
+		type key struct{ d, onlyB int }
+		split := make(map[key]int)
+		var touched []int
+		for _, x := range preB {
+			onlyB := 0
+			if countB[x] == pt.counts[countS[x]] {
+				onlyB = 1
+			}
+			d := pt.blockOf[x]
+			kk := key{d, onlyB}
+			nb, ok := split[kk]
+			if !ok {
+				nb = pt.newBlock(d)
+				split[kk] = nb
+				if _, seen := split[key{d, 1 - onlyB}]; !seen {
+					touched = append(touched, d)
+				}
+			}
+			pt.move(x, nb)
+		}
+		for _, d := range touched {
+			if len(pt.elems[d]) == 0 {
+				pt.dropBlock(d)
+			}
+		}
+
+		// Edges into B now count against their own record count(x, B).
+		newRec := make(map[int]int)
+		for _, y := range bElems {
+			for _, e := range pt.pred[y] {
+				x := pt.edgeFrom[e]
+				pt.counts[pt.edgeCount[e]]--
+				r, ok := newRec[x]
+				if !ok {
+					r = len(pt.counts)
+					pt.counts = append(pt.counts, 0)
+					newRec[x] = r
+				}
+				pt.counts[r]++
+				pt.edgeCount[e] = r
+			}
+		}
+	}
+}
+
+// newBlock creates an empty P-block in the same X-block as d.
+func (pt *paigeTarjan) newBlock(d int) int {
+	nb := len(pt.elems)
+	pt.elems = append(pt.elems, nil)
+	s := pt.xOf[d]
+	pt.xOf = append(pt.xOf, s)
+	pt.xPos = append(pt.xPos, len(pt.xBlocks[s]))
+	pt.xBlocks[s] = append(pt.xBlocks[s], nb)
+	pt.markCompound(s)
+	return nb
+}
+
+func (pt *paigeTarjan) move(x, to int) {
+	from := pt.blockOf[x]
+	el := pt.elems[from]
+	last := el[len(el)-1]
+	el[pt.pos[x]] = last
+	pt.pos[last] = pt.pos[x]
+	pt.elems[from] = el[:len(el)-1]
+
+	pt.blockOf[x] = to
+	pt.pos[x] = len(pt.elems[to])
+	pt.elems[to] = append(pt.elems[to], x)
+}
+
+func (pt *paigeTarjan) removeFromX(b int) {
+	s := pt.xOf[b]
+	bs := pt.xBlocks[s]
+	last := bs[len(bs)-1]
+	bs[pt.xPos[b]] = last
+	pt.xPos[last] = pt.xPos[b]
+	pt.xBlocks[s] = bs[:len(bs)-1]
+}
+
+// dropBlock removes an emptied P-block from its X-block.
+func (pt *paigeTarjan) dropBlock(d int) {
+	pt.removeFromX(d)
+	pt.xOf[d] = -1
+}
+
+// ---------- stuttering refinement ----------
+
+// stutterRefine computes the coarsest stuttering bisimulation refining
+// initial on a graph without inert cycles (Groote–Vaandrager style): block
+// C is split by splitter B ≠ C into the states that can reach B through
+// steps inside C and those that cannot, until no splitter splits anything.
+func stutterRefine(k *dense, initial []int) []int {
+	n := len(k.ids)
+	pred := make([][]int, n)
+	for x, succ := range k.succ {
+		for _, y := range succ {
+			pred[y] = append(pred[y], x)
+		}
+	}
+	blockOf := make([]int, n)
+	remap := make(map[int]int)
+	for x, c := range initial {
+		b, ok := remap[c]
+		if !ok {
+			b = len(remap)
+			remap[c] = b
+		}
+		blockOf[x] = b
+	}
+	nblocks := len(remap)
+
+	for changed := true; changed; {
+		changed = false
+		for b := 0; b < nblocks; b++ {
+			// pos = states outside B with a step into B, closed backwards
+			// under inert steps.
+			pos := make([]bool, n)
+			var queue []int
+			for y := 0; y < n; y++ {
+				if blockOf[y] != b {
+					continue
+				}
+				for _, x := range pred[y] {
+					if blockOf[x] != b && !pos[x] {
+						pos[x] = true
+						queue = append(queue, x)
+					}
+				}
+			}
+			for len(queue) > 0 {
+				y := queue[0]
+				queue = queue[1:]
+				for _, x := range pred[y] {
+					if blockOf[x] == blockOf[y] && !pos[x] {
+						pos[x] = true
+						queue = append(queue, x)
+					}
+				}
+			}
+
+			hasNeg := make(map[int]bool)
+			for x := 0; x < n; x++ {
+				if blockOf[x] != b && !pos[x] {
+					hasNeg[blockOf[x]] = true
+				}
+			}
+			fresh := make(map[int]int)
+			for x := 0; x < n; x++ {
+				c := blockOf[x]
+				if c == b || !pos[x] || !hasNeg[c] {
+					continue
+				}
+				nb, ok := fresh[c]
+				if !ok {
+					nb = nblocks
+					nblocks++
+					fresh[c] = nb
+				}
+				blockOf[x] = nb
+				changed = true
+			}
+		}
+	}
+	return blockOf
+}
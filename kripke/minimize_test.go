@@ -0,0 +1,191 @@
+package kripke
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// randomGraph builds a graph with n states, random edges and random p/q
+// labels drawn from rng.
+func randomGraph(rng *rand.Rand, n, edges int) *Graph {
+	g := NewGraph()
+	for i := 0; i < n; i++ {
+		g.AddState(fmt.Sprintf("s%d", i), map[string]bool{
+			"p": rng.Intn(2) == 0,
+			"q": rng.Intn(3) == 0,
+		})
+	}
+	for i := 0; i < edges; i++ {
+		g.AddEdge(fmt.Sprintf("s%d", rng.Intn(n)), fmt.Sprintf("s%d", rng.Intn(n)))
+	}
+	g.SetInitial("s0")
+	return g
+}
+
+// randomFormula builds a random CTL formula over p and q. With next false
+// it avoids EX and AX.
+func randomFormula(rng *rand.Rand, depth int, next bool) Formula {
+	if depth == 0 {
+		return Atom([]string{"p", "q"}[rng.Intn(2)])
+	}
+	sub := func() Formula { return randomFormula(rng, depth-1, next) }
+	ops := 8
+	if next {
+		ops = 10
+	}
+	switch rng.Intn(ops) {
+	case 0:
+		return Not(sub())
+	case 1:
+		return And(sub(), sub())
+	case 2:
+		return Or(sub(), sub())
+	case 3:
+		return EU(sub(), sub())
+	case 4:
+		return EF(sub())
+	case 5:
+		return AF(sub())
+	case 6:
+		return EG(sub())
+	case 7:
+		return AG(sub())
+	case 8:
+		return EX(sub())
+	}
+	return AX(sub())
+}
+
+// checkPreserved verifies that f has the same truth value in every state
+// of g and in its block of q.
+func checkPreserved(t *testing.T, g *Graph, q *Quotient, f Formula) {
+	t.Helper()
+	orig, quot := f.Sat(g), f.Sat(q.Graph)
+	for _, s := range g.States() {
+		if orig.Contains(s) != quot.Contains(q.Block[s]) {
+			t.Fatalf("formula %#v differs at %s (block %s)", f, g.NameOf(s), q.Graph.NameOf(q.Block[s]))
+		}
+	}
+}
+
+func TestMinimizeOrderGraph(t *testing.T) {
+	// Ignoring "cancelled", Delivered and Cancelled only differ by
+	// "delivered"; with no props at all every state is equivalent.
+	g := OrderGraph()
+	q := Minimize(g, nil)
+	if n := len(q.Graph.States()); n != 1 {
+		t.Fatalf("expected 1 block with no props, got %d", n)
+	}
+	q = Minimize(g, []string{"accepted", "delivered", "cancelled"})
+	if n := len(q.Graph.States()); n != 4 {
+		t.Fatalf("expected 4 blocks, got %d", n)
+	}
+	q = Minimize(g, []string{"accepted"})
+	// s0 | s1 s2 s3 are all accepted and can step forever within accepted.
+	if n := len(q.Graph.States()); n != 2 {
+		t.Fatalf("expected 2 blocks for {accepted}, got %d", n)
+	}
+}
+
+func TestMinimizeStutteringChain(t *testing.T) {
+	// a chain of n equally-labelled states ending in a q loop collapses to
+	// two states under stuttering, but not under strong bisimulation.
+	g := NewGraph()
+	for i := 0; i < 5; i++ {
+		g.AddState(fmt.Sprintf("s%d", i), map[string]bool{"p": true})
+	}
+	g.AddState("done", map[string]bool{"q": true})
+	for i := 0; i < 4; i++ {
+		g.AddEdge(fmt.Sprintf("s%d", i), fmt.Sprintf("s%d", i+1))
+	}
+	g.AddEdge("s4", "done")
+	g.AddEdge("done", "done")
+	g.SetInitial("s0")
+
+	if n := len(Minimize(g, []string{"p", "q"}).Graph.States()); n != 6 {
+		t.Fatalf("strong bisimulation: expected 6 blocks, got %d", n)
+	}
+	q := MinimizeStuttering(g, []string{"p", "q"})
+	if n := len(q.Graph.States()); n != 2 {
+		t.Fatalf("stuttering: expected 2 blocks, got %d", n)
+	}
+
+	// A p self-loop makes s2 divergent: it may stay in p forever.
+	g.AddEdge("s2", "s2")
+	q = MinimizeStuttering(g, []string{"p", "q"})
+	if n := len(q.Graph.States()); n != 3 {
+		t.Fatalf("stuttering with divergence: expected 3 blocks, got %d", n)
+	}
+}
+
+func TestMinimizePreservesCTL(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 200; i++ {
+		n := 2 + rng.Intn(12)
+		g := randomGraph(rng, n, rng.Intn(3*n))
+		strong := Minimize(g, []string{"p", "q"})
+		stutter := MinimizeStuttering(g, []string{"p", "q"})
+		if len(stutter.Graph.States()) > len(strong.Graph.States()) {
+			t.Fatalf("stuttering quotient larger than strong quotient")
+		}
+		for j := 0; j < 20; j++ {
+			checkPreserved(t, g, strong, randomFormula(rng, 3, true))
+			checkPreserved(t, g, stutter, randomFormula(rng, 3, false))
+		}
+		if want := naiveBisimBlocks(g, []string{"p", "q"}); len(strong.Graph.States()) != want {
+			t.Fatalf("Paige–Tarjan found %d blocks, naive refinement %d", len(strong.Graph.States()), want)
+		}
+		// Strong bisimulation is the coarsest: re-minimizing is a no-op.
+		if again := Minimize(strong.Graph, []string{"p", "q"}); len(again.Graph.States()) != len(strong.Graph.States()) {
+			t.Fatalf("minimizing a quotient shrank it further")
+		}
+	}
+}
+
+// naiveBisimBlocks counts strong bisimulation classes by refining
+// signatures (labels + set of successor blocks) until nothing changes.
+func naiveBisimBlocks(g *Graph, props []string) int {
+	block := make(map[StateID]string)
+	for _, s := range g.States() {
+		for _, p := range props {
+			block[s] += fmt.Sprint(g.HasLabel(s, p))
+		}
+	}
+	count := func() int {
+		seen := make(map[string]bool)
+		for _, b := range block {
+			seen[b] = true
+		}
+		return len(seen)
+	}
+	for {
+		before := count()
+		next := make(map[StateID]string)
+		for _, s := range g.States() {
+			succ := make(map[string]bool)
+			for _, t := range g.Succ(s) {
+				succ[block[t]] = true
+			}
+			keys := make([]string, 0, len(succ))
+			for k := range succ {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			next[s] = block[s] + "|" + strings.Join(keys, ",")
+		}
+		// Renumber so signatures don't nest.
+		ids := make(map[string]string)
+		for s, sig := range next {
+			if _, ok := ids[sig]; !ok {
+				ids[sig] = fmt.Sprint(len(ids))
+			}
+			block[s] = ids[sig]
+		}
+		if count() == before {
+			return before
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package kripke
+
+import "fmt"
+
+// ---------- modal μ-calculus ----------
+//
+// The μ-calculus talks about the actions on edges (see EdgeLabel) as well
+// as the labels of states. <a>φ holds where some a-edge leads to a φ
+// state, [a]φ where every a-edge does, μX.φ is the least and νX.φ the
+// greatest fixpoint of φ in the variable X. Its formulas are ordinary
+// Formulas and mix freely with the CTL ones: the CTL operators are
+// fixpoints that ignore actions, e.g. EF φ = μX. φ ∨ <true>X.
+//
+// A fixpoint is computed by iterating its body from the empty set (μ) or
+// the full set (ν), substituting the current approximation for the
+// variable each round. Bodies must be monotone in their variable (it
+// occurs under an even number of negations), which ValidateMu checks.
+
+// ActionFormula matches edges by their action.
+type ActionFormula interface {
+	MatchAction(l EdgeLabel) bool
+}
+
+// ActName matches edges whose action is exactly Name.
+type ActName struct {
+	Name string
+}
+
+// Act matches the action name.
+func Act(name string) ActionFormula { return ActName{Name: name} }
+
+func (a ActName) MatchAction(l EdgeLabel) bool { return l.Action == a.Name }
+
+// ActAny matches every edge, with or without an action.
+type ActAny struct{}
+
+// AnyAction matches every edge; <true>φ is EX φ.
+func AnyAction() ActionFormula { return ActAny{} }
+
+func (ActAny) MatchAction(EdgeLabel) bool { return true }
+
+// ActNot matches the edges Inner does not.
+type ActNot struct {
+	Inner ActionFormula
+}
+
+func NotAct(inner ActionFormula) ActionFormula { return ActNot{Inner: inner} }
+
+func (a ActNot) MatchAction(l EdgeLabel) bool { return !a.Inner.MatchAction(l) }
+
+// ActOr matches the edges either side does.
+type ActOr struct {
+	Left, Right ActionFormula
+}
+
+func OrAct(l, r ActionFormula) ActionFormula { return ActOr{Left: l, Right: r} }
+
+func (a ActOr) MatchAction(l EdgeLabel) bool {
+	return a.Left.MatchAction(l) || a.Right.MatchAction(l)
+}
+
+// ActAnd matches the edges both sides do.
+type ActAnd struct {
+	Left, Right ActionFormula
+}
+
+func AndAct(l, r ActionFormula) ActionFormula { return ActAnd{Left: l, Right: r} }
+
+func (a ActAnd) MatchAction(l EdgeLabel) bool {
+	return a.Left.MatchAction(l) && a.Right.MatchAction(l)
+}
+
+// ----- <a>φ and [a]φ -----
+
+type DiamondFormula struct {
+	Action ActionFormula
+	Inner  Formula
+}
+
+// Diamond is <a>φ: some edge matching a leads to a state satisfying φ.
+func Diamond(a ActionFormula, inner Formula) Formula {
+	return DiamondFormula{Action: a, Inner: inner}
+}
+
+func (f DiamondFormula) Sat(g *Graph) StateSet {
+	target := f.Inner.Sat(g)
+	res := NewStateSet()
+	for _, s := range g.States() {
+		for e := range g.EdgesFrom(s) {
+			if f.Action.MatchAction(e.EdgeLabel) && target.Contains(e.To) {
+				res.Add(s)
+				break
+			}
+		}
+	}
+	return res
+}
+
+type BoxFormula struct {
+	Action ActionFormula
+	Inner  Formula
+}
+
+// Box is [a]φ: every edge matching a leads to a state satisfying φ. It
+// holds vacuously where no edge matches.
+func Box(a ActionFormula, inner Formula) Formula {
+	return BoxFormula{Action: a, Inner: inner}
+}
+
+func (f BoxFormula) Sat(g *Graph) StateSet {
+	target := f.Inner.Sat(g)
+	res := NewStateSet()
+	for _, s := range g.States() {
+		ok := true
+		for e := range g.EdgesFrom(s) {
+			if f.Action.MatchAction(e.EdgeLabel) && !target.Contains(e.To) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			res.Add(s)
+		}
+	}
+	return res
+}
+
+// ----- fixpoints -----
+
+// VarFormula is an occurrence of a fixpoint variable. It only has a
+// meaning inside a MuFormula or NuFormula binding it; Sat panics on a free
+// variable.
+type VarFormula struct {
+	Name string
+}
+
+// FixVar refers to the variable bound by an enclosing Mu or Nu.
+func FixVar(name string) Formula { return VarFormula{Name: name} }
+
+func (v VarFormula) Sat(g *Graph) StateSet {
+	panic(fmt.Sprintf("kripke: free fixpoint variable %s", v.Name))
+}
+
+type MuFormula struct {
+	Var  string
+	Body Formula
+}
+
+// Mu is μX.φ, the least fixpoint: the smallest set X with X = φ(X).
+func Mu(x string, body Formula) Formula { return MuFormula{Var: x, Body: body} }
+
+func (f MuFormula) Sat(g *Graph) StateSet {
+	return fixpoint(g, f.Var, f.Body, NewStateSet())
+}
+
+type NuFormula struct {
+	Var  string
+	Body Formula
+}
+
+// Nu is νX.φ, the greatest fixpoint: the largest set X with X = φ(X).
+func Nu(x string, body Formula) Formula { return NuFormula{Var: x, Body: body} }
+
+func (f NuFormula) Sat(g *Graph) StateSet {
+	return fixpoint(g, f.Var, f.Body, True().Sat(g))
+}
+
+// fixpoint iterates body from start until it stops changing. When body
+// is monotone the iterates only grow (from ∅) or shrink (from all states),
+// so that takes at most one round per state; running longer means body is
+// not monotone, and Sat panics rather than cycle forever.
+func fixpoint(g *Graph, x string, body Formula, start StateSet) StateSet {
+	cur := start
+	for i := 0; i <= g.nextID; i++ {
+		next := substitute(body, x, setFormula{cur}).Sat(g)
+		if next.Equal(cur) {
+			return cur
+		}
+		cur = next
+	}
+	panic(fmt.Sprintf("kripke: fixpoint in %s does not converge; is the body monotone?", x))
+}
+
+// setFormula holds exactly in a fixed set of states.
+type setFormula struct {
+	set StateSet
+}
+
+func (f setFormula) Sat(g *Graph) StateSet { return f.set.Clone() }
+
+// substitute replaces the free occurrences of variable x in f by val.
+func substitute(f Formula, x string, val Formula) Formula {
+	switch f := f.(type) {
+	case VarFormula:
+		if f.Name == x {
+			return val
+		}
+		return f
+	case MuFormula:
+		if f.Var == x {
+			return f
+		}
+	case NuFormula:
+		if f.Var == x {
+			return f
+		}
+	}
+	return mapChildren(f, func(c Formula) Formula { return substitute(c, x, val) })
+}
+
+// ValidateMu checks that f is closed (every variable is bound by an
+// enclosing Mu or Nu) and that every fixpoint body is monotone: its
+// variable only occurs under an even number of negations. It also checks
+// the past operators in f as WithPast does; in particular a fixpoint
+// variable may not occur inside one.
+func ValidateMu(f Formula) error {
+	if err := validateMu(f, make(map[string]bool), false); err != nil {
+		return err
+	}
+	return validatePast(f)
+}
+
+// validateMu walks f; bound maps each variable in scope to the negation
+// parity at its binder, and neg is the parity at f.
+func validateMu(f Formula, bound map[string]bool, neg bool) error {
+	bind := func(x string, body Formula) error {
+		outer, shadowed := bound[x]
+		bound[x] = neg
+		err := validateMu(body, bound, neg)
+		if shadowed {
+			bound[x] = outer
+		} else {
+			delete(bound, x)
+		}
+		return err
+	}
+	switch f := f.(type) {
+	case VarFormula:
+		parity, ok := bound[f.Name]
+		if !ok {
+			return fmt.Errorf("free variable %s", f.Name)
+		}
+		if parity != neg {
+			return fmt.Errorf("variable %s occurs under an odd number of negations", f.Name)
+		}
+		return nil
+	case MuFormula:
+		return bind(f.Var, f.Body)
+	case NuFormula:
+		return bind(f.Var, f.Body)
+	case NotFormula:
+		return validateMu(f.Inner, bound, !neg)
+	case EFormula:
+		return validatePath(f.Path, bound, neg)
+	case AFormula:
+		// A φ = ¬E ¬φ: the two negations cancel.
+		return validatePath(f.Path, bound, neg)
+	}
+	for _, c := range children(f) {
+		if err := validateMu(c, bound, neg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePath(p PathFormula, bound map[string]bool, neg bool) error {
+	switch p := p.(type) {
+	case PathState:
+		return validateMu(p.State, bound, neg)
+	case PathNot:
+		return validatePath(p.Inner, bound, !neg)
+	case PathAnd:
+		if err := validatePath(p.Left, bound, neg); err != nil {
+			return err
+		}
+		return validatePath(p.Right, bound, neg)
+	case PathOr:
+		if err := validatePath(p.Left, bound, neg); err != nil {
+			return err
+		}
+		return validatePath(p.Right, bound, neg)
+	case PathNext:
+		return validatePath(p.Inner, bound, neg)
+	case PathUntil:
+		if err := validatePath(p.Left, bound, neg); err != nil {
+			return err
+		}
+		return validatePath(p.Right, bound, neg)
+	}
+	return nil
+}
+
+// ---------- ACTL ----------
+//
+// Action-based CTL operators, defined in the μ-calculus. The variable they
+// bind is not a valid identifier in the parser, so it cannot capture a
+// variable of the caller's formula.
+
+const actlVar = "actl'X"
+
+// EUAct is E[φ {a} U ψ]: some path reaches ψ taking only a-edges through
+// φ states on the way.
+//
+//	μX. ψ ∨ (φ ∧ <a>X)
+func EUAct(phi Formula, a ActionFormula, psi Formula) Formula {
+	return Mu(actlVar, Or(psi, And(phi, Diamond(a, FixVar(actlVar)))))
+}
+
+// EFAct holds where some path eventually takes an a-edge.
+//
+//	μX. <a>true ∨ <true>X
+func EFAct(a ActionFormula) Formula {
+	return Mu(actlVar, Or(Diamond(a, True()), Diamond(AnyAction(), FixVar(actlVar))))
+}
+
+// AFAct holds where every path eventually takes an a-edge; a path that
+// deadlocks first does not count.
+//
+//	μX. <true>true ∧ [¬a]X
+func AFAct(a ActionFormula) Formula {
+	return Mu(actlVar, And(Diamond(AnyAction(), True()), Box(NotAct(a), FixVar(actlVar))))
+}
+
+// AGAct holds where no reachable edge matches a.
+//
+//	νX. [a]false ∧ [true]X
+func AGAct(a ActionFormula) Formula {
+	return Nu(actlVar, And(Box(a, False()), Box(AnyAction(), FixVar(actlVar))))
+}
+
+// AfterEvery holds where φ holds after every reachable a-edge, e.g.
+// "after every send_order, deliver or cancel eventually happens":
+//
+//	AfterEvery(Act("send_order"), AFAct(OrAct(Act("deliver"), Act("cancel"))))
+//
+// is νX. [a]φ ∧ [true]X.
+func AfterEvery(a ActionFormula, phi Formula) Formula {
+	return Nu(actlVar, And(Box(a, phi), Box(AnyAction(), FixVar(actlVar))))
+}
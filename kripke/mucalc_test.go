@@ -0,0 +1,164 @@
+package kripke
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// ctlToMu rewrites the CTL operators of f as fixpoints over any action.
+func ctlToMu(f Formula, fresh *int) Formula {
+	rec := func(f Formula) Formula { return ctlToMu(f, fresh) }
+	x := func() string { *fresh++; return fmt.Sprintf("Z%d", *fresh) }
+	any := AnyAction()
+	switch f := f.(type) {
+	case NotFormula:
+		return Not(rec(f.Inner))
+	case AndFormula:
+		return And(rec(f.Left), rec(f.Right))
+	case OrFormula:
+		return Or(rec(f.Left), rec(f.Right))
+	case EXFormula:
+		return Diamond(any, rec(f.Inner))
+	case AXFormula:
+		return Box(any, rec(f.Inner))
+	case EUFormula:
+		z := x()
+		return Mu(z, Or(rec(f.Psi), And(rec(f.Phi), Diamond(any, FixVar(z)))))
+	case EFFormula:
+		z := x()
+		return Mu(z, Or(rec(f.Inner), Diamond(any, FixVar(z))))
+	case AFFormula:
+		z := x()
+		return Mu(z, Or(rec(f.Inner), Box(any, FixVar(z))))
+	case EGFormula:
+		z := x()
+		return Nu(z, And(rec(f.Inner), Diamond(any, FixVar(z))))
+	case AGFormula:
+		z := x()
+		return Nu(z, And(rec(f.Inner), Box(any, FixVar(z))))
+	}
+	return f
+}
+
+func TestMuMatchesCTL(t *testing.T) {
+	rng := rand.New(rand.NewSource(40))
+	for i := 0; i < 300; i++ {
+		g := randomGraph(rng, 1+rng.Intn(8), rng.Intn(16))
+		f := randomFormula(rng, 3, true)
+		fresh := 0
+		m := ctlToMu(f, &fresh)
+		if err := ValidateMu(m); err != nil {
+			t.Fatalf("%#v: %v", m, err)
+		}
+		if want, got := f.Sat(g), m.Sat(g); !want.Equal(got) {
+			t.Fatalf("%#v: CTL %v, μ-calculus %v", f, want, got)
+		}
+	}
+}
+
+// orderProcess sends an order that is then delivered, cancelled, or
+// (from Stuck) never resolved. Each state is labelled with its name.
+func orderProcess(stuck bool) *Graph {
+	g := NewGraph()
+	for _, name := range []string{"Idle", "Sent", "Packed", "Stuck"} {
+		if stuck || name != "Stuck" {
+			g.AddState(name, map[string]bool{name: true})
+		}
+	}
+	g.AddLabeledEdge("Idle", "Sent", EdgeLabel{Action: "send_order"})
+	g.AddLabeledEdge("Sent", "Packed", EdgeLabel{Action: "pack"})
+	g.AddLabeledEdge("Packed", "Idle", EdgeLabel{Action: "deliver"})
+	g.AddLabeledEdge("Sent", "Idle", EdgeLabel{Action: "cancel"})
+	if stuck {
+		g.AddLabeledEdge("Packed", "Stuck", EdgeLabel{Action: "lose"})
+		g.AddLabeledEdge("Stuck", "Stuck", EdgeLabel{Action: "retry"})
+	}
+	g.SetInitial("Idle")
+	return g
+}
+
+func TestActionProperties(t *testing.T) {
+	resolved := OrAct(Act("deliver"), Act("cancel"))
+	built := AfterEvery(Act("send_order"), AFAct(resolved))
+	parsed := MustParseMu("nu X. [send_order](mu Y. <true>true & [!(deliver | cancel)]Y) & [true]X")
+	for _, c := range []struct {
+		stuck bool
+		want  bool
+	}{{false, true}, {true, false}} {
+		g := orderProcess(c.stuck)
+		init := g.InitialStates()[0]
+		for _, f := range []Formula{built, parsed} {
+			if got := f.Sat(g).Contains(init); got != c.want {
+				t.Fatalf("stuck=%v: got %v, want %v", c.stuck, got, c.want)
+			}
+		}
+	}
+
+	g := orderProcess(true)
+	holds := func(f Formula) bool { return f.Sat(g).Contains(g.InitialStates()[0]) }
+	if !holds(EFAct(Act("lose"))) || holds(AFAct(Act("lose"))) {
+		t.Fatalf("lose is possible but not inevitable")
+	}
+	if !holds(AGAct(Act("refund"))) || holds(AGAct(Act("cancel"))) {
+		t.Fatalf("AGAct: refund never happens, cancel can")
+	}
+	if !holds(EUAct(True(), NotAct(Act("cancel")), Atom("Stuck"))) {
+		t.Fatalf("Stuck is reachable without cancelling")
+	}
+	if holds(EUAct(True(), Act("send_order"), Atom("Stuck"))) {
+		t.Fatalf("Stuck is not reachable by send_order edges alone")
+	}
+}
+
+func TestParseMuSyntax(t *testing.T) {
+	g := orderProcess(true)
+	init := g.InitialStates()[0]
+	for src, want := range map[string]bool{
+		"mu X. Stuck | <true>X":                  true,
+		"mu X.Stuck | <true>X":                   true,
+		"μX.Stuck ∨ <true>X":                     true,
+		"mu X . Stuck | <true>X":                 true,
+		"nu X. <true>X":                          true,
+		"nu X. !Stuck & [true]X":                 false,
+		"<send_order><\"pack\">Packed":           true,
+		"<send_order>[pack | cancel]Idle":        false,
+		"[!send_order]false":                     true,
+		"<send_order & !cancel>true":             true,
+		"AG <true>true":                          true,
+		"EF nu X. Stuck & <retry>X":              true,
+		"EF mu X. Stuck & <retry>X":              false,
+		"E[!Stuck U mu X. <lose>true | <pack>X]": true,
+	} {
+		f, err := ParseMu(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if got := f.Sat(g).Contains(init); got != want {
+			t.Fatalf("%s: got %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestParseMuErrors(t *testing.T) {
+	for _, src := range []string{
+		"mu X. !X",
+		"nu X. X -> Idle",
+		"mu . p",
+		"mu X p",
+		"<send p",
+		"[] p",
+		"<!>p",
+	} {
+		if _, err := ParseMu(src); err == nil || !strings.HasPrefix(err.Error(), "ParseMu:") {
+			t.Fatalf("%q: expected a ParseMu error, got %v", src, err)
+		}
+	}
+	if err := ValidateMu(And(Atom("p"), FixVar("X"))); err == nil {
+		t.Fatalf("free variable not reported")
+	}
+	if _, err := ParseCTL("<a>p"); err == nil {
+		t.Fatalf("ParseCTL accepted a modality")
+	}
+}
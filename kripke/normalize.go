@@ -0,0 +1,302 @@
+package kripke
+
+import "fmt"
+
+// ---------- Normalization ----------
+
+// Normalize rewrites f into existential normal form: the CTL operators
+// become combinations of true, !, &, EX, EU and EG, using
+//
+//	φ | ψ = !(!φ & !ψ)   AX φ = !EX !φ   EF φ = E[true U φ]
+//	AF φ = !EG !φ        AG φ = !E[true U !φ]
+//
+// Double negations cancel and constants fold (φ & false = false,
+// E[φ U false] = false, EX false = false, ...), as do φ & φ and φ & !φ.
+// Other operators (μ-calculus, CTL*, past) are kept, with their
+// subformulas normalized. The result holds in exactly the same states as
+// f on every graph; fewer distinct operators means more subformulas are
+// shared when evaluated with a Memo.
+func Normalize(f Formula) Formula {
+	switch f := f.(type) {
+	case TrueFormula, AtomFormula, CompareFormula:
+		return f
+	case NotFormula:
+		return normNot(Normalize(f.Inner))
+	case AndFormula:
+		return normAnd(Normalize(f.Left), Normalize(f.Right))
+	case OrFormula:
+		return normNot(normAnd(normNot(Normalize(f.Left)), normNot(Normalize(f.Right))))
+	case EXFormula:
+		return normEX(Normalize(f.Inner))
+	case AXFormula:
+		return normNot(normEX(normNot(Normalize(f.Inner))))
+	case EFFormula:
+		return normEU(True(), Normalize(f.Inner))
+	case AFFormula:
+		return normNot(normEG(normNot(Normalize(f.Inner))))
+	case EGFormula:
+		return normEG(Normalize(f.Inner))
+	case AGFormula:
+		return normNot(normEU(True(), normNot(Normalize(f.Inner))))
+	case EUFormula:
+		return normEU(Normalize(f.Phi), Normalize(f.Psi))
+	}
+	return mapChildren(f, Normalize)
+}
+
+func isTrue(f Formula) bool {
+	_, ok := f.(TrueFormula)
+	return ok
+}
+
+func isFalse(f Formula) bool {
+	n, ok := f.(NotFormula)
+	return ok && isTrue(n.Inner)
+}
+
+func normNot(f Formula) Formula {
+	if n, ok := f.(NotFormula); ok {
+		return n.Inner
+	}
+	return Not(f)
+}
+
+func normAnd(l, r Formula) Formula {
+	switch {
+	case isTrue(l):
+		return r
+	case isTrue(r):
+		return l
+	case isFalse(l) || isFalse(r):
+		return False()
+	}
+	lk, rk := formulaKey(l), formulaKey(r)
+	switch {
+	case lk == rk:
+		return l
+	case formulaKey(normNot(l)) == rk:
+		return False()
+	}
+	return And(l, r)
+}
+
+func normEX(f Formula) Formula {
+	if isFalse(f) {
+		return False()
+	}
+	return EX(f)
+}
+
+func normEG(f Formula) Formula {
+	if isFalse(f) {
+		return False()
+	}
+	return EG(f)
+}
+
+func normEU(phi, psi Formula) Formula {
+	switch {
+	case isFalse(psi) || isTrue(psi):
+		return psi
+	case isFalse(phi):
+		return psi
+	}
+	return EU(phi, psi)
+}
+
+// formulaKey identifies a formula by its structure.
+func formulaKey(f Formula) string { return fmt.Sprintf("%#v", f) }
+
+// ---------- Memo ----------
+
+// Memo evaluates formulas on one graph, remembering the states satisfying
+// each distinct subformula so that checking many requirements evaluates
+// every shared subformula once. Formulas are normalized first, so AF φ in
+// one requirement and AG φ in another share their EG or EU evaluation
+// with whatever else uses !φ.
+//
+// The graph must not change while the memo is in use.
+type Memo struct {
+	g    *Graph
+	sets map[string]StateSet
+	pred map[StateID][]StateID
+
+	hits, misses int
+}
+
+// NewMemo returns an empty memo table for g.
+func NewMemo(g *Graph) *Memo {
+	return &Memo{g: g, sets: make(map[string]StateSet)}
+}
+
+// Sat is f.Sat of the memo's graph, computed from the table where
+// possible. The returned set belongs to the caller.
+func (m *Memo) Sat(f Formula) StateSet {
+	set, _ := m.eval(Normalize(f))
+	return set.Clone()
+}
+
+// Stats reports how many subformula evaluations were answered from the
+// table and how many were computed.
+func (m *Memo) Stats() (hits, misses int) { return m.hits, m.misses }
+
+// eval returns the states satisfying the normalized formula f and its
+// key. Keys are built from the children's keys, so each node is printed
+// once.
+func (m *Memo) eval(f Formula) (StateSet, string) {
+	var key string
+	var compute func() StateSet
+	g := m.g
+	switch f := f.(type) {
+	case NotFormula:
+		inner, k := m.eval(f.Inner)
+		key = "!" + k
+		compute = func() StateSet {
+			res := NewStateSet()
+			for _, s := range g.States() {
+				if !inner.Contains(s) {
+					res.Add(s)
+				}
+			}
+			return res
+		}
+	case AndFormula:
+		l, lk := m.eval(f.Left)
+		r, rk := m.eval(f.Right)
+		key = "&(" + lk + "," + rk + ")"
+		compute = func() StateSet {
+			res := NewStateSet()
+			for s := range l {
+				if r.Contains(s) {
+					res.Add(s)
+				}
+			}
+			return res
+		}
+	case EXFormula:
+		inner, k := m.eval(f.Inner)
+		key = "EX(" + k + ")"
+		compute = func() StateSet { return m.pre(inner) }
+	case EUFormula:
+		phi, pk := m.eval(f.Phi)
+		psi, qk := m.eval(f.Psi)
+		key = "EU(" + pk + "," + qk + ")"
+		compute = func() StateSet { return m.eu(phi, psi) }
+	case EGFormula:
+		inner, k := m.eval(f.Inner)
+		key = "EG(" + k + ")"
+		compute = func() StateSet { return m.eg(inner) }
+	case DiamondFormula, BoxFormula:
+		// Evaluate the operand through the table and the modality itself
+		// as usual.
+		var keys []string
+		g2 := mapChildren(f, func(c Formula) Formula {
+			set, k := m.eval(c)
+			keys = append(keys, k)
+			return setFormula{set}
+		})
+		key = fmt.Sprintf("%T%#v(%v)", f, actionOf(f), keys)
+		compute = func() StateSet { return g2.Sat(g) }
+	default:
+		// Leaves, and operators whose subformulas cannot be evaluated on
+		// their own (fixpoint bodies, path formulas, past operators).
+		key = formulaKey(f)
+		compute = func() StateSet { return f.Sat(g) }
+	}
+	if set, ok := m.sets[key]; ok {
+		m.hits++
+		return set, key
+	}
+	m.misses++
+	set := compute()
+	m.sets[key] = set
+	return set, key
+}
+
+func actionOf(f Formula) ActionFormula {
+	switch f := f.(type) {
+	case DiamondFormula:
+		return f.Action
+	case BoxFormula:
+		return f.Action
+	}
+	return nil
+}
+
+func (m *Memo) preds(s StateID) []StateID {
+	if m.pred == nil {
+		m.pred = make(map[StateID][]StateID)
+		for _, u := range m.g.States() {
+			for _, t := range m.g.Succ(u) {
+				m.pred[t] = append(m.pred[t], u)
+			}
+		}
+	}
+	return m.pred[s]
+}
+
+// pre is EX: the states with a successor in set.
+func (m *Memo) pre(set StateSet) StateSet {
+	res := NewStateSet()
+	for t := range set {
+		for _, s := range m.preds(t) {
+			res.Add(s)
+		}
+	}
+	return res
+}
+
+// eu is E[φ U ψ] by backward search from psi through phi.
+func (m *Memo) eu(phi, psi StateSet) StateSet {
+	res := psi.Clone()
+	queue := make([]StateID, 0, len(psi))
+	for s := range psi {
+		queue = append(queue, s)
+	}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		for _, s := range m.preds(t) {
+			if !res.Contains(s) && phi.Contains(s) {
+				res.Add(s)
+				queue = append(queue, s)
+			}
+		}
+	}
+	return res
+}
+
+// eg is EG φ: states of phi are removed once none of their successors
+// is left, counting the successors still in the set.
+func (m *Memo) eg(phi StateSet) StateSet {
+	res := phi.Clone()
+	count := make(map[StateID]int, len(phi))
+	var queue []StateID
+	for s := range phi {
+		for _, t := range m.g.Succ(s) {
+			if phi.Contains(t) {
+				count[s]++
+			}
+		}
+		if count[s] == 0 {
+			queue = append(queue, s)
+			delete(res, s)
+		}
+	}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		for _, s := range m.preds(t) {
+			if !res.Contains(s) {
+				continue
+			}
+			// One count per edge, so parallel edges are removed in turn.
+			count[s]--
+			if count[s] == 0 {
+				delete(res, s)
+				queue = append(queue, s)
+			}
+		}
+	}
+	return res
+}
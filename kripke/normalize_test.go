@@ -0,0 +1,100 @@
+package kripke
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// inENF reports whether f only uses the operators Normalize produces.
+func inENF(f Formula) bool {
+	switch f := f.(type) {
+	case TrueFormula, AtomFormula:
+		return true
+	case NotFormula:
+		_, double := f.Inner.(NotFormula)
+		return !double && inENF(f.Inner)
+	case AndFormula:
+		return inENF(f.Left) && inENF(f.Right)
+	case EXFormula:
+		return inENF(f.Inner)
+	case EGFormula:
+		return inENF(f.Inner)
+	case EUFormula:
+		return inENF(f.Phi) && inENF(f.Psi)
+	}
+	return false
+}
+
+func TestNormalizePreservesMeaning(t *testing.T) {
+	rng := rand.New(rand.NewSource(44))
+	for i := 0; i < 100; i++ {
+		g := randomGraph(rng, 1+rng.Intn(8), rng.Intn(16))
+		memo := NewMemo(g)
+		for j := 0; j < 10; j++ {
+			f := randomFormula(rng, 4, true)
+			n := Normalize(f)
+			if !inENF(n) {
+				t.Fatalf("%#v normalizes to %#v", f, n)
+			}
+			want := f.Sat(g)
+			if got := n.Sat(g); !got.Equal(want) {
+				t.Fatalf("%#v: normalized %v, want %v", f, got, want)
+			}
+			if got := memo.Sat(f); !got.Equal(want) {
+				t.Fatalf("%#v: memo %v, want %v", f, got, want)
+			}
+			fresh := 0
+			mu := ctlToMu(f, &fresh)
+			if got := memo.Sat(Or(mu, Atom("q"))); !got.Equal(Or(f, Atom("q")).Sat(g)) {
+				t.Fatalf("%#v: memo of the μ-calculus form disagrees", f)
+			}
+		}
+	}
+}
+
+func TestNormalizeFolds(t *testing.T) {
+	p, q := Atom("p"), Atom("q")
+	for _, c := range []struct {
+		in, want Formula
+	}{
+		{And(True(), p), p},
+		{Not(Not(p)), p},
+		{And(p, Not(p)), False()},
+		{Or(p, p), p},
+		{Or(q, Not(q)), True()},
+		{EU(p, False()), False()},
+		{EU(False(), q), q},
+		{EF(True()), True()},
+		{AX(True()), True()},
+		{AX(p), Not(EX(Not(p)))},
+		{AG(Implies(p, False())), Not(EU(True(), p))},
+		{AF(q), Not(EG(Not(q)))},
+		{EX(And(p, False())), False()},
+	} {
+		if got := Normalize(c.in); formulaKey(got) != formulaKey(c.want) && !(c.want == False() && isFalse(got)) {
+			t.Fatalf("%#v: got %#v, want %#v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestMemoSharesSubformulas(t *testing.T) {
+	g := orderProcess(true)
+	memo := NewMemo(g)
+	memo.Sat(MustParseCTL("AG(Sent -> AF Idle)"))
+	_, misses := memo.Stats()
+	// AF Idle = !EG !Idle; EG !Idle was evaluated as part of the first
+	// formula, so only the outer negation is new.
+	if got, want := memo.Sat(MustParseCTL("AF Idle")), AF(Atom("Idle")).Sat(g); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	hits, again := memo.Stats()
+	if again != misses+1 || hits < 2 {
+		t.Fatalf("AF Idle was recomputed: %d hits, %d then %d misses", hits, misses, again)
+	}
+	// Sat hands out copies.
+	set := memo.Sat(Atom("Idle"))
+	set.Add(3)
+	if memo.Sat(Atom("Idle")).Contains(3) {
+		t.Fatalf("memo table was modified through a returned set")
+	}
+}
@@ -0,0 +1,856 @@
+package kripke
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseCTL parses the textual CTL syntax used in CTLSpec.Formula and
+// Requirement.FormulaString.
+//
+//	φ ::= true | false | p | e relop e
+//	    | !φ | φ & φ | φ | φ | φ -> φ | (φ)
+//	    | EX φ | AX φ | EF φ | AF φ | EG φ | AG φ
+//	    | E[φ U φ] | A[φ U φ] | EU(φ, φ) | AU(φ, φ)
+//	    | E(π) | A(π) | Y φ | O φ | H φ | S(φ, φ)
+//	π ::= φ | !π | π & π | π | π | π -> π | (π)
+//	    | X π | F π | G π | π U π | π R π
+//	e ::= n | "s" | x | len(x) | e + e | e - e | e * e | e / e | e % e | -e | (e)
+//
+// relop is one of == != < <= > >=. Unicode ¬ ∧ ∨ → are accepted for
+// ! & | ->, as are && || and =. Identifiers may contain dots, e.g.
+// "consumer.in.full". A bare identifier is an atomic proposition (label or
+// bool variable); inside a comparison it names a state variable.
+//
+// E(π) and A(π) quantify over CTL* path formulas (see E and A); runs of
+// path operators may be written together, as in "E(GF p & FG q)". U and R
+// are right-associative and bind tighter than &. Y, O, H and S are the
+// past-time operators, and a formula using them is wrapped in WithPast.
+// Y, O and H are operators only when a formula follows, so they still work
+// as proposition names.
+func ParseCTL(src string) (Formula, error) {
+	return parse("ParseCTL", src, false)
+}
+
+// ParseMu parses the modal μ-calculus: the CTL syntax of ParseCTL plus
+//
+//	φ ::= ... | <α>φ | [α]φ | mu X. φ | nu X. φ | X
+//	α ::= a | "a" | true | !α | α & α | α | α | (α)
+//
+// μ and ν are accepted for mu and nu. A fixpoint extends as far right as
+// possible. Inside its body X refers to the fixpoint variable, shadowing
+// any proposition of the same name; variable names may not contain dots.
+// In an action formula a bare or quoted name matches edges with that
+// action and true matches every edge. The result is checked with
+// ValidateMu.
+//
+//	nu X. [send_order](mu Y. <true>true & [!(deliver | cancel)]Y) & [true]X
+func ParseMu(src string) (Formula, error) {
+	f, err := parse("ParseMu", src, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateMu(f); err != nil {
+		return nil, fmt.Errorf("ParseMu: %w", err)
+	}
+	return f, nil
+}
+
+// MustParseMu is ParseMu for formulas known to be valid; it panics on error.
+func MustParseMu(src string) Formula {
+	f, err := ParseMu(src)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func parse(fn, src string, mu bool) (Formula, error) {
+	toks, err := lex(fn, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, fn: fn}
+	if mu {
+		p.bound = make(map[string]int)
+	}
+	f, err := p.formula()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	if err := validatePast(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return WithPast(f)
+}
+
+// MustParseCTL is ParseCTL for formulas known to be valid; it panics on error.
+func MustParseCTL(src string) Formula {
+	f, err := ParseCTL(src)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// ParseExpr parses a variable expression in the syntax of the e
+// production of ParseCTL, such as "next + 1" or "len(C.inbox) * 2".
+func ParseExpr(src string) (Expr, error) {
+	toks, err := lex("ParseExpr", src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, fn: "ParseExpr"}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return e, nil
+}
+
+// AU is A[φ U ψ]: on every path ψ eventually holds and φ holds until then.
+//
+//	A[φ U ψ] = ¬(E[¬ψ U (¬φ ∧ ¬ψ)] ∨ EG ¬ψ)
+func AU(phi, psi Formula) Formula {
+	return Not(Or(EU(Not(psi), And(Not(phi), Not(psi))), EG(Not(psi))))
+}
+
+// ---------- lexer ----------
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokInt
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+// lexOps lists operators longest first so "<=" wins over "<".
+var lexOps = []string{
+	"->", "&&", "||", "==", "!=", "<=", ">=",
+	"→", "∧", "∨", "¬", "≤", "≥", "≠",
+	"!", "&", "|", "<", ">", "=", "+", "-", "*", "/", "%", "(", ")", "[", "]", ",", ".",
+}
+
+var opAliases = map[string]string{
+	"→": "->", "∧": "&", "&&": "&", "∨": "|", "||": "|", "¬": "!",
+	"≤": "<=", "≥": ">=", "≠": "!=", "=": "==",
+}
+
+// lex splits src into tokens; fn names the caller in errors.
+func lex(fn, src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		r := rune(src[i])
+		if r >= 0x80 {
+			r = []rune(src[i:])[0]
+		}
+		switch {
+		case unicode.IsSpace(r):
+			i += len(string(r))
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(src) {
+				c := rune(src[j])
+				if c >= 0x80 {
+					c = []rune(src[j:])[0]
+				}
+				if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+					break
+				}
+				j += len(string(c))
+			}
+			toks = append(toks, token{tokIdent, src[i:j], i})
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+				j++
+			}
+			toks = append(toks, token{tokInt, src[i:j], i})
+			i = j
+		case r == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("%s: unterminated string at %d", fn, i)
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: bad string at %d: %v", fn, i, err)
+			}
+			toks = append(toks, token{tokString, s, i})
+			i = j + 1
+		default:
+			matched := false
+			for _, op := range lexOps {
+				if strings.HasPrefix(src[i:], op) {
+					text := op
+					if a, ok := opAliases[op]; ok {
+						text = a
+					}
+					toks = append(toks, token{tokOp, text, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("%s: unexpected character %q at %d", fn, r, i)
+			}
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src)}), nil
+}
+
+// ---------- parser ----------
+
+type parser struct {
+	toks []token
+	pos  int
+	fn   string
+
+	// bound counts the enclosing binders of each fixpoint variable; it is
+	// nil when parsing plain CTL.
+	bound map[string]int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(text string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	t := p.next()
+	if t.kind != tokOp || t.text != text {
+		return p.errorf(t, "expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("%s: at %d: %s", p.fn, t.pos, fmt.Sprintf(format, args...))
+}
+
+var unaryTemporal = map[string]func(Formula) Formula{
+	"EX": EX, "AX": AX, "EF": EF, "AF": AF, "EG": EG, "AG": AG,
+}
+
+var unaryPast = map[string]func(Formula) Formula{
+	"Y": Yesterday, "O": Once, "H": Historically,
+}
+
+var unaryPath = map[rune]func(PathFormula) PathFormula{
+	'X': Next, 'F': Eventually, 'G': Globally,
+}
+
+// followedBy reports whether the token after the next one is the operator
+// text.
+func (p *parser) followedBy(text string) bool {
+	t := p.toks[p.pos+1]
+	return t.kind == tokOp && t.text == text
+}
+
+// startsFormula reports whether the token after the next one can start a
+// formula, which tells an operator like Y apart from a proposition named Y.
+func (p *parser) startsFormula() bool {
+	t := p.toks[p.pos+1]
+	switch t.kind {
+	case tokIdent, tokInt, tokString:
+		return true
+	case tokOp:
+		switch t.text {
+		case "(", "!", "-":
+			return true
+		case "<", "[":
+			return p.bound != nil
+		}
+	}
+	return false
+}
+
+// formula := or ('->' formula)?
+func (p *parser) formula() (Formula, error) {
+	l, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("->") {
+		p.next()
+		r, err := p.formula()
+		if err != nil {
+			return nil, err
+		}
+		return Implies(l, r), nil
+	}
+	return l, nil
+}
+
+func (p *parser) or() (Formula, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("|") {
+		p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = Or(l, r)
+	}
+	return l, nil
+}
+
+func (p *parser) and() (Formula, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&") {
+		p.next()
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = And(l, r)
+	}
+	return l, nil
+}
+
+func (p *parser) unary() (Formula, error) {
+	t := p.peek()
+	if p.isOp("!") {
+		p.next()
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(inner), nil
+	}
+	if p.bound != nil {
+		if f, ok, err := p.modal(); ok {
+			return f, err
+		}
+	}
+	if t.kind == tokIdent {
+		if op, ok := unaryTemporal[t.text]; ok {
+			p.next()
+			inner, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return op(inner), nil
+		}
+		if (t.text == "E" || t.text == "A") && p.toks[p.pos+1].kind == tokOp && p.toks[p.pos+1].text == "[" {
+			return p.until()
+		}
+		if (t.text == "EU" || t.text == "AU" || t.text == "S") && p.followedBy("(") {
+			return p.untilCall()
+		}
+		if (t.text == "E" || t.text == "A") && p.followedBy("(") {
+			return p.quantified()
+		}
+		if op, ok := unaryPast[t.text]; ok && p.bound[t.text] == 0 && p.startsFormula() {
+			p.next()
+			inner, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return op(inner), nil
+		}
+	}
+	return p.primary()
+}
+
+// until := ('E' | 'A') '[' formula 'U' formula ']'
+func (p *parser) until() (Formula, error) {
+	q := p.next().text
+	p.next() // '['
+	phi, err := p.formula()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.kind != tokIdent || t.text != "U" {
+		return nil, p.errorf(t, "expected U, got %q", t.text)
+	}
+	psi, err := p.formula()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	if q == "E" {
+		return EU(phi, psi), nil
+	}
+	return AU(phi, psi), nil
+}
+
+// untilCall := ('EU' | 'AU' | 'S') '(' formula ',' formula ')'
+func (p *parser) untilCall() (Formula, error) {
+	q := p.next().text
+	p.next() // '('
+	phi, err := p.formula()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(","); err != nil {
+		return nil, err
+	}
+	psi, err := p.formula()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	switch q {
+	case "EU":
+		return EU(phi, psi), nil
+	case "S":
+		return Since(phi, psi), nil
+	}
+	return AU(phi, psi), nil
+}
+
+// quantified := ('E' | 'A') '(' path ')'
+func (p *parser) quantified() (Formula, error) {
+	q := p.next().text
+	p.next() // '('
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if q == "E" {
+		return E(path), nil
+	}
+	return A(path), nil
+}
+
+// path := pathOr ('->' path)?
+func (p *parser) path() (PathFormula, error) {
+	l, err := p.pathOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("->") {
+		p.next()
+		r, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return ImpliesPath(l, r), nil
+	}
+	return l, nil
+}
+
+func (p *parser) pathOr() (PathFormula, error) {
+	l, err := p.pathAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("|") {
+		p.next()
+		r, err := p.pathAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = OrPath(l, r)
+	}
+	return l, nil
+}
+
+func (p *parser) pathAnd() (PathFormula, error) {
+	l, err := p.pathUntil()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&") {
+		p.next()
+		r, err := p.pathUntil()
+		if err != nil {
+			return nil, err
+		}
+		l = AndPath(l, r)
+	}
+	return l, nil
+}
+
+// pathUntil := pathUnary (('U' | 'R') pathUntil)?
+func (p *parser) pathUntil() (PathFormula, error) {
+	l, err := p.pathUnary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokIdent && (t.text == "U" || t.text == "R") {
+		p.next()
+		r, err := p.pathUntil()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "U" {
+			return Until(l, r), nil
+		}
+		return Release(l, r), nil
+	}
+	return l, nil
+}
+
+// pathUnary := '!' pathUnary | ('X' | 'F' | 'G')+ pathUnary | '(' path ')' | unary
+func (p *parser) pathUnary() (PathFormula, error) {
+	t := p.peek()
+	switch {
+	case p.isOp("!"):
+		p.next()
+		inner, err := p.pathUnary()
+		if err != nil {
+			return nil, err
+		}
+		return NotPath(inner), nil
+	case p.isOp("("):
+		// A parenthesized comparison is a state formula; anything else in
+		// parentheses is a path formula.
+		start := p.pos
+		if _, err := p.expr(); err == nil && p.peek().kind == tokOp && relOps[p.peek().text] {
+			p.pos = start
+			break
+		}
+		p.pos = start
+		p.next()
+		inner, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case t.kind == tokIdent && isPathOps(t.text) && p.bound[t.text] == 0 && p.startsFormula():
+		p.next()
+		inner, err := p.pathUnary()
+		if err != nil {
+			return nil, err
+		}
+		ops := []rune(t.text)
+		for i := len(ops) - 1; i >= 0; i-- {
+			inner = unaryPath[ops[i]](inner)
+		}
+		return inner, nil
+	}
+	f, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return Now(f), nil
+}
+
+// isPathOps reports whether s is a run of X, F and G.
+func isPathOps(s string) bool {
+	for _, r := range s {
+		if unaryPath[r] == nil {
+			return false
+		}
+	}
+	return s != ""
+}
+
+var relOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// primary := comparison | '(' formula ')' | true | false | ident
+//
+// A comparison is tried first; if no relational operator follows the
+// left-hand expression, the parser backs up and reads a formula instead.
+func (p *parser) primary() (Formula, error) {
+	start := p.pos
+	if l, err := p.expr(); err == nil && p.peek().kind == tokOp && relOps[p.peek().text] {
+		op := p.next().text
+		r, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return Compare(op, l, r), nil
+	}
+	p.pos = start
+
+	t := p.next()
+	switch {
+	case t.kind == tokOp && t.text == "(":
+		f, err := p.formula()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return f, nil
+	case t.kind == tokIdent && t.text == "true":
+		return True(), nil
+	case t.kind == tokIdent && t.text == "false":
+		return False(), nil
+	case t.kind == tokIdent && p.bound[t.text] > 0:
+		return FixVar(t.text), nil
+	case t.kind == tokIdent:
+		return Atom(t.text), nil
+	case t.kind == tokEOF:
+		return nil, p.errorf(t, "unexpected end of formula")
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+// modal parses the μ-calculus forms that start a unary formula:
+//
+//	'<' action '>' unary | '[' action ']' unary | ('mu' | 'nu') X '.' formula
+//
+// ok is false, with nothing consumed, when none of them is next.
+func (p *parser) modal() (f Formula, ok bool, err error) {
+	switch {
+	case p.isOp("<") || p.isOp("["):
+		open := p.next().text
+		a, err := p.actionOr()
+		if err != nil {
+			return nil, true, err
+		}
+		if err := p.expect(map[string]string{"<": ">", "[": "]"}[open]); err != nil {
+			return nil, true, err
+		}
+		inner, err := p.unary()
+		if err != nil {
+			return nil, true, err
+		}
+		if open == "<" {
+			return Diamond(a, inner), true, nil
+		}
+		return Box(a, inner), true, nil
+	}
+
+	// The lexer folds the binder, its variable and the dot into
+	// identifiers in several ways: "mu X.p", "mu X. p", "μX.p".
+	t := p.peek()
+	if t.kind != tokIdent {
+		return nil, false, nil
+	}
+	var kw, rest string
+	switch {
+	case t.text == "mu" || t.text == "nu" || t.text == "μ" || t.text == "ν":
+		kw = t.text
+		p.next()
+		v := p.next()
+		if v.kind != tokIdent {
+			return nil, true, p.errorf(v, "expected a variable after %s, got %q", kw, v.text)
+		}
+		rest = v.text
+	case strings.HasPrefix(t.text, "μ") || strings.HasPrefix(t.text, "ν"):
+		kw, rest = t.text[:len("μ")], t.text[len("μ"):]
+		p.next()
+	default:
+		return nil, false, nil
+	}
+	x, after, dotted := strings.Cut(rest, ".")
+	switch {
+	case !dotted:
+		if err := p.expect("."); err != nil {
+			return nil, true, err
+		}
+	case after != "":
+		// Put the text after the dot back as the next token.
+		p.pos--
+		p.toks[p.pos] = token{tokIdent, after, p.toks[p.pos].pos + len(p.toks[p.pos].text) - len(after)}
+	}
+	if x == "" {
+		return nil, true, p.errorf(t, "missing fixpoint variable")
+	}
+	p.bound[x]++
+	body, err := p.formula()
+	p.bound[x]--
+	if err != nil {
+		return nil, true, err
+	}
+	if kw == "mu" || kw == "μ" {
+		return Mu(x, body), true, nil
+	}
+	return Nu(x, body), true, nil
+}
+
+// actionOr := actionAnd ('|' actionAnd)*
+func (p *parser) actionOr() (ActionFormula, error) {
+	l, err := p.actionAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("|") {
+		p.next()
+		r, err := p.actionAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = OrAct(l, r)
+	}
+	return l, nil
+}
+
+// actionAnd := action ('&' action)*
+func (p *parser) actionAnd() (ActionFormula, error) {
+	l, err := p.action()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&") {
+		p.next()
+		r, err := p.action()
+		if err != nil {
+			return nil, err
+		}
+		l = AndAct(l, r)
+	}
+	return l, nil
+}
+
+// action := '!' action | '(' actionOr ')' | true | name | "name"
+func (p *parser) action() (ActionFormula, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokOp && t.text == "!":
+		inner, err := p.action()
+		if err != nil {
+			return nil, err
+		}
+		return NotAct(inner), nil
+	case t.kind == tokOp && t.text == "(":
+		a, err := p.actionOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return a, nil
+	case t.kind == tokIdent && t.text == "true":
+		return AnyAction(), nil
+	case t.kind == tokIdent || t.kind == tokString:
+		return Act(t.text), nil
+	}
+	return nil, p.errorf(t, "expected an action, got %q", t.text)
+}
+
+// expr := term (('+' | '-') term)*
+func (p *parser) expr() (Expr, error) {
+	l, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.next().text
+		r, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		l = Arith(op, l, r)
+	}
+	return l, nil
+}
+
+// term := factor (('*' | '/' | '%') factor)*
+func (p *parser) term() (Expr, error) {
+	l, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.next().text
+		r, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		l = Arith(op, l, r)
+	}
+	return l, nil
+}
+
+func (p *parser) factor() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokInt:
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, p.errorf(t, "bad integer %q", t.text)
+		}
+		return Const(n), nil
+	case tokString:
+		return Const(t.text), nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return Const(true), nil
+		case "false":
+			return Const(false), nil
+		case "len":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			arg := p.next()
+			if arg.kind != tokIdent {
+				return nil, p.errorf(arg, "len expects a variable name")
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return Len(arg.text), nil
+		}
+		return Var(t.text), nil
+	case tokOp:
+		switch t.text {
+		case "-":
+			inner, err := p.factor()
+			if err != nil {
+				return nil, err
+			}
+			return Sub(Const(0), inner), nil
+		case "(":
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	}
+	return nil, p.errorf(t, "expected expression, got %q", t.text)
+}
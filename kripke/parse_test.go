@@ -0,0 +1,103 @@
+package kripke
+
+import "testing"
+
+func TestParseCTLMatchesConstructors(t *testing.T) {
+	g := OrderGraph()
+	accepted, delivered, cancelled := Atom("accepted"), Atom("delivered"), Atom("cancelled")
+
+	cases := []struct {
+		src  string
+		want Formula
+	}{
+		{"AG(accepted -> AF(delivered | cancelled))",
+			AG(Implies(accepted, AF(Or(delivered, cancelled))))},
+		{"AG ¬(delivered ∧ cancelled)", AG(Not(And(delivered, cancelled)))},
+		{"EF delivered", EF(delivered)},
+		{"E[!delivered U cancelled]", EU(Not(delivered), cancelled)},
+		{"EU(!delivered, cancelled)", EU(Not(delivered), cancelled)},
+		{"A[true U delivered | cancelled]", AF(Or(delivered, cancelled))},
+		{"!accepted & EX accepted || false", Or(And(Not(accepted), EX(accepted)), False())},
+		{"AX AX (delivered → EG delivered)", AX(AX(Implies(delivered, EG(delivered))))},
+	}
+	for _, c := range cases {
+		f, err := ParseCTL(c.src)
+		if err != nil {
+			t.Fatalf("%s: %v", c.src, err)
+		}
+		if got, want := f.Sat(g), c.want.Sat(g); !got.Equal(want) {
+			t.Fatalf("%s: got %v, want %v", c.src, stateNames(g, got), stateNames(g, want))
+		}
+	}
+}
+
+func TestParseCTLErrors(t *testing.T) {
+	for _, src := range []string{"", "AG", "p &", "(p", "E[p U q", "x >", "p ? q", `s == "x`} {
+		if _, err := ParseCTL(src); err == nil {
+			t.Fatalf("expected an error for %q", src)
+		}
+	}
+}
+
+func TestRelationalPropositions(t *testing.T) {
+	g := NewGraph()
+	for i, inv := range []int{3, 1, -1} {
+		id := g.AddState(string(rune('a'+i)), nil)
+		g.SetVars(id, map[string]any{"inventory": int64(inv), "mode": "run", "open": inv > 0})
+	}
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "c")
+	g.AddEdge("c", "c")
+	g.SetInitial("a")
+
+	cases := map[string][]string{
+		"inventory >= 0":                  {"a", "b"},
+		"inventory * 2 - 1 > 1":           {"a"},
+		"inventory % 2 == 1":              {"a", "b"},
+		`mode = "run" & open`:             {"a", "b"},
+		"open == false":                   {"c"},
+		"len(mode) == 3 & -inventory > 0": {"c"},
+		"AG (inventory >= 0)":             {},
+		"EF (inventory < 0)":              {"a", "b", "c"},
+		"missing > 0 | !(missing <= 0)":   {"a", "b", "c"},
+	}
+	for src, want := range cases {
+		f, err := ParseCTL(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		got := stateNames(g, f.Sat(g))
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %v, want %v", src, got, want)
+		}
+		for _, s := range want {
+			if !got[s] {
+				t.Fatalf("%s: got %v, want %v", src, got, want)
+			}
+		}
+	}
+}
+
+func TestExploreChannelVariables(t *testing.T) {
+	w := producerConsumerWorld(4, 3)
+	g, err := Explore(w, WithVariables(ChannelVariables(w)), WithVariables(Variables{
+		"received": func(w *World) any { return w.Procs[1].(*testConsumer).count },
+		"sent":     func(w *World) any { return w.Procs[0].(*testProducer).next - 1 },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	init := g.InitialStates()[0]
+	for src, want := range map[string]bool{
+		"AG (len(C.inbox) <= 3)":                 true,
+		"AG (len(C.inbox) <= 2)":                 false,
+		"AG (sent - received == len(C.inbox))":   true,
+		"AF (received == 4)":                     true,
+		"EF (len(C.inbox) == 3 & received == 0)": true,
+		"EF (len(C.inbox) == 3 & received == 2)": false,
+	} {
+		if got := MustParseCTL(src).Sat(g).Contains(init); got != want {
+			t.Fatalf("%s: got %v, want %v", src, got, want)
+		}
+	}
+}
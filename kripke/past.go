@@ -0,0 +1,379 @@
+package kripke
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ---------- past-time operators ----------
+//
+// Past operators look back along the path that led to a state:
+//
+//	Y φ       φ held in the previous state (false in the first state)
+//	O φ       φ held at some point so far, now included
+//	H φ       φ held at every point so far, now included
+//	φ S ψ     ψ held at some point, and φ at every point after it
+//
+// Whether they hold depends on the history, not just the state, so a
+// formula using them is evaluated on a history-augmented product: each
+// state is paired with the values of the formula's past subformulas on
+// the way in, which the product's edges update. WithPast does this for a
+// whole formula, and the result is read off the pairs where each state is
+// the start of the path; for an initial state that is the usual anchored
+// meaning, so
+//
+//	WithPast(AG(Implies(Atom("reply"), Once(Atom("request")))))
+//
+// holds initially when every reply is preceded by a request. ParseCTL
+// wraps formulas with past operators in WithPast itself. A past operator
+// evaluated on its own, outside WithPast, is evaluated the same way with
+// itself as the whole formula.
+//
+// The arguments of past operators may combine past operators with !, & and
+// |, and use any closed formula without past operators. They may not nest
+// a past operator inside a future one, as in O EF Y p, nor use a fixpoint
+// variable bound outside them, as in mu X. p | Y X; WithPast, ValidateMu
+// and the parsers reject both, and evaluating such a formula panics.
+
+type YFormula struct {
+	Inner Formula
+}
+
+// Yesterday is Y φ.
+func Yesterday(inner Formula) Formula { return YFormula{Inner: inner} }
+
+func (f YFormula) Sat(g *Graph) StateSet { return satPast(f, g) }
+
+type OFormula struct {
+	Inner Formula
+}
+
+// Once is O φ.
+func Once(inner Formula) Formula { return OFormula{Inner: inner} }
+
+func (f OFormula) Sat(g *Graph) StateSet { return satPast(f, g) }
+
+type HFormula struct {
+	Inner Formula
+}
+
+// Historically is H φ.
+func Historically(inner Formula) Formula { return HFormula{Inner: inner} }
+
+func (f HFormula) Sat(g *Graph) StateSet { return satPast(f, g) }
+
+type SFormula struct {
+	Left, Right Formula
+}
+
+// Since is φ S ψ.
+func Since(phi, psi Formula) Formula { return SFormula{Left: phi, Right: psi} }
+
+func (f SFormula) Sat(g *Graph) StateSet { return satPast(f, g) }
+
+func isPast(f Formula) bool {
+	switch f.(type) {
+	case YFormula, OFormula, HFormula, SFormula:
+		return true
+	}
+	return false
+}
+
+// hasPast reports whether f uses a past operator outside a nested
+// WithPast.
+func hasPast(f Formula) bool {
+	if isPast(f) {
+		return true
+	}
+	if _, ok := f.(HistoryFormula); ok {
+		return false
+	}
+	for _, c := range children(f) {
+		if hasPast(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// ----- history product -----
+
+type HistoryFormula struct {
+	Inner Formula
+}
+
+// WithPast evaluates f on the history-augmented product of the graph,
+// anchored at each state as the start of the path. Formulas without past
+// operators are returned unchanged. It fails if f nests a past operator
+// inside a future one within a past operator, uses a fixpoint variable
+// inside a past operator that is bound outside it, or has more than 64
+// distinct past subformulas.
+func WithPast(f Formula) (Formula, error) {
+	if err := validatePast(f); err != nil {
+		return nil, fmt.Errorf("WithPast: %w", err)
+	}
+	if _, ok := f.(HistoryFormula); ok || !hasPast(f) {
+		return f, nil
+	}
+	return HistoryFormula{Inner: f}, nil
+}
+
+// satPast evaluates the past operator f outside WithPast.
+func satPast(f Formula, g *Graph) StateSet {
+	h, err := WithPast(f)
+	if err != nil {
+		panic("kripke: " + err.Error())
+	}
+	return h.Sat(g)
+}
+
+// maxPastOperators bounds the distinct past subformulas of one formula,
+// which are tracked as bits.
+const maxPastOperators = 64
+
+// validatePast checks that the history product can evaluate f; see
+// WithPast.
+func validatePast(f Formula) error {
+	if h, ok := f.(HistoryFormula); ok {
+		f = h.Inner
+	}
+	h := &history{index: make(map[string]int)}
+	h.collect(f)
+	if len(h.ops) > maxPastOperators {
+		return fmt.Errorf("formula has %d past operators; at most %d are supported", len(h.ops), maxPastOperators)
+	}
+	return validatePastWithin(f)
+}
+
+func validatePastWithin(f Formula) error {
+	if h, ok := f.(HistoryFormula); ok {
+		if x, ok := freeVar(h.Inner, make(map[string]int)); ok {
+			return fmt.Errorf("fixpoint variable %s occurs inside a past formula", x)
+		}
+		return validatePast(h.Inner)
+	}
+	check := validatePastWithin
+	if isPast(f) {
+		check = validatePastArg
+	}
+	for _, c := range children(f) {
+		if err := check(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePastArg checks f, the argument of a past operator, which
+// history.value evaluates.
+func validatePastArg(f Formula) error {
+	switch f.(type) {
+	case YFormula, OFormula, HFormula, SFormula:
+		return validatePastWithin(f)
+	case NotFormula, AndFormula, OrFormula:
+		for _, c := range children(f) {
+			if err := validatePastArg(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if hasPast(f) {
+		op := strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", f), "kripke."), "Formula")
+		return fmt.Errorf("past operator nested inside %s within a past operator", op)
+	}
+	if x, ok := freeVar(f, make(map[string]int)); ok {
+		return fmt.Errorf("fixpoint variable %s occurs inside a past operator", x)
+	}
+	return validatePastWithin(f)
+}
+
+// freeVar returns a fixpoint variable that occurs free in f; bound counts
+// the enclosing binders of each variable.
+func freeVar(f Formula, bound map[string]int) (string, bool) {
+	var x string
+	switch f := f.(type) {
+	case VarFormula:
+		return f.Name, bound[f.Name] == 0
+	case MuFormula:
+		x = f.Var
+	case NuFormula:
+		x = f.Var
+	}
+	if x != "" {
+		bound[x]++
+		defer func() { bound[x]-- }()
+	}
+	for _, c := range children(f) {
+		if name, ok := freeVar(c, bound); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (f HistoryFormula) Sat(g *Graph) StateSet {
+	if err := validatePast(f.Inner); err != nil {
+		panic("kripke: " + err.Error())
+	}
+	h := newHistory(g, f.Inner)
+	inner := h.replace(f.Inner)
+	sat := inner.Sat(h.product)
+	res := NewStateSet()
+	for s, start := range h.starts {
+		if sat.Contains(start) {
+			res.Add(s)
+		}
+	}
+	return res
+}
+
+// history is the product of a graph with the values of the past
+// subformulas ops, listed arguments first.
+type history struct {
+	g       *Graph
+	ops     []Formula
+	index   map[string]int
+	sets    map[string]StateSet // formulas without past operators, on g
+	product *Graph
+	bits    []uint64            // product state -> past values
+	starts  map[StateID]StateID // state of g -> product state starting there
+}
+
+func newHistory(g *Graph, f Formula) *history {
+	h := &history{g: g, index: make(map[string]int), sets: make(map[string]StateSet)}
+	h.collect(f)
+	h.build()
+	return h
+}
+
+// collect lists the past subformulas of f after their arguments.
+func (h *history) collect(f Formula) {
+	if _, ok := f.(HistoryFormula); ok {
+		return
+	}
+	for _, c := range children(f) {
+		h.collect(c)
+	}
+	if isPast(f) {
+		key := fmt.Sprintf("%#v", f)
+		if _, ok := h.index[key]; !ok {
+			h.index[key] = len(h.ops)
+			h.ops = append(h.ops, f)
+		}
+	}
+}
+
+// value is the truth of a past operator's argument f in state s with
+// past values bits.
+func (h *history) value(f Formula, s StateID, bits uint64) bool {
+	switch f := f.(type) {
+	case YFormula, OFormula, HFormula, SFormula:
+		return bits>>h.index[fmt.Sprintf("%#v", f)]&1 == 1
+	case NotFormula:
+		return !h.value(f.Inner, s, bits)
+	case AndFormula:
+		return h.value(f.Left, s, bits) && h.value(f.Right, s, bits)
+	case OrFormula:
+		return h.value(f.Left, s, bits) || h.value(f.Right, s, bits)
+	}
+	key := fmt.Sprintf("%#v", f)
+	set, ok := h.sets[key]
+	if !ok {
+		set = f.Sat(h.g)
+		h.sets[key] = set
+	}
+	return set.Contains(s)
+}
+
+// step computes the past values on entering s from state from with past
+// values prev; prev is nil for the first state of a path.
+func (h *history) step(s, from StateID, prev *uint64) uint64 {
+	var bits uint64
+	set := func(i int, v bool) {
+		if v {
+			bits |= 1 << i
+		}
+	}
+	was := func(i int) bool { return prev != nil && *prev>>i&1 == 1 }
+	for i, op := range h.ops {
+		switch op := op.(type) {
+		case YFormula:
+			set(i, prev != nil && h.value(op.Inner, from, *prev))
+		case OFormula:
+			set(i, h.value(op.Inner, s, bits) || was(i))
+		case HFormula:
+			set(i, h.value(op.Inner, s, bits) && (prev == nil || was(i)))
+		case SFormula:
+			set(i, h.value(op.Right, s, bits) || (h.value(op.Left, s, bits) && was(i)))
+		}
+	}
+	return bits
+}
+
+func (h *history) build() {
+	h.product = NewGraph()
+	h.starts = make(map[StateID]StateID)
+	type node struct {
+		s    StateID
+		bits uint64
+	}
+	ids := make(map[node]StateID)
+	var queue []node
+	visit := func(n node) StateID {
+		if id, ok := ids[n]; ok {
+			return id
+		}
+		name := h.g.NameOf(n.s)
+		if len(h.ops) > 0 {
+			name += "@" + strconv.FormatUint(n.bits, 2)
+		}
+		id := h.product.AddState(name, copyMap(h.g.labels[n.s]))
+		if vars := h.g.vars[n.s]; len(vars) > 0 {
+			h.product.SetVars(id, vars)
+		}
+		ids[n] = id
+		h.bits = append(h.bits, n.bits)
+		queue = append(queue, n)
+		return id
+	}
+	for _, s := range sortedStates(h.g) {
+		h.starts[s] = visit(node{s, h.step(s, s, nil)})
+	}
+	for _, s := range h.g.InitialStates() {
+		h.product.SetInitial(h.product.NameOf(h.starts[s]))
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		from := h.product.NameOf(ids[n])
+		for e := range h.g.EdgesFrom(n.s) {
+			to := h.product.NameOf(visit(node{e.To, h.step(e.To, n.s, &n.bits)}))
+			if h.g.prob[n.s] != nil {
+				h.product.addEdgeProbLabel(from, to, e.Prob, e.EdgeLabel)
+			} else {
+				h.product.AddLabeledEdge(from, to, e.EdgeLabel)
+			}
+		}
+	}
+}
+
+// replace substitutes each past subformula of f by the product states
+// where it holds.
+func (h *history) replace(f Formula) Formula {
+	if isPast(f) {
+		i := h.index[fmt.Sprintf("%#v", f)]
+		set := NewStateSet()
+		for _, id := range h.product.States() {
+			if h.bits[id]>>i&1 == 1 {
+				set.Add(id)
+			}
+		}
+		return setFormula{set}
+	}
+	if _, ok := f.(HistoryFormula); ok {
+		return f
+	}
+	return mapChildren(f, h.replace)
+}
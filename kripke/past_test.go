@@ -0,0 +1,140 @@
+package kripke
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Past formulas anchored at the initial state agree with future-time
+// formulas that say the same thing.
+func TestPastMatchesFuture(t *testing.T) {
+	rng := rand.New(rand.NewSource(141))
+	p, q := Atom("p"), Atom("q")
+	cases := []struct {
+		name         string
+		past, future Formula
+	}{
+		{"O", AG(Implies(q, Once(p))), Not(EU(Not(p), And(q, Not(p))))},
+		{"Y", AG(Implies(Yesterday(p), q)), AG(Implies(p, AX(q)))},
+		{"H", EF(And(q, Historically(p))), EU(p, And(q, p))},
+		{"EY", EF(And(q, Yesterday(p))), EF(And(p, EX(q)))},
+		{"S", EF(And(Not(q), Since(p, q))), EF(And(q, EX(And(p, Not(q)))))},
+		{"HY", AG(Historically(Implies(Yesterday(q), p))), AG(Implies(q, AX(p)))},
+	}
+	for i := 0; i < 300; i++ {
+		g := randomGraph(rng, 1+rng.Intn(8), rng.Intn(16))
+		s0 := g.InitialStates()[0]
+		for _, c := range cases {
+			want := c.future.Sat(g).Contains(s0)
+			if got := mustWithPast(t, c.past).Sat(g).Contains(s0); got != want {
+				t.Fatalf("%s: past %v, future %v", c.name, got, want)
+			}
+		}
+	}
+}
+
+func TestPastRequestReply(t *testing.T) {
+	g := NewGraph()
+	g.AddState("idle", nil)
+	g.AddState("req", map[string]bool{"request": true})
+	g.AddState("reply", map[string]bool{"reply": true})
+	g.AddEdge("idle", "req")
+	g.AddEdge("req", "reply")
+	g.AddEdge("reply", "idle")
+	g.SetInitial("idle")
+	if !holdsInitially(t, g, "AG(reply -> O request)") {
+		t.Fatalf("every reply follows a request")
+	}
+	if !holdsInitially(t, g, "AG(reply -> Y request)") {
+		t.Fatalf("every reply directly follows a request")
+	}
+
+	g.AddEdge("idle", "reply")
+	if holdsInitially(t, g, "AG(reply -> O request)") {
+		t.Fatalf("idle -> reply answers no request")
+	}
+	// From req the history starts at req, so its replies are covered.
+	req, _ := g.StateByName("req")
+	if !MustParseCTL("AG(reply -> O request)").Sat(g).Contains(req) {
+		t.Fatalf("paths starting at req always request first")
+	}
+}
+
+func TestParsePast(t *testing.T) {
+	rng := rand.New(rand.NewSource(1441))
+	p, q := Atom("p"), Atom("q")
+	cases := []struct {
+		src  string
+		want Formula
+	}{
+		{"AG(q -> O p)", AG(Implies(q, Once(p)))},
+		{"EF(q & Y Y p)", EF(And(q, Yesterday(Yesterday(p))))},
+		{"EF(!q & S(p, q))", EF(And(Not(q), Since(p, q)))},
+		{"AG H(Y q -> p)", AG(Historically(Implies(Yesterday(q), p)))},
+		{"EF(q & Y)", EF(And(q, Atom("Y")))},
+	}
+	for i := 0; i < 50; i++ {
+		g := randomGraph(rng, 1+rng.Intn(6), rng.Intn(12))
+		for _, c := range cases {
+			f, err := ParseCTL(c.src)
+			if err != nil {
+				t.Fatalf("%s: %v", c.src, err)
+			}
+			if got, want := f.Sat(g), mustWithPast(t, c.want).Sat(g); !got.Equal(want) {
+				t.Fatalf("%s: got %v, want %v", c.src, stateNames(g, got), stateNames(g, want))
+			}
+		}
+	}
+}
+
+// Formulas the history product cannot evaluate are errors wherever they
+// enter, not panics in Sat.
+func TestPastRejected(t *testing.T) {
+	p := Atom("p")
+	many := p
+	for i := 0; i <= maxPastOperators; i++ {
+		many = And(many, Once(Atom(fmt.Sprint("p", i))))
+	}
+	cases := []struct {
+		name string
+		f    Formula
+		want string
+	}{
+		{"O EF Y p", Once(EF(Yesterday(p))), "nested inside EF within a past operator"},
+		{"mu X. p | Y X", Mu("X", Or(p, Yesterday(FixVar("X")))), "fixpoint variable X"},
+		{"nu X. H X", Nu("X", Historically(FixVar("X"))), "fixpoint variable X"},
+		{"many", many, "65 past operators"},
+	}
+	for _, c := range cases {
+		if _, err := WithPast(c.f); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("WithPast(%s): error %v, want %q", c.name, err, c.want)
+		}
+		if err := ValidateMu(c.f); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("ValidateMu(%s): error %v, want %q", c.name, err, c.want)
+		}
+	}
+	for src, parse := range map[string]func(string) (Formula, error){
+		"O EF Y p":      ParseCTL,
+		"mu X. p | Y X": ParseMu,
+		"nu X. H X":     ParseMu,
+	} {
+		if _, err := parse(src); err == nil {
+			t.Errorf("%s parses", src)
+		}
+	}
+	// A fixpoint bound inside the argument is closed there.
+	if _, err := ParseMu("O (mu X. p | <true>X)"); err != nil {
+		t.Errorf("closed fixpoint under O: %v", err)
+	}
+}
+
+func mustWithPast(t *testing.T, f Formula) Formula {
+	t.Helper()
+	f, err := WithPast(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
@@ -0,0 +1,127 @@
+package kripke
+
+import "sort"
+
+// Propositions maps atomic proposition names to predicates over a World.
+// Explore evaluates every proposition on every state it discovers and
+// stores the results as that state's labels, so CTL formulas can refer
+// to them with Atom(name).
+type Propositions map[string]Predicate
+
+// Label evaluates every proposition on w.
+func (ps Propositions) Label(w *World) map[string]bool {
+	out := make(map[string]bool, len(ps))
+	for name, p := range ps {
+		out[name] = p(w)
+	}
+	return out
+}
+
+// Names returns the proposition names in sorted order.
+func (ps Propositions) Names() []string {
+	out := make([]string, 0, len(ps))
+	for name := range ps {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// WithPropositions labels every explored state with the given propositions.
+// Repeated options are merged.
+func WithPropositions(ps Propositions) ExploreOption {
+	return func(opts *exploreOptions) {
+		if opts.props == nil {
+			opts.props = make(Propositions, len(ps))
+		}
+		for name, p := range ps {
+			opts.props[name] = p
+		}
+	}
+}
+
+// Variables maps state variable names to functions reading them from a
+// World. Explore stores the values on every state it discovers, so CTL
+// comparisons such as "inventory >= 0" can refer to them.
+type Variables map[string]func(*World) any
+
+// Eval reads every variable from w.
+func (vs Variables) Eval(w *World) map[string]any {
+	out := make(map[string]any, len(vs))
+	for name, f := range vs {
+		out[name] = f(w)
+	}
+	return out
+}
+
+// WithVariables records the given variables on every explored state.
+// Repeated options are merged.
+func WithVariables(vs Variables) ExploreOption {
+	return func(opts *exploreOptions) {
+		if opts.vars == nil {
+			opts.vars = make(Variables, len(vs))
+		}
+		for name, f := range vs {
+			opts.vars[name] = f
+		}
+	}
+}
+
+// ChannelVariables derives "<actor>.<channel>.len" for every channel in w,
+// which the CTL syntax len(<actor>.<channel>) reads.
+func ChannelVariables(w *World) Variables {
+	vs := make(Variables, len(w.Channels))
+	for _, ch := range w.Channels {
+		a := ch.Address()
+		vs[a.String()+".len"] = func(w *World) any {
+			if ch := w.ChannelByAddress(a); ch != nil {
+				return ch.Len()
+			}
+			return 0
+		}
+	}
+	return vs
+}
+
+// ---------- Common predicates ----------
+
+// ChannelEmpty holds when the channel at a has no queued messages.
+func ChannelEmpty(a Address) Predicate {
+	return func(w *World) bool {
+		ch := w.ChannelByAddress(a)
+		return ch != nil && ch.IsEmpty()
+	}
+}
+
+// ChannelFull holds when the channel at a is at capacity.
+func ChannelFull(a Address) Predicate {
+	return func(w *World) bool {
+		ch := w.ChannelByAddress(a)
+		return ch != nil && ch.IsFull()
+	}
+}
+
+// ChannelLenAtLeast holds when the channel at a holds n or more messages.
+func ChannelLenAtLeast(a Address, n int) Predicate {
+	return func(w *World) bool {
+		ch := w.ChannelByAddress(a)
+		return ch != nil && ch.Len() >= n
+	}
+}
+
+// Deadlocked holds when no process has an enabled step.
+func Deadlocked(w *World) bool {
+	return len(w.EnabledSteps()) == 0
+}
+
+// ChannelPropositions derives "<actor>.<channel>.empty" and
+// "<actor>.<channel>.full" for every channel in w, plus "deadlock".
+func ChannelPropositions(w *World) Propositions {
+	ps := Propositions{"deadlock": Deadlocked}
+	for _, ch := range w.Channels {
+		a := ch.Address()
+		ps[a.String()+".empty"] = ChannelEmpty(a)
+		ps[a.String()+".full"] = ChannelFull(a)
+	}
+	return ps
+}
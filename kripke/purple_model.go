@@ -33,5 +33,13 @@ func BuildPurpleGraph() (*SimpleGraph, NodeID) {
     g.Succ[NodeConsonantSolved] = []NodeID{NodeUniqueKey}
     g.Succ[NodeUniqueKey] = nil // terminal
 
+    // Each node is labelled with its own name, as ToGraph does for an
+    // unlabelled graph, plus the propositions the PURPLE CTL specs use.
+    g.Labels = make(map[NodeID]map[string]bool)
+    for _, n := range g.States {
+        g.Labels[n] = map[string]bool{string(n): true}
+    }
+    g.Labels[NodeUniqueKey]["attackerKnowsKey"] = true
+
     return g, NodeUnknownKey
 }
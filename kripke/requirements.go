@@ -0,0 +1,247 @@
+package kripke
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ---------- Requirement evaluation ----------
+//
+// A requirement holds for a model when every initial state satisfies its
+// formula; states that are not reachable from an initial state do not
+// matter. EvaluateRequirement reports that verdict together with the
+// result in each initial state, the full satisfaction set and, for each
+// failing initial state, the evidence from EvidenceAt.
+
+// Verdict is the outcome of a requirement on a graph.
+type Verdict string
+
+const (
+	VerdictPass Verdict = "pass"
+	VerdictFail Verdict = "fail"
+	// VerdictUnknown is reported when the requirement has no usable
+	// formula or the graph has no initial states.
+	VerdictUnknown Verdict = "unknown"
+)
+
+// InitialResult is a requirement's result in one initial state.
+type InitialResult struct {
+	State string `json:"state"`
+	Holds bool   `json:"holds"`
+	// Counterexample explains a failure. It is nil when the state
+	// satisfies the formula, and when the requirement has only a Formula
+	// function that FormulaString does not reproduce.
+	Counterexample *Counterexample `json:"counterexample,omitempty"`
+}
+
+// Counterexample is the JSON form of the Evidence for a failing initial
+// state, with states given by name.
+type Counterexample struct {
+	Path      []string `json:"path,omitempty"`
+	Loop      int      `json:"loop"`
+	Narrative string   `json:"narrative"`
+}
+
+// RequirementResult is the outcome of one Requirement on a graph.
+type RequirementResult struct {
+	ID          string          `json:"id"`
+	Description string          `json:"description,omitempty"`
+	Formula     string          `json:"formula"`
+	Verdict     Verdict         `json:"verdict"`
+	Initial     []InitialResult `json:"initial"`
+	// Satisfying lists the names of all states satisfying the formula,
+	// sorted by state id.
+	Satisfying []string      `json:"satisfying"`
+	States     int           `json:"states"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Failing returns the initial states where the requirement fails.
+func (r RequirementResult) Failing() []InitialResult {
+	var out []InitialResult
+	for _, ir := range r.Initial {
+		if !ir.Holds {
+			out = append(out, ir)
+		}
+	}
+	return out
+}
+
+// EvaluateRequirement checks req against g. The formula is req.Formula
+// when it is set and otherwise FormulaString parsed with ParseCTL, which
+// is also what counterexamples are built from. When both are set,
+// FormulaString is still parsed and checked, and Error says so if it does
+// not parse or holds in different states than Formula; there are no
+// counterexamples then. Elapsed covers computing the satisfaction set of
+// the checked formula, not the comparison or the counterexamples.
+func (g *Graph) EvaluateRequirement(req Requirement) RequirementResult {
+	return g.evaluate(req, nil)
+}
+
+// evaluate is EvaluateRequirement, evaluating parsed formulas through
+// memo when it is not nil.
+func (g *Graph) evaluate(req Requirement, memo *Memo) RequirementResult {
+	res := RequirementResult{
+		ID:          req.ID,
+		Description: req.Description,
+		Formula:     req.FormulaString,
+		Verdict:     VerdictUnknown,
+		States:      len(g.States()),
+	}
+	satOf := func(f Formula) StateSet {
+		if memo != nil {
+			return memo.Sat(f)
+		}
+		return f.Sat(g)
+	}
+	var parsed Formula
+	sat := req.Formula
+	if sat == nil {
+		var err error
+		if parsed, err = ParseCTL(req.FormulaString); err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		sat = func(*Graph) StateSet { return satOf(parsed) }
+	}
+
+	start := time.Now()
+	set := sat(g)
+	res.Elapsed = time.Since(start)
+
+	for _, s := range sortedStates(g) {
+		if set.Contains(s) {
+			res.Satisfying = append(res.Satisfying, g.NameOf(s))
+		}
+	}
+	// Counterexamples come from the parsed formula. With a Formula
+	// function, FormulaString is used only when it agrees with the formula
+	// that was checked.
+	if req.Formula != nil && req.FormulaString != "" {
+		f, err := ParseCTL(req.FormulaString)
+		switch {
+		case err != nil:
+			res.Error = err.Error()
+		case !satOf(f).Equal(set):
+			res.Error = fmt.Sprintf("FormulaString %q does not match Formula", req.FormulaString)
+		default:
+			parsed = f
+		}
+	}
+	inits := g.InitialStates()
+	if len(inits) == 0 {
+		res.Error = "graph has no initial states"
+		return res
+	}
+	res.Verdict = VerdictPass
+	for _, s := range inits {
+		ir := InitialResult{State: g.NameOf(s), Holds: set.Contains(s)}
+		if !ir.Holds {
+			res.Verdict = VerdictFail
+			if parsed != nil {
+				ev := g.EvidenceAt(parsed, s)
+				ir.Counterexample = &Counterexample{Loop: ev.Loop, Narrative: ev.Narrative}
+				for _, p := range ev.Path {
+					ir.Counterexample.Path = append(ir.Counterexample.Path, g.NameOf(p))
+				}
+			}
+		}
+		res.Initial = append(res.Initial, ir)
+	}
+	return res
+}
+
+// EvaluateRequirements checks each requirement in turn. Requirements
+// given as FormulaString share one Memo, so a subformula common to
+// several of them is evaluated once; each Elapsed then only counts the
+// work its requirement added.
+func (g *Graph) EvaluateRequirements(requirements []Requirement) []RequirementResult {
+	memo := NewMemo(g)
+	out := make([]RequirementResult, len(requirements))
+	for i, req := range requirements {
+		out[i] = g.evaluate(req, memo)
+	}
+	return out
+}
+
+// WriteResultsJSON writes requirement results as indented JSON:
+//
+//	[{"id": "R1", "formula": "AG !Stuck", "verdict": "fail",
+//	  "initial": [{"state": "Idle", "holds": false,
+//	               "counterexample": {"path": ["Idle", ...], "loop": -1, "narrative": "..."}}],
+//	  "satisfying": ["Idle", ...], "states": 4, "elapsed_ns": 12000}, ...]
+func WriteResultsJSON(w io.Writer, results []RequirementResult) error {
+	if results == nil {
+		results = []RequirementResult{}
+	}
+	b, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadResultsJSON reads results written by WriteResultsJSON.
+func ReadResultsJSON(r io.Reader) ([]RequirementResult, error) {
+	var out []RequirementResult
+	if err := json.NewDecoder(r).Decode(&out); err != nil {
+		return nil, fmt.Errorf("ReadResultsJSON: %w", err)
+	}
+	return out, nil
+}
+
+// ----- markdown -----
+
+// resultCell renders the verdict column of GenerateCTLTable, linking a
+// failure to its counterexamples.
+func resultCell(r RequirementResult) string {
+	switch r.Verdict {
+	case VerdictPass:
+		return fmt.Sprintf("✅ PASS (%d/%d initial)", len(r.Initial), len(r.Initial))
+	case VerdictFail:
+		failing := len(r.Failing())
+		return fmt.Sprintf("❌ FAIL (%d/%d initial) [counterexample](#%s)",
+			len(r.Initial)-failing, len(r.Initial), counterexampleAnchor(r.ID))
+	}
+	if r.Error != "" {
+		return "❓ UNKNOWN: " + r.Error
+	}
+	return "❓ UNKNOWN"
+}
+
+func counterexampleAnchor(id string) string {
+	var sb strings.Builder
+	sb.WriteString("cex-")
+	for _, r := range strings.ToLower(id) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
+
+// writeCounterexamples writes a section per failing requirement, with the
+// anchor resultCell links to.
+func writeCounterexamples(sb *strings.Builder, results []RequirementResult) {
+	for _, r := range results {
+		if r.Verdict != VerdictFail {
+			continue
+		}
+		fmt.Fprintf(sb, "\n<a id=\"%s\"></a>\n#### Counterexamples for %s\n\n", counterexampleAnchor(r.ID), r.ID)
+		for _, ir := range r.Failing() {
+			if ir.Counterexample == nil {
+				fmt.Fprintf(sb, "- **%s**: fails (no counterexample for a formula given only as a function)\n", ir.State)
+				continue
+			}
+			fmt.Fprintf(sb, "- **%s**: %s\n", ir.State, ir.Counterexample.Narrative)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package kripke
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateRequirementUsesInitialStates(t *testing.T) {
+	g := orderProcess(true)
+	// Stuck can be reached, but the requirement is about paths from Idle:
+	// it holds even though Stuck itself does not satisfy it.
+	res := g.EvaluateRequirement(Requirement{ID: "R1", FormulaString: "EF Idle | EF Stuck"})
+	if res.Verdict != VerdictPass || len(res.Initial) != 1 || !res.Initial[0].Holds {
+		t.Fatalf("got %+v", res)
+	}
+	res = g.EvaluateRequirement(Requirement{ID: "R2", FormulaString: "EF Idle"})
+	if res.Verdict != VerdictPass || !reflect.DeepEqual(res.Satisfying, []string{"Idle", "Sent", "Packed"}) || res.States != 4 {
+		t.Fatalf("got %+v", res)
+	}
+
+	g.SetInitial("Stuck")
+	res = g.EvaluateRequirement(Requirement{ID: "R2", FormulaString: "EF Idle"})
+	if res.Verdict != VerdictFail {
+		t.Fatalf("Stuck never returns to Idle, got %v", res.Verdict)
+	}
+	failing := res.Failing()
+	if len(failing) != 1 || failing[0].State != "Stuck" || failing[0].Counterexample == nil {
+		t.Fatalf("failing %+v", failing)
+	}
+	if !strings.Contains(failing[0].Counterexample.Narrative, "reachable from Stuck") {
+		t.Fatalf("narrative %q", failing[0].Counterexample.Narrative)
+	}
+}
+
+func TestEvaluateRequirementFormulaFunc(t *testing.T) {
+	g := orderProcess(true)
+	f := AG(Not(Atom("Stuck")))
+	res := g.EvaluateRequirement(Requirement{ID: "R", FormulaString: "AG !Stuck", Formula: f.Sat})
+	if res.Verdict != VerdictFail || res.Initial[0].Counterexample == nil {
+		t.Fatalf("got %+v", res)
+	}
+	if got := res.Initial[0].Counterexample.Path; !reflect.DeepEqual(got, []string{"Idle", "Sent", "Packed", "Stuck"}) {
+		t.Fatalf("path %v", got)
+	}
+	// The function and the string disagree, so there is no evidence to give.
+	res = g.EvaluateRequirement(Requirement{ID: "R", FormulaString: "AG !Packed", Formula: f.Sat})
+	if res.Verdict != VerdictFail || res.Initial[0].Counterexample != nil || !strings.Contains(res.Error, "does not match") {
+		t.Fatalf("got %+v", res)
+	}
+	res = g.EvaluateRequirement(Requirement{ID: "R", FormulaString: "AG !Packed", Formula: True().Sat})
+	if res.Verdict != VerdictPass || !strings.Contains(res.Error, "does not match") {
+		t.Fatalf("got %+v", res)
+	}
+
+	// The string is parsed whether or not the formula fails.
+	res = g.EvaluateRequirement(Requirement{ID: "R", FormulaString: "AG (", Formula: f.Sat})
+	if res.Verdict != VerdictFail || res.Initial[0].Counterexample != nil || res.Error == "" {
+		t.Fatalf("got %+v", res)
+	}
+	res = g.EvaluateRequirement(Requirement{ID: "R", FormulaString: "AG (", Formula: True().Sat})
+	if res.Verdict != VerdictPass || res.Error == "" {
+		t.Fatalf("got %+v", res)
+	}
+	res = g.EvaluateRequirement(Requirement{ID: "R", FormulaString: "true", Formula: True().Sat})
+	if res.Verdict != VerdictPass || res.Error != "" {
+		t.Fatalf("got %+v", res)
+	}
+
+	res = g.EvaluateRequirement(Requirement{ID: "R", FormulaString: "AG ("})
+	if res.Verdict != VerdictUnknown || res.Error == "" {
+		t.Fatalf("got %+v", res)
+	}
+	res = NewGraph().EvaluateRequirement(Requirement{ID: "R", FormulaString: "true"})
+	if res.Verdict != VerdictUnknown || res.Error == "" {
+		t.Fatalf("no initial states: got %+v", res)
+	}
+}
+
+func TestCTLTableAndJSON(t *testing.T) {
+	g := orderProcess(true)
+	reqs := []Requirement{
+		{ID: "R1", Description: "orders are never lost", FormulaString: "AG !Stuck"},
+		{ID: "R2", Description: "an order can be delivered", FormulaString: "EF Packed"},
+	}
+	table := g.GenerateCTLTable(reqs)
+	for _, want := range []string{
+		"❌ FAIL (0/1 initial) [counterexample](#cex-r1)",
+		"✅ PASS (1/1 initial)",
+		`<a id="cex-r1"></a>`,
+		"- **Idle**: The path Idle -send_order-> Sent",
+	} {
+		if !strings.Contains(table, want) {
+			t.Fatalf("table lacks %q:\n%s", want, table)
+		}
+	}
+	if strings.Contains(table, "PARTIAL") {
+		t.Fatalf("table still reports PARTIAL:\n%s", table)
+	}
+
+	results := g.EvaluateRequirements(reqs)
+	var buf bytes.Buffer
+	if err := WriteResultsJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"verdict": "fail"`, `"loop": -1`, `"elapsed_ns":`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("JSON lacks %s:\n%s", want, buf.String())
+		}
+	}
+	back, err := ReadResultsJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, results) {
+		t.Fatalf("round trip:\n%+v\n%+v", back, results)
+	}
+}
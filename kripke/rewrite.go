@@ -0,0 +1,87 @@
+package kripke
+
+// ---------- formula traversal ----------
+
+// mapChildren rebuilds f with fn applied to each of its direct state
+// subformulas; for E and A those are the state formulas at the leaves of
+// the path formula. Formula types it does not know are leaves and are
+// returned unchanged.
+func mapChildren(f Formula, fn func(Formula) Formula) Formula {
+	switch f := f.(type) {
+	case NotFormula:
+		return NotFormula{Inner: fn(f.Inner)}
+	case AndFormula:
+		return AndFormula{Left: fn(f.Left), Right: fn(f.Right)}
+	case OrFormula:
+		return OrFormula{Left: fn(f.Left), Right: fn(f.Right)}
+	case EXFormula:
+		return EXFormula{Inner: fn(f.Inner)}
+	case AXFormula:
+		return AXFormula{Inner: fn(f.Inner)}
+	case EFFormula:
+		return EFFormula{Inner: fn(f.Inner)}
+	case AFFormula:
+		return AFFormula{Inner: fn(f.Inner)}
+	case EGFormula:
+		return EGFormula{Inner: fn(f.Inner)}
+	case AGFormula:
+		return AGFormula{Inner: fn(f.Inner)}
+	case EUFormula:
+		return EUFormula{Phi: fn(f.Phi), Psi: fn(f.Psi)}
+	case DiamondFormula:
+		return DiamondFormula{Action: f.Action, Inner: fn(f.Inner)}
+	case BoxFormula:
+		return BoxFormula{Action: f.Action, Inner: fn(f.Inner)}
+	case MuFormula:
+		return MuFormula{Var: f.Var, Body: fn(f.Body)}
+	case NuFormula:
+		return NuFormula{Var: f.Var, Body: fn(f.Body)}
+	case EFormula:
+		return EFormula{Path: mapPathLeaves(f.Path, fn)}
+	case AFormula:
+		return AFormula{Path: mapPathLeaves(f.Path, fn)}
+	case YFormula:
+		return YFormula{Inner: fn(f.Inner)}
+	case OFormula:
+		return OFormula{Inner: fn(f.Inner)}
+	case HFormula:
+		return HFormula{Inner: fn(f.Inner)}
+	case SFormula:
+		return SFormula{Left: fn(f.Left), Right: fn(f.Right)}
+	case HistoryFormula:
+		return HistoryFormula{Inner: fn(f.Inner)}
+	}
+	return f
+}
+
+// mapPathLeaves rebuilds p with fn applied to the state formula at each
+// leaf.
+func mapPathLeaves(p PathFormula, fn func(Formula) Formula) PathFormula {
+	rec := func(p PathFormula) PathFormula { return mapPathLeaves(p, fn) }
+	switch p := p.(type) {
+	case PathState:
+		return PathState{State: fn(p.State)}
+	case PathNot:
+		return PathNot{Inner: rec(p.Inner)}
+	case PathAnd:
+		return PathAnd{Left: rec(p.Left), Right: rec(p.Right)}
+	case PathOr:
+		return PathOr{Left: rec(p.Left), Right: rec(p.Right)}
+	case PathNext:
+		return PathNext{Inner: rec(p.Inner)}
+	case PathUntil:
+		return PathUntil{Left: rec(p.Left), Right: rec(p.Right)}
+	}
+	return p
+}
+
+// children lists the direct state subformulas of f, in the order
+// mapChildren visits them.
+func children(f Formula) []Formula {
+	var out []Formula
+	mapChildren(f, func(c Formula) Formula {
+		out = append(out, c)
+		return c
+	})
+	return out
+}
@@ -0,0 +1,514 @@
+package sat
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimit is returned when a solver gives up before deciding.
+var ErrLimit = errors.New("sat: conflict limit reached")
+
+// CDCL is a conflict-driven clause-learning solver: two watched literals,
+// first-UIP learning, VSIDS variable activities with phase saving, and
+// Luby restarts. Learnt clauses are kept, which suits the moderate
+// problems bounded model checking produces.
+type CDCL struct {
+	// MaxConflicts bounds the search; 0 means no bound.
+	MaxConflicts int
+}
+
+// Solve decides c.
+func (s CDCL) Solve(c *CNF) (Result, error) {
+	in := Incremental{CNF: *c, MaxConflicts: s.MaxConflicts}
+	ok, err := in.Solve()
+	if err != nil || !ok {
+		return Result{}, err
+	}
+	return Result{Sat: true, Model: in.model}, nil
+}
+
+// Incremental keeps a CNF loaded in a CDCL solver between calls to Solve,
+// so what it learns answering one query speeds up the next. Clauses and
+// variables may be added between calls, and each call may assume
+// literals, which holds only for that call.
+type Incremental struct {
+	CNF
+	// MaxConflicts bounds each call; 0 means no bound.
+	MaxConflicts int
+
+	s      *solver
+	loaded int
+	model  []bool
+	core   []Lit
+}
+
+// Solve decides the clauses added so far together with the assumptions.
+func (in *Incremental) Solve(assumptions ...Lit) (bool, error) {
+	if in.s == nil {
+		in.s = newSolver(0)
+	}
+	s := in.s
+	s.grow(in.NumVars)
+	for ; in.loaded < len(in.Clauses); in.loaded++ {
+		if !s.unsat && !s.addClause(in.Clauses[in.loaded]) {
+			s.unsat = true
+		}
+	}
+	in.model, in.core = nil, nil
+	if s.unsat {
+		return false, nil
+	}
+	as := make([]lit, len(assumptions))
+	for i, l := range assumptions {
+		if l == 0 || l.Var() > in.NumVars {
+			panic(fmt.Sprintf("sat: assumption %d of a formula with %d variables", l, in.NumVars))
+		}
+		as[i] = toLit(l)
+	}
+	ok, err := s.search(in.MaxConflicts, as)
+	if ok {
+		in.model = make([]bool, in.NumVars+1)
+		for v := 1; v <= in.NumVars; v++ {
+			in.model[v] = s.assign[v-1] == 1
+		}
+	} else if err == nil {
+		for _, l := range s.core {
+			in.core = append(in.core, fromLit(l))
+		}
+	}
+	s.backtrack(0)
+	return ok, err
+}
+
+// Value is l's value in the model found by the last Solve, which must
+// have succeeded.
+func (in *Incremental) Value(l Lit) bool {
+	return Result{Sat: true, Model: in.model}.Value(l)
+}
+
+// Core is, after a Solve that failed, a subset of its assumptions that
+// cannot hold together; it is empty when the clauses alone are
+// unsatisfiable.
+func (in *Incremental) Core() []Lit { return in.core }
+
+// Inside the solver variables count from 0 and literal 2v+1 is ¬v.
+type lit int32
+
+func toLit(l Lit) lit {
+	if l < 0 {
+		return lit(2*(-l-1) + 1)
+	}
+	return lit(2 * (l - 1))
+}
+
+func fromLit(l lit) Lit {
+	if l&1 == 1 {
+		return Lit(-(l.v() + 1))
+	}
+	return Lit(l.v() + 1)
+}
+
+func (l lit) v() int   { return int(l >> 1) }
+func (l lit) neg() lit { return l ^ 1 }
+func (l lit) sign() int8 {
+	if l&1 == 1 {
+		return -1
+	}
+	return 1
+}
+
+type solver struct {
+	clauses [][]lit
+	// watches[l] lists the clauses watching l, visited when l becomes
+	// false. A clause watches its first two literals.
+	watches [][]int
+	assign  []int8 // 1 true, -1 false, 0 unassigned
+	level   []int
+	reason  []int // clause index, or -1 for decisions and level-0 units
+	trail   []lit
+	limits  []int // trail length at each decision
+	qhead   int
+
+	activity []float64
+	inc      float64
+	order    heap
+	phase    []bool
+	seen     []bool
+
+	unsat bool  // the clauses alone are unsatisfiable
+	core  []lit // failed assumptions of the last search
+}
+
+func newSolver(n int) *solver {
+	s := &solver{inc: 1}
+	s.grow(n)
+	return s
+}
+
+// grow adds variables up to n.
+func (s *solver) grow(n int) {
+	for v := len(s.assign); v < n; v++ {
+		s.watches = append(s.watches, nil, nil)
+		s.assign = append(s.assign, 0)
+		s.level = append(s.level, 0)
+		s.reason = append(s.reason, -1)
+		s.activity = append(s.activity, 0)
+		s.phase = append(s.phase, false)
+		s.seen = append(s.seen, false)
+		s.order.act = s.activity
+		s.order.pos = append(s.order.pos, -1)
+		s.order.push(v)
+	}
+}
+
+func (s *solver) value(l lit) int8 { return s.assign[l.v()] * l.sign() }
+
+func (s *solver) decisionLevel() int { return len(s.limits) }
+
+// addClause adds an input clause at level 0, simplifying it; false means
+// the formula is already unsatisfiable.
+func (s *solver) addClause(in []Lit) bool {
+	var cl []lit
+	has := make(map[lit]bool, len(in))
+	for _, l := range in {
+		x := toLit(l)
+		switch {
+		case has[x]:
+			continue
+		case has[x.neg()] || s.value(x) == 1:
+			return true // a tautology, or already satisfied
+		case s.value(x) == -1:
+			continue
+		}
+		has[x] = true
+		cl = append(cl, x)
+	}
+	switch len(cl) {
+	case 0:
+		return false
+	case 1:
+		s.enqueue(cl[0], -1)
+		return s.propagate() < 0
+	}
+	s.attach(cl)
+	return true
+}
+
+func (s *solver) attach(cl []lit) int {
+	i := len(s.clauses)
+	s.clauses = append(s.clauses, cl)
+	s.watches[cl[0]] = append(s.watches[cl[0]], i)
+	s.watches[cl[1]] = append(s.watches[cl[1]], i)
+	return i
+}
+
+func (s *solver) enqueue(l lit, reason int) {
+	v := l.v()
+	s.assign[v] = l.sign()
+	s.level[v] = s.decisionLevel()
+	s.reason[v] = reason
+	s.trail = append(s.trail, l)
+}
+
+// propagate runs unit propagation and returns a conflicting clause, or -1.
+func (s *solver) propagate() int {
+	for s.qhead < len(s.trail) {
+		f := s.trail[s.qhead].neg()
+		s.qhead++
+		ws := s.watches[f]
+		kept := ws[:0]
+		for i := 0; i < len(ws); i++ {
+			ci := ws[i]
+			cl := s.clauses[ci]
+			if cl[0] == f {
+				cl[0], cl[1] = cl[1], cl[0]
+			}
+			if s.value(cl[0]) == 1 {
+				kept = append(kept, ci)
+				continue
+			}
+			moved := false
+			for k := 2; k < len(cl); k++ {
+				if s.value(cl[k]) != -1 {
+					cl[1], cl[k] = cl[k], cl[1]
+					s.watches[cl[1]] = append(s.watches[cl[1]], ci)
+					moved = true
+					break
+				}
+			}
+			if moved {
+				continue
+			}
+			kept = append(kept, ci)
+			if s.value(cl[0]) == -1 {
+				kept = append(kept, ws[i+1:]...)
+				s.watches[f] = kept
+				return ci
+			}
+			s.enqueue(cl[0], ci)
+		}
+		s.watches[f] = kept
+	}
+	return -1
+}
+
+// analyze derives the first-UIP clause from a conflict; the asserting
+// literal comes first and one from the backjump level second.
+func (s *solver) analyze(confl int) ([]lit, int) {
+	learnt := []lit{0}
+	pending := 0
+	var p lit = -1
+	idx := len(s.trail) - 1
+	for {
+		cl := s.clauses[confl]
+		start := 0
+		if p != -1 {
+			start = 1 // cl[0] is p, the literal this clause implied
+		}
+		for _, q := range cl[start:] {
+			v := q.v()
+			if s.seen[v] || s.level[v] == 0 {
+				continue
+			}
+			s.bump(v)
+			s.seen[v] = true
+			if s.level[v] == s.decisionLevel() {
+				pending++
+			} else {
+				learnt = append(learnt, q)
+			}
+		}
+		for !s.seen[s.trail[idx].v()] {
+			idx--
+		}
+		p = s.trail[idx]
+		idx--
+		s.seen[p.v()] = false
+		pending--
+		if pending == 0 {
+			break
+		}
+		confl = s.reason[p.v()]
+	}
+	learnt[0] = p.neg()
+
+	back := 0
+	for i := 1; i < len(learnt); i++ {
+		s.seen[learnt[i].v()] = false
+		if lv := s.level[learnt[i].v()]; lv > back {
+			back = lv
+			learnt[1], learnt[i] = learnt[i], learnt[1]
+		}
+	}
+	return learnt, back
+}
+
+// analyzeFinal lists the assumptions that force the assumption p false:
+// p and the decisions its implication depends on.
+func (s *solver) analyzeFinal(p lit) []lit {
+	core := []lit{p}
+	if s.level[p.v()] == 0 {
+		return core
+	}
+	s.seen[p.v()] = true
+	for i := len(s.trail) - 1; i >= s.limits[0]; i-- {
+		x := s.trail[i].v()
+		if !s.seen[x] {
+			continue
+		}
+		if r := s.reason[x]; r < 0 {
+			core = append(core, s.trail[i])
+		} else {
+			for _, q := range s.clauses[r][1:] {
+				if s.level[q.v()] > 0 {
+					s.seen[q.v()] = true
+				}
+			}
+		}
+		s.seen[x] = false
+	}
+	return core
+}
+
+func (s *solver) backtrack(lv int) {
+	if s.decisionLevel() <= lv {
+		return
+	}
+	for i := len(s.trail) - 1; i >= s.limits[lv]; i-- {
+		v := s.trail[i].v()
+		s.phase[v] = s.assign[v] == 1
+		s.assign[v] = 0
+		if s.order.pos[v] < 0 {
+			s.order.push(v)
+		}
+	}
+	s.trail = s.trail[:s.limits[lv]]
+	s.qhead = len(s.trail)
+	s.limits = s.limits[:lv]
+}
+
+func (s *solver) bump(v int) {
+	s.activity[v] += s.inc
+	if s.activity[v] > 1e100 {
+		for i := range s.activity {
+			s.activity[i] *= 1e-100
+		}
+		s.inc *= 1e-100
+	}
+	if s.order.pos[v] >= 0 {
+		s.order.up(s.order.pos[v])
+	}
+}
+
+// luby is the i-th element (from 0) of 1 1 2 1 1 2 4 1 1 2 ...
+func luby(i int) int {
+	size, seq := 1, 0
+	for size < i+1 {
+		seq++
+		size = 2*size + 1
+	}
+	for size-1 != i {
+		size = (size - 1) / 2
+		seq--
+		i %= size
+	}
+	return 1 << seq
+}
+
+// search looks for a model in which the assumptions hold; they are
+// decided first, one per level. When they cannot hold, core lists the
+// ones responsible.
+func (s *solver) search(maxConflicts int, assumptions []lit) (bool, error) {
+	s.core = nil
+	if s.propagate() >= 0 {
+		s.unsat = true
+		return false, nil
+	}
+	conflicts := 0
+	for restart := 0; ; restart++ {
+		budget := 100 * luby(restart)
+		for budget > 0 {
+			if confl := s.propagate(); confl >= 0 {
+				conflicts++
+				budget--
+				if s.decisionLevel() == 0 {
+					s.unsat = true
+					return false, nil
+				}
+				if maxConflicts > 0 && conflicts >= maxConflicts {
+					return false, ErrLimit
+				}
+				learnt, back := s.analyze(confl)
+				s.backtrack(back)
+				if len(learnt) == 1 {
+					s.enqueue(learnt[0], -1)
+				} else {
+					s.enqueue(learnt[0], s.attach(learnt))
+				}
+				s.inc /= 0.95
+				continue
+			}
+			if dl := s.decisionLevel(); dl < len(assumptions) {
+				p := assumptions[dl]
+				switch s.value(p) {
+				case 1:
+					// Already true: an empty level keeps the count.
+					s.limits = append(s.limits, len(s.trail))
+				case -1:
+					s.core = s.analyzeFinal(p)
+					return false, nil
+				default:
+					s.limits = append(s.limits, len(s.trail))
+					s.enqueue(p, -1)
+				}
+				continue
+			}
+			v := s.pick()
+			if v < 0 {
+				return true, nil
+			}
+			s.limits = append(s.limits, len(s.trail))
+			l := lit(2 * v)
+			if !s.phase[v] {
+				l = l.neg()
+			}
+			s.enqueue(l, -1)
+		}
+		s.backtrack(0)
+	}
+}
+
+// pick returns the unassigned variable of highest activity, or -1.
+func (s *solver) pick() int {
+	for s.order.len() > 0 {
+		v := s.order.pop()
+		if s.assign[v] == 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+// heap is a max-heap of variables by activity.
+type heap struct {
+	act  []float64
+	vars []int
+	pos  []int // index in vars, -1 when absent
+}
+
+func (h *heap) len() int { return len(h.vars) }
+
+func (h *heap) push(v int) {
+	h.pos[v] = len(h.vars)
+	h.vars = append(h.vars, v)
+	h.up(h.pos[v])
+}
+
+func (h *heap) pop() int {
+	v := h.vars[0]
+	last := h.vars[len(h.vars)-1]
+	h.vars = h.vars[:len(h.vars)-1]
+	h.pos[v] = -1
+	if len(h.vars) > 0 {
+		h.vars[0] = last
+		h.pos[last] = 0
+		h.down(0)
+	}
+	return v
+}
+
+func (h *heap) less(i, j int) bool { return h.act[h.vars[i]] > h.act[h.vars[j]] }
+
+func (h *heap) swap(i, j int) {
+	h.vars[i], h.vars[j] = h.vars[j], h.vars[i]
+	h.pos[h.vars[i]] = i
+	h.pos[h.vars[j]] = j
+}
+
+func (h *heap) up(i int) {
+	for i > 0 {
+		p := (i - 1) / 2
+		if !h.less(i, p) {
+			return
+		}
+		h.swap(i, p)
+		i = p
+	}
+}
+
+func (h *heap) down(i int) {
+	for {
+		c := 2*i + 1
+		if c >= len(h.vars) {
+			return
+		}
+		if c+1 < len(h.vars) && h.less(c+1, c) {
+			c++
+		}
+		if !h.less(c, i) {
+			return
+		}
+		h.swap(i, c)
+		i = c
+	}
+}
@@ -0,0 +1,141 @@
+// Package sat holds formulas in conjunctive normal form and solves them.
+// The CDCL solver is pure Go and needs nothing installed; Command runs an
+// external DIMACS solver instead for problems beyond it. Both implement
+// Solver.
+//
+// Literals use the DIMACS convention: variable v is the literal v and its
+// negation -v, with variables numbered from 1.
+package sat
+
+import "fmt"
+
+// Lit is a literal: a variable, or its negation when negative.
+type Lit int
+
+// Var is the literal's variable.
+func (l Lit) Var() int {
+	if l < 0 {
+		return int(-l)
+	}
+	return int(l)
+}
+
+// Not is the literal's negation.
+func (l Lit) Not() Lit { return -l }
+
+// CNF is a conjunction of clauses, each a disjunction of literals.
+type CNF struct {
+	NumVars int
+	Clauses [][]Lit
+
+	// constant is a variable forced true, made on first use.
+	constant Lit
+}
+
+// NewVar adds a variable and returns it as a positive literal.
+func (c *CNF) NewVar() Lit {
+	c.NumVars++
+	return Lit(c.NumVars)
+}
+
+// Add appends the clause l1 ∨ l2 ∨ .... An empty clause makes the formula
+// unsatisfiable.
+func (c *CNF) Add(lits ...Lit) {
+	for _, l := range lits {
+		if l == 0 || l.Var() > c.NumVars {
+			panic(fmt.Sprintf("sat: literal %d of a formula with %d variables", l, c.NumVars))
+		}
+	}
+	c.Clauses = append(c.Clauses, append([]Lit(nil), lits...))
+}
+
+// ---------- Gates ----------
+//
+// The gates below add Tseitin definitions: each returns a fresh literal
+// constrained to equal the gate's output, so nested formulas become
+// clauses of linear size.
+
+// True is a literal that always holds.
+func (c *CNF) True() Lit {
+	if c.constant == 0 {
+		c.constant = c.NewVar()
+		c.Add(c.constant)
+	}
+	return c.constant
+}
+
+// False is a literal that never holds.
+func (c *CNF) False() Lit { return c.True().Not() }
+
+// And is a literal equal to the conjunction of lits (True for none).
+func (c *CNF) And(lits ...Lit) Lit {
+	switch len(lits) {
+	case 0:
+		return c.True()
+	case 1:
+		return lits[0]
+	}
+	out := c.NewVar()
+	long := []Lit{out}
+	for _, l := range lits {
+		c.Add(out.Not(), l)
+		long = append(long, l.Not())
+	}
+	c.Add(long...)
+	return out
+}
+
+// Or is a literal equal to the disjunction of lits (False for none).
+func (c *CNF) Or(lits ...Lit) Lit {
+	neg := make([]Lit, len(lits))
+	for i, l := range lits {
+		neg[i] = l.Not()
+	}
+	return c.And(neg...).Not()
+}
+
+// Ite is a literal equal to if cond then t else e.
+func (c *CNF) Ite(cond, t, e Lit) Lit {
+	switch {
+	case t == e:
+		return t
+	case c.constant != 0 && cond == c.constant:
+		return t
+	case c.constant != 0 && cond == c.constant.Not():
+		return e
+	}
+	out := c.NewVar()
+	c.Add(cond.Not(), t.Not(), out)
+	c.Add(cond.Not(), t, out.Not())
+	c.Add(cond, e.Not(), out)
+	c.Add(cond, e, out.Not())
+	// Redundant, but lets propagation conclude out when t and e agree.
+	c.Add(t.Not(), e.Not(), out)
+	c.Add(t, e, out.Not())
+	return out
+}
+
+// Equiv is a literal equal to a ↔ b.
+func (c *CNF) Equiv(a, b Lit) Lit { return c.Ite(a, b, b.Not()) }
+
+// ---------- Solving ----------
+
+// Result is the outcome of solving a CNF. When Sat, Model[v] is the value
+// of variable v (Model[0] is unused).
+type Result struct {
+	Sat   bool
+	Model []bool
+}
+
+// Value is the model's value for the literal.
+func (r Result) Value(l Lit) bool {
+	if l < 0 {
+		return !r.Model[-l]
+	}
+	return r.Model[l]
+}
+
+// Solver decides satisfiability.
+type Solver interface {
+	Solve(c *CNF) (Result, error)
+}
@@ -0,0 +1,162 @@
+package sat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// WriteDIMACS writes c in the DIMACS CNF format.
+func (c *CNF) WriteDIMACS(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "p cnf %d %d\n", c.NumVars, len(c.Clauses))
+	for _, cl := range c.Clauses {
+		for _, l := range cl {
+			bw.WriteString(strconv.Itoa(int(l)))
+			bw.WriteByte(' ')
+		}
+		bw.WriteString("0\n")
+	}
+	return bw.Flush()
+}
+
+// ReadDIMACS parses a DIMACS CNF file. Comment lines are skipped and
+// clauses may span lines.
+func ReadDIMACS(r io.Reader) (*CNF, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<24)
+	c := &CNF{}
+	header := false
+	var clause []Lit
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		switch {
+		case text == "" || text[0] == 'c' || text[0] == '%':
+			continue
+		case text[0] == 'p':
+			var n, m int
+			if _, err := fmt.Sscanf(text, "p cnf %d %d", &n, &m); err != nil || header {
+				return nil, fmt.Errorf("sat: line %d: bad problem line %q", line, text)
+			}
+			c.NumVars, header = n, true
+			continue
+		}
+		if !header {
+			return nil, fmt.Errorf("sat: line %d: clause before the problem line", line)
+		}
+		for _, field := range strings.Fields(text) {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("sat: line %d: %w", line, err)
+			}
+			if n == 0 {
+				c.Add(clause...)
+				clause = clause[:0]
+				continue
+			}
+			if l := Lit(n); l.Var() > c.NumVars {
+				return nil, fmt.Errorf("sat: line %d: variable %d beyond %d", line, l.Var(), c.NumVars)
+			}
+			clause = append(clause, Lit(n))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(clause) > 0 {
+		c.Add(clause...)
+	}
+	return c, nil
+}
+
+// Command runs an external solver that reads DIMACS on standard input
+// and answers in the SAT competition format ("s SATISFIABLE" and "v"
+// lines), as minisat-style tools such as kissat and cadical do.
+type Command struct {
+	Path string
+	Args []string
+	Ctx  context.Context // nil means context.Background()
+}
+
+// Solve runs the command on c.
+func (s Command) Solve(c *CNF) (Result, error) {
+	ctx := s.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var in, out, stderr bytes.Buffer
+	if err := c.WriteDIMACS(&in); err != nil {
+		return Result{}, err
+	}
+	cmd := exec.CommandContext(ctx, s.Path, s.Args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = &in, &out, &stderr
+	err := cmd.Run()
+	// By convention the exit status is 10 for satisfiable and 20 for
+	// unsatisfiable, so a non-zero status alone is not a failure.
+	if _, ok := err.(*exec.ExitError); !ok && err != nil {
+		return Result{}, err
+	}
+	res, perr := ParseSolution(&out, c.NumVars)
+	if perr != nil {
+		if err != nil {
+			return Result{}, fmt.Errorf("sat: %s: %v: %s", s.Path, err, strings.TrimSpace(stderr.String()))
+		}
+		return Result{}, fmt.Errorf("sat: %s: %w", s.Path, perr)
+	}
+	return res, nil
+}
+
+// ParseSolution reads a solver's answer in the SAT competition format.
+// Variables the "v" lines leave out are false.
+func ParseSolution(r io.Reader, numVars int) (Result, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<24)
+	var res Result
+	status := ""
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "s":
+			status = strings.Join(fields[1:], " ")
+		case "v":
+			if res.Model == nil {
+				res.Model = make([]bool, numVars+1)
+			}
+			for _, f := range fields[1:] {
+				n, err := strconv.Atoi(f)
+				if err != nil {
+					return Result{}, fmt.Errorf("bad value %q", f)
+				}
+				if n > 0 && n <= numVars {
+					res.Model[n] = true
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return Result{}, err
+	}
+	switch status {
+	case "SATISFIABLE":
+		res.Sat = true
+		if res.Model == nil {
+			res.Model = make([]bool, numVars+1)
+		}
+		return res, nil
+	case "UNSATISFIABLE":
+		return Result{}, nil
+	case "UNKNOWN":
+		return Result{}, ErrLimit
+	}
+	return Result{}, fmt.Errorf("no solution line")
+}
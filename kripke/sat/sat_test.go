@@ -0,0 +1,244 @@
+package sat
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// brute decides c by trying every assignment.
+func brute(c *CNF) bool {
+	for a := 0; a < 1<<c.NumVars; a++ {
+		if satisfies(c, func(v int) bool { return a>>(v-1)&1 == 1 }) {
+			return true
+		}
+	}
+	return false
+}
+
+func satisfies(c *CNF, value func(v int) bool) bool {
+	for _, cl := range c.Clauses {
+		ok := false
+		for _, l := range cl {
+			if value(l.Var()) == (l > 0) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func random3SAT(rng *rand.Rand, n, m int) *CNF {
+	c := &CNF{NumVars: n}
+	for i := 0; i < m; i++ {
+		var cl []Lit
+		for j := 0; j < 3; j++ {
+			l := Lit(1 + rng.Intn(n))
+			if rng.Intn(2) == 0 {
+				l = -l
+			}
+			cl = append(cl, l)
+		}
+		c.Add(cl...)
+	}
+	return c
+}
+
+func TestCDCLMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(46))
+	counts := map[bool]int{}
+	for i := 0; i < 500; i++ {
+		n := 3 + rng.Intn(10)
+		// Around the 4.26 clauses per variable threshold, both answers
+		// are common.
+		c := random3SAT(rng, n, 3*n+rng.Intn(3*n))
+		res, err := CDCL{}.Solve(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := brute(c); res.Sat != want {
+			t.Fatalf("instance %d: CDCL says %v, brute force %v", i, res.Sat, want)
+		}
+		if res.Sat && !satisfies(c, func(v int) bool { return res.Model[v] }) {
+			t.Fatalf("instance %d: model does not satisfy the formula", i)
+		}
+		counts[res.Sat]++
+	}
+	if counts[true] == 0 || counts[false] == 0 {
+		t.Fatalf("unbalanced instances: %v", counts)
+	}
+}
+
+// pigeons encodes n+1 pigeons in n holes, which is unsatisfiable.
+func pigeons(n int) *CNF {
+	c := &CNF{}
+	p := make([][]Lit, n+1)
+	for i := range p {
+		p[i] = make([]Lit, n)
+		for j := range p[i] {
+			p[i][j] = c.NewVar()
+		}
+		c.Add(p[i]...)
+	}
+	for j := 0; j < n; j++ {
+		for a := 0; a <= n; a++ {
+			for b := a + 1; b <= n; b++ {
+				c.Add(p[a][j].Not(), p[b][j].Not())
+			}
+		}
+	}
+	return c
+}
+
+func TestPigeonholeAndLimit(t *testing.T) {
+	res, err := CDCL{}.Solve(pigeons(6))
+	if err != nil || res.Sat {
+		t.Fatalf("pigeonhole: %v %v", res.Sat, err)
+	}
+	if _, err := (CDCL{MaxConflicts: 10}).Solve(pigeons(8)); err != ErrLimit {
+		t.Fatalf("conflict limit: %v", err)
+	}
+}
+
+func TestGates(t *testing.T) {
+	c := &CNF{}
+	a, b, d := c.NewVar(), c.NewVar(), c.NewVar()
+	and, or, ite, eq := c.And(a, b, d), c.Or(a, b.Not()), c.Ite(a, b, d), c.Equiv(a, d)
+	for x := 0; x < 8; x++ {
+		va, vb, vd := x&1 == 1, x&2 == 2, x&4 == 4
+		f := &CNF{NumVars: c.NumVars, Clauses: c.Clauses}
+		for l, val := range map[Lit]bool{a: va, b: vb, d: vd} {
+			if val {
+				f.Add(l)
+			} else {
+				f.Add(l.Not())
+			}
+		}
+		res, err := CDCL{}.Solve(f)
+		if err != nil || !res.Sat {
+			t.Fatalf("%03b: %v %v", x, res.Sat, err)
+		}
+		ite0 := vd
+		if va {
+			ite0 = vb
+		}
+		if res.Value(and) != (va && vb && vd) || res.Value(or) != (va || !vb) ||
+			res.Value(ite) != ite0 || res.Value(eq) != (va == vd) {
+			t.Fatalf("%03b: gates disagree", x)
+		}
+	}
+	if res, _ := (CDCL{}).Solve(&CNF{Clauses: [][]Lit{{}}}); res.Sat {
+		t.Fatal("empty clause satisfied")
+	}
+}
+
+func TestDIMACS(t *testing.T) {
+	rng := rand.New(rand.NewSource(4646))
+	c := random3SAT(rng, 8, 20)
+	var buf bytes.Buffer
+	if err := c.WriteDIMACS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadDIMACS(strings.NewReader("c a comment\n" + buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.NumVars != c.NumVars || len(d.Clauses) != len(c.Clauses) {
+		t.Fatalf("round trip: %d vars %d clauses", d.NumVars, len(d.Clauses))
+	}
+	for i := range c.Clauses {
+		for j := range c.Clauses[i] {
+			if c.Clauses[i][j] != d.Clauses[i][j] {
+				t.Fatalf("clause %d differs", i)
+			}
+		}
+	}
+	for _, bad := range []string{"1 2 0\n", "p cnf 2 1\n1 3 0\n", "p cnf x\n"} {
+		if _, err := ReadDIMACS(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+
+	res, err := ParseSolution(strings.NewReader("c hi\ns SATISFIABLE\nv 1 -2\nv 3 0\n"), 3)
+	if err != nil || !res.Sat || !res.Model[1] || res.Model[2] || !res.Model[3] {
+		t.Fatalf("solution: %+v %v", res, err)
+	}
+	if res, err := ParseSolution(strings.NewReader("s UNSATISFIABLE\n"), 3); err != nil || res.Sat {
+		t.Fatalf("unsat solution: %+v %v", res, err)
+	}
+}
+
+func TestLuby(t *testing.T) {
+	want := []int{1, 1, 2, 1, 1, 2, 4, 1, 1, 2, 1, 1, 2, 4, 8}
+	for i, w := range want {
+		if got := luby(i); got != w {
+			t.Fatalf("luby(%d) = %d, want %d", i, got, w)
+		}
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	rng := rand.New(rand.NewSource(4647))
+	for i := 0; i < 100; i++ {
+		n := 4 + rng.Intn(8)
+		in := &Incremental{}
+		for v := 0; v < n; v++ {
+			in.NewVar()
+		}
+		// Add clauses in batches, querying under random assumptions in
+		// between.
+		for batch := 0; batch < 4; batch++ {
+			for _, cl := range random3SAT(rng, n, n).Clauses {
+				in.Add(cl...)
+			}
+			var as []Lit
+			for _, v := range rng.Perm(n)[:rng.Intn(4)] {
+				l := Lit(v + 1)
+				if rng.Intn(2) == 0 {
+					l = -l
+				}
+				as = append(as, l)
+			}
+			withAssumptions := func(as []Lit) *CNF {
+				c := &CNF{NumVars: in.NumVars, Clauses: append([][]Lit(nil), in.Clauses...)}
+				for _, l := range as {
+					c.Add(l)
+				}
+				return c
+			}
+			ok, err := in.Solve(as...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := brute(withAssumptions(as)); ok != want {
+				t.Fatalf("instance %d batch %d: %v, want %v", i, batch, ok, want)
+			}
+			if ok {
+				for _, l := range as {
+					if !in.Value(l) {
+						t.Fatalf("assumption %d is false in the model", l)
+					}
+				}
+				continue
+			}
+			core := in.Core()
+			for _, l := range core {
+				found := false
+				for _, a := range as {
+					found = found || a == l
+				}
+				if !found {
+					t.Fatalf("core %v is not within the assumptions %v", core, as)
+				}
+			}
+			if brute(withAssumptions(core)) {
+				t.Fatalf("core %v of %v is satisfiable", core, as)
+			}
+		}
+	}
+}
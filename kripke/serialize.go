@@ -0,0 +1,540 @@
+package kripke
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// graphData is the exported, dense form of a Graph shared by checkpoints
+// and every serialization format. Slices are indexed by StateID.
+type graphData struct {
+	Names  []string
+	Labels []map[string]bool
+	Vars   []map[string]any
+	Succ   [][]StateID
+	Probs  [][]float64
+	Init   []StateID
+
+	EdgeLabels [][]EdgeLabel // parallel to Succ; nil for unlabeled states
+}
+
+func (g *Graph) data() graphData {
+	d := graphData{
+		Names:  make([]string, g.nextID),
+		Labels: make([]map[string]bool, g.nextID),
+		Vars:   make([]map[string]any, g.nextID),
+		Succ:   make([][]StateID, g.nextID),
+		Probs:  make([][]float64, g.nextID),
+		Init:   g.InitialStates(),
+
+		EdgeLabels: make([][]EdgeLabel, g.nextID),
+	}
+	for i := 0; i < g.nextID; i++ {
+		id := StateID(i)
+		d.Names[i] = g.idToName[id]
+		d.Labels[i] = g.labels[id]
+		d.Vars[i] = g.vars[id]
+		d.Succ[i] = g.succ[id]
+		d.Probs[i] = g.prob[id]
+		d.EdgeLabels[i] = g.elabels[id]
+	}
+	return d
+}
+
+// graphFromData rebuilds a Graph, checking that every reference is in range
+// and names are unique, since the data may come from an untrusted file.
+func graphFromData(d graphData) (*Graph, error) {
+	n := len(d.Names)
+	inRange := func(id StateID) bool { return id >= 0 && int(id) < n }
+	g := NewGraph()
+	for i, name := range d.Names {
+		if _, dup := g.nameToID[name]; dup {
+			return nil, fmt.Errorf("duplicate state name %q", name)
+		}
+		var lbls map[string]bool
+		if i < len(d.Labels) && d.Labels[i] != nil {
+			lbls = make(map[string]bool, len(d.Labels[i]))
+			for k, v := range d.Labels[i] {
+				lbls[k] = v
+			}
+		}
+		id := g.AddState(name, lbls)
+		if i < len(d.Vars) && d.Vars[i] != nil {
+			for k, v := range d.Vars[i] {
+				if _, ok := normalizeValue(v); !ok {
+					return nil, fmt.Errorf("state %q: variable %s has unsupported type %T", name, k, v)
+				}
+			}
+			g.SetVars(id, d.Vars[i])
+		}
+	}
+	for i, succ := range d.Succ {
+		if i >= n {
+			return nil, fmt.Errorf("edges for undefined state %d", i)
+		}
+		for _, t := range succ {
+			if !inRange(t) {
+				return nil, fmt.Errorf("state %q: edge to undefined state %d", d.Names[i], t)
+			}
+		}
+		if len(succ) > 0 {
+			g.succ[StateID(i)] = append([]StateID(nil), succ...)
+		}
+		if i < len(d.Probs) && len(d.Probs[i]) > 0 {
+			if len(d.Probs[i]) != len(succ) {
+				return nil, fmt.Errorf("state %q: %d probabilities for %d edges", d.Names[i], len(d.Probs[i]), len(succ))
+			}
+			g.prob[StateID(i)] = append([]float64(nil), d.Probs[i]...)
+		}
+		if i < len(d.EdgeLabels) && len(d.EdgeLabels[i]) > 0 {
+			if len(d.EdgeLabels[i]) != len(succ) {
+				return nil, fmt.Errorf("state %q: %d edge labels for %d edges", d.Names[i], len(d.EdgeLabels[i]), len(succ))
+			}
+			ls := make([]EdgeLabel, len(succ))
+			for j, l := range d.EdgeLabels[i] {
+				l.Prob = 0
+				ls[j] = l
+			}
+			g.elabels[StateID(i)] = ls
+		}
+	}
+	for _, id := range d.Init {
+		if !inRange(id) {
+			return nil, fmt.Errorf("undefined initial state %d", id)
+		}
+		g.init = append(g.init, id)
+	}
+	return g, nil
+}
+
+// ---------- JSON ----------
+
+// The JSON form lists states in StateID order and refers to them by index:
+//
+//	{
+//	  "states":  [{"name": "s0", "labels": {"p": true}, "vars": {"x": 1}}, ...],
+//	  "edges":   [{"from": 0, "to": 1, "prob": 0.5, "action": "send"}, ...],
+//	  "initial": [0]
+//	}
+//
+// "prob" is only present on edges of probabilistic states, and "action",
+// "guard" and "process" only when they are set.
+type jsonGraph struct {
+	States  []jsonState `json:"states"`
+	Edges   []jsonEdge  `json:"edges"`
+	Initial []StateID   `json:"initial"`
+}
+
+type jsonState struct {
+	Name   string          `json:"name"`
+	Labels map[string]bool `json:"labels,omitempty"`
+	Vars   map[string]any  `json:"vars,omitempty"`
+}
+
+type jsonEdge struct {
+	From StateID  `json:"from"`
+	To   StateID  `json:"to"`
+	Prob *float64 `json:"prob,omitempty"`
+
+	Action  string `json:"action,omitempty"`
+	Guard   string `json:"guard,omitempty"`
+	Process string `json:"process,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	d := g.data()
+	jg := jsonGraph{States: make([]jsonState, len(d.Names)), Edges: []jsonEdge{}, Initial: d.Init}
+	for i, name := range d.Names {
+		jg.States[i] = jsonState{Name: name, Labels: d.Labels[i], Vars: d.Vars[i]}
+		for j, t := range d.Succ[i] {
+			e := jsonEdge{From: StateID(i), To: t}
+			if d.Probs[i] != nil {
+				p := d.Probs[i][j]
+				e.Prob = &p
+			}
+			if d.EdgeLabels[i] != nil {
+				l := d.EdgeLabels[i][j]
+				e.Action, e.Guard, e.Process = l.Action, l.Guard, l.Process
+			}
+			jg.Edges = append(jg.Edges, e)
+		}
+	}
+	return json.Marshal(jg)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing g's contents.
+func (g *Graph) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var jg jsonGraph
+	if err := dec.Decode(&jg); err != nil {
+		return err
+	}
+	n := len(jg.States)
+	d := graphData{
+		Names:  make([]string, n),
+		Labels: make([]map[string]bool, n),
+		Vars:   make([]map[string]any, n),
+		Succ:   make([][]StateID, n),
+		Probs:  make([][]float64, n),
+		Init:   jg.Initial,
+
+		EdgeLabels: make([][]EdgeLabel, n),
+	}
+	for i, st := range jg.States {
+		d.Names[i] = st.Name
+		d.Labels[i] = st.Labels
+		if len(st.Vars) > 0 {
+			d.Vars[i] = make(map[string]any, len(st.Vars))
+			for k, v := range st.Vars {
+				if num, ok := v.(json.Number); ok {
+					iv, err := num.Int64()
+					if err != nil {
+						return fmt.Errorf("state %q: variable %s: %v", st.Name, k, err)
+					}
+					v = int(iv)
+				}
+				d.Vars[i][k] = v
+			}
+		}
+	}
+	probabilistic := make([]bool, n)
+	labeled := make([]bool, n)
+	for _, e := range jg.Edges {
+		if e.From < 0 || int(e.From) >= n {
+			return fmt.Errorf("edge from undefined state %d", e.From)
+		}
+		if e.Prob != nil {
+			probabilistic[e.From] = true
+		}
+		if e.Action != "" || e.Guard != "" || e.Process != "" {
+			labeled[e.From] = true
+		}
+	}
+	for _, e := range jg.Edges {
+		d.Succ[e.From] = append(d.Succ[e.From], e.To)
+		if probabilistic[e.From] {
+			p := 0.0
+			if e.Prob != nil {
+				p = *e.Prob
+			}
+			d.Probs[e.From] = append(d.Probs[e.From], p)
+		}
+		if labeled[e.From] {
+			d.EdgeLabels[e.From] = append(d.EdgeLabels[e.From], EdgeLabel{Action: e.Action, Guard: e.Guard, Process: e.Process})
+		}
+	}
+	ng, err := graphFromData(d)
+	if err != nil {
+		return err
+	}
+	*g = *ng
+	return nil
+}
+
+// WriteJSON writes g as indented JSON.
+func WriteJSON(w io.Writer, g *Graph) error {
+	b, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadJSON reads a graph written by WriteJSON.
+func ReadJSON(r io.Reader) (*Graph, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	g := NewGraph()
+	if err := g.UnmarshalJSON(b); err != nil {
+		return nil, fmt.Errorf("ReadJSON: %w", err)
+	}
+	return g, nil
+}
+
+// ---------- binary ----------
+
+// The binary form is compact and byte-for-byte deterministic:
+//
+//	"KRPG" version:byte
+//	nStates:uvarint
+//	  per state: name:str nLabels:uvarint (key:str value:byte)*
+//	             nVars:uvarint (key:str type:byte value)*
+//	  per state: nEdges:uvarint hasProb:byte hasLabels:byte
+//	             (to:uvarint [prob:float64] [action:str guard:str process:str])*
+//	nInit:uvarint id:uvarint*
+//
+// str is a uvarint length followed by bytes; labels and variables are
+// sorted by key; variable types are 'i' (varint), 's' (str) and 'b' (byte).
+const (
+	binaryMagic   = "KRPG"
+	binaryVersion = 1
+)
+
+var errBinaryFormat = errors.New("not a kripke binary graph")
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (g *Graph) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing g's contents.
+func (g *Graph) UnmarshalBinary(b []byte) error {
+	ng, err := ReadBinary(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	*g = *ng
+	return nil
+}
+
+type binWriter struct {
+	w   *bufio.Writer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (bw *binWriter) uvarint(v uint64) {
+	bw.w.Write(bw.tmp[:binary.PutUvarint(bw.tmp[:], v)])
+}
+
+func (bw *binWriter) str(s string) {
+	bw.uvarint(uint64(len(s)))
+	bw.w.WriteString(s)
+}
+
+func (bw *binWriter) flag(b bool) {
+	if b {
+		bw.w.WriteByte(1)
+	} else {
+		bw.w.WriteByte(0)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteBinary writes g in the native binary format.
+func WriteBinary(w io.Writer, g *Graph) error {
+	d := g.data()
+	bw := &binWriter{w: bufio.NewWriter(w)}
+	bw.w.WriteString(binaryMagic)
+	bw.w.WriteByte(binaryVersion)
+	bw.uvarint(uint64(len(d.Names)))
+	for i, name := range d.Names {
+		bw.str(name)
+		bw.uvarint(uint64(len(d.Labels[i])))
+		for _, k := range sortedKeys(d.Labels[i]) {
+			bw.str(k)
+			if d.Labels[i][k] {
+				bw.w.WriteByte(1)
+			} else {
+				bw.w.WriteByte(0)
+			}
+		}
+		bw.uvarint(uint64(len(d.Vars[i])))
+		for _, k := range sortedKeys(d.Vars[i]) {
+			bw.str(k)
+			switch v := d.Vars[i][k].(type) {
+			case int:
+				bw.w.WriteByte('i')
+				bw.w.Write(bw.tmp[:binary.PutVarint(bw.tmp[:], int64(v))])
+			case string:
+				bw.w.WriteByte('s')
+				bw.str(v)
+			case bool:
+				bw.w.WriteByte('b')
+				if v {
+					bw.w.WriteByte(1)
+				} else {
+					bw.w.WriteByte(0)
+				}
+			}
+		}
+	}
+	for i := range d.Names {
+		bw.uvarint(uint64(len(d.Succ[i])))
+		bw.flag(d.Probs[i] != nil)
+		bw.flag(d.EdgeLabels[i] != nil)
+		for j, t := range d.Succ[i] {
+			bw.uvarint(uint64(t))
+			if d.Probs[i] != nil {
+				var fb [8]byte
+				binary.LittleEndian.PutUint64(fb[:], math.Float64bits(d.Probs[i][j]))
+				bw.w.Write(fb[:])
+			}
+			if d.EdgeLabels[i] != nil {
+				l := d.EdgeLabels[i][j]
+				bw.str(l.Action)
+				bw.str(l.Guard)
+				bw.str(l.Process)
+			}
+		}
+	}
+	bw.uvarint(uint64(len(d.Init)))
+	for _, id := range d.Init {
+		bw.uvarint(uint64(id))
+	}
+	return bw.w.Flush()
+}
+
+type binReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (br *binReader) uvarint() uint64 {
+	if br.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(br.r)
+	br.err = err
+	return v
+}
+
+// count reads a length and rejects ones that can't fit in the input.
+func (br *binReader) count() int {
+	v := br.uvarint()
+	if v > math.MaxInt32 {
+		br.err = errBinaryFormat
+		return 0
+	}
+	return int(v)
+}
+
+func (br *binReader) byte() byte {
+	if br.err != nil {
+		return 0
+	}
+	b, err := br.r.ReadByte()
+	br.err = err
+	return b
+}
+
+func (br *binReader) str() string {
+	n := br.count()
+	if br.err != nil {
+		return ""
+	}
+	b := make([]byte, 0, min(n, 4096))
+	for len(b) < n && br.err == nil {
+		chunk := make([]byte, min(n-len(b), 4096))
+		_, br.err = io.ReadFull(br.r, chunk)
+		b = append(b, chunk...)
+	}
+	return string(b)
+}
+
+// ReadBinary reads a graph written by WriteBinary.
+func ReadBinary(r io.Reader) (*Graph, error) {
+	br := &binReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(br.r, magic); err != nil || string(magic) != binaryMagic {
+		return nil, fmt.Errorf("ReadBinary: %w", errBinaryFormat)
+	}
+	version := br.byte()
+	if br.err == nil && version != binaryVersion {
+		return nil, fmt.Errorf("ReadBinary: unsupported version %d", version)
+	}
+
+	var d graphData
+	n := br.count()
+	for i := 0; i < n && br.err == nil; i++ {
+		d.Names = append(d.Names, br.str())
+		var lbls map[string]bool
+		if nl := br.count(); nl > 0 {
+			lbls = make(map[string]bool)
+			for j := 0; j < nl && br.err == nil; j++ {
+				k := br.str()
+				lbls[k] = br.byte() != 0
+			}
+		}
+		d.Labels = append(d.Labels, lbls)
+		var vars map[string]any
+		if nv := br.count(); nv > 0 {
+			vars = make(map[string]any)
+			for j := 0; j < nv && br.err == nil; j++ {
+				k := br.str()
+				switch br.byte() {
+				case 'i':
+					v, err := binary.ReadVarint(br.r)
+					if br.err == nil {
+						br.err = err
+					}
+					vars[k] = int(v)
+				case 's':
+					vars[k] = br.str()
+				case 'b':
+					vars[k] = br.byte() != 0
+				default:
+					if br.err == nil {
+						br.err = errBinaryFormat
+					}
+				}
+			}
+		}
+		d.Vars = append(d.Vars, vars)
+	}
+	for i := 0; i < len(d.Names) && br.err == nil; i++ {
+		ne := br.count()
+		hasProb := br.byte() != 0
+		hasLabels := br.byte() != 0
+		var succ []StateID
+		var probs []float64
+		var labels []EdgeLabel
+		if hasProb {
+			probs = []float64{}
+		}
+		for j := 0; j < ne && br.err == nil; j++ {
+			succ = append(succ, StateID(br.count()))
+			if hasProb {
+				var fb [8]byte
+				if _, err := io.ReadFull(br.r, fb[:]); err != nil {
+					br.err = err
+				}
+				probs = append(probs, math.Float64frombits(binary.LittleEndian.Uint64(fb[:])))
+			}
+			if hasLabels {
+				labels = append(labels, EdgeLabel{Action: br.str(), Guard: br.str(), Process: br.str()})
+			}
+		}
+		d.Succ = append(d.Succ, succ)
+		d.Probs = append(d.Probs, probs)
+		d.EdgeLabels = append(d.EdgeLabels, labels)
+	}
+	ni := br.count()
+	for i := 0; i < ni && br.err == nil; i++ {
+		d.Init = append(d.Init, StateID(br.count()))
+	}
+	if br.err != nil {
+		if br.err == io.EOF {
+			br.err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("ReadBinary: %w", br.err)
+	}
+	g, err := graphFromData(d)
+	if err != nil {
+		return nil, fmt.Errorf("ReadBinary: %w", err)
+	}
+	return g, nil
+}
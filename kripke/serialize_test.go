@@ -0,0 +1,136 @@
+package kripke
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// richGraph has labels, typed variables, probabilities, edge labels, a
+// duplicate edge, awkward names and two initial states.
+func richGraph() *Graph {
+	g := NewGraph()
+	a := g.AddState("a", map[string]bool{"p": true, "q": false, "x=1,y": true})
+	g.AddState(`b "quoted"\n`, map[string]bool{"q": true})
+	c := g.AddState("c d", nil)
+	g.SetVars(a, map[string]any{"n": -3, "mode": "run", "ok": true})
+	g.SetVars(c, map[string]any{"n": 7})
+	g.AddEdgeProb("a", `b "quoted"\n`, 0.25)
+	g.AddLabeledEdge("a", "c d", EdgeLabel{Action: "go", Prob: 0.75})
+	g.AddEdge(`b "quoted"\n`, "c d")
+	g.AddEdge(`b "quoted"\n`, "c d")
+	g.AddEdge("c d", "c d")
+	g.AddLabeledEdge("c d", "a", EdgeLabel{Action: `re "set"`, Guard: "n > 5", Process: "P"})
+	g.SetInitial("c d")
+	g.SetInitial("a")
+	return g
+}
+
+func TestSerializationRoundTrip(t *testing.T) {
+	formats := map[string]struct {
+		write func(*bytes.Buffer, *Graph) error
+		read  func(*bytes.Buffer) (*Graph, error)
+	}{
+		"json":   {func(b *bytes.Buffer, g *Graph) error { return WriteJSON(b, g) }, func(b *bytes.Buffer) (*Graph, error) { return ReadJSON(b) }},
+		"dot":    {func(b *bytes.Buffer, g *Graph) error { return WriteDOT(b, g) }, func(b *bytes.Buffer) (*Graph, error) { return ReadDOT(b) }},
+		"binary": {func(b *bytes.Buffer, g *Graph) error { return WriteBinary(b, g) }, func(b *bytes.Buffer) (*Graph, error) { return ReadBinary(b) }},
+	}
+	for _, g := range []*Graph{richGraph(), OrderGraph(), NewGraph()} {
+		want := g.data()
+		for name, f := range formats {
+			var buf bytes.Buffer
+			if err := f.write(&buf, g); err != nil {
+				t.Fatalf("%s: write: %v", name, err)
+			}
+			text := buf.String()
+			got, err := f.read(&buf)
+			if err != nil {
+				t.Fatalf("%s: read: %v\n%s", name, err, text)
+			}
+			if !reflect.DeepEqual(normalizeData(got.data()), normalizeData(want)) {
+				t.Fatalf("%s: round trip mismatch\n got %+v\nwant %+v\n%s", name, got.data(), want, text)
+			}
+		}
+	}
+}
+
+// normalizeData maps empty label/var maps to nil, which the formats do
+// not distinguish.
+func normalizeData(d graphData) graphData {
+	for i := range d.Names {
+		if len(d.Labels[i]) == 0 {
+			d.Labels[i] = nil
+		}
+		if len(d.Vars[i]) == 0 {
+			d.Vars[i] = nil
+		}
+		if len(d.Succ[i]) == 0 {
+			d.Succ[i] = nil
+		}
+		if len(d.EdgeLabels[i]) == 0 {
+			d.EdgeLabels[i] = nil
+		}
+	}
+	return d
+}
+
+func TestBinaryIsCompactAndDeterministic(t *testing.T) {
+	g, err := Explore(producerConsumerWorld(20, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := g.MarshalBinary()
+	b, _ := g.MarshalBinary()
+	j, _ := g.MarshalJSON()
+	if !bytes.Equal(a, b) {
+		t.Fatalf("binary encoding is not deterministic")
+	}
+	if len(a)*2 > len(j) {
+		t.Fatalf("binary (%d bytes) is not much smaller than JSON (%d bytes)", len(a), len(j))
+	}
+}
+
+func TestReadRejectsBadInput(t *testing.T) {
+	bad := []struct{ name, src string }{
+		{"json", `{"states":[{"name":"a"}],"edges":[{"from":0,"to":5}]}`},
+		{"json", `{"states":[{"name":"a"},{"name":"a"}]}`},
+		{"json", `{"states":[{"name":"a","vars":{"x":1.5}}]}`},
+		{"dot", `digraph { a -> b [prob=x]; }`},
+		{"dot", `digraph { a [kripke_labels="p"]; }`},
+		{"binary", "KRPG\x01\x05"},
+		{"binary", "KRPG\x02\x00\x00"},
+		{"binary", "nope"},
+	}
+	for _, c := range bad {
+		var err error
+		switch c.name {
+		case "json":
+			_, err = ReadJSON(strings.NewReader(c.src))
+		case "dot":
+			_, err = ReadDOT(strings.NewReader(c.src))
+		case "binary":
+			_, err = ReadBinary(strings.NewReader(c.src))
+		}
+		if err == nil {
+			t.Fatalf("%s: expected an error for %q", c.name, c.src)
+		}
+	}
+}
+
+func TestReadPlainDOT(t *testing.T) {
+	src := `strict digraph G {
+		// a comment
+		node [shape=box]
+		subgraph cluster_0 { a; b }
+		a -> b -> c [label="go"];
+		c -> a
+	}`
+	g, err := ReadDOT(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.States()) != 3 || len(g.Succ(g.nameToID["a"])) != 1 || g.NameOf(g.Succ(g.nameToID["b"])[0]) != "c" {
+		t.Fatalf("unexpected graph %+v", g.data())
+	}
+}
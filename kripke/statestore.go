@@ -0,0 +1,316 @@
+package kripke
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// StateStore is the visited set used by the explorer: it maps the canonical
+// StateKey of each World seen so far to the StateID it was given.
+//
+// Implementations only need to support insert-once semantics; the explorer
+// never inserts a key twice.
+type StateStore interface {
+	// Lookup returns the StateID recorded for key, if any.
+	Lookup(key string) (StateID, bool, error)
+	// Insert records key as id.
+	Insert(key string, id StateID) error
+	// Len returns the number of keys stored.
+	Len() int
+	// Close flushes and releases any underlying resources.
+	Close() error
+}
+
+// PersistentStore is a StateStore whose contents outlive the process.
+// Checkpoints only flush it rather than copying every key.
+type PersistentStore interface {
+	StateStore
+	// Sync flushes all inserted keys to stable storage.
+	Sync() error
+}
+
+// ---------- MemoryStore ----------
+
+// MemoryStore is a StateStore backed by a Go map.
+type MemoryStore struct {
+	ids map[string]StateID
+}
+
+// NewMemoryStore constructs an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{ids: make(map[string]StateID)}
+}
+
+func (m *MemoryStore) Lookup(key string) (StateID, bool, error) {
+	id, ok := m.ids[key]
+	return id, ok, nil
+}
+
+func (m *MemoryStore) Insert(key string, id StateID) error {
+	m.ids[key] = id
+	return nil
+}
+
+// Each calls fn for every stored key, stopping at the first error.
+func (m *MemoryStore) Each(fn func(key string, id StateID) error) error {
+	for k, id := range m.ids {
+		if err := fn(k, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Len() int     { return len(m.ids) }
+func (m *MemoryStore) Close() error { return nil }
+
+// ---------- DiskStore ----------
+
+// DiskStore is a StateStore kept entirely on disk, for visited sets that do
+// not fit in memory. It is an open-addressing hash table of fixed-size slots
+// (index.tbl) pointing into an append-only log of keys (keys.log):
+//
+//	slot = hash uint64 | id+1 uint64 | key offset uint64   (0 id = empty)
+//
+// Lookups probe linearly from hash%capacity and compare the full key on a
+// hash match, so there are no false positives. The table doubles when it is
+// half full. Both files live in one directory, and reopening that directory
+// with OpenDiskStore picks up where the previous run stopped.
+type DiskStore struct {
+	dir   string
+	index *os.File
+	keys  *os.File
+	cap   uint64
+	count uint64
+	keyAt int64
+}
+
+const (
+	diskStoreMagic    = "KRIPKEVS"
+	diskStoreHeader   = 24 // magic + capacity + count
+	diskStoreSlot     = 24
+	diskStoreInitSize = 1024
+)
+
+// OpenDiskStore opens (or creates) a disk-backed store in dir.
+func OpenDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	keys, err := os.OpenFile(filepath.Join(dir, "keys.log"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(dir, "index.tbl"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		keys.Close()
+		return nil, err
+	}
+	d := &DiskStore{dir: dir, index: index, keys: keys}
+	if err := d.load(); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *DiskStore) load() error {
+	st, err := d.keys.Stat()
+	if err != nil {
+		return err
+	}
+	d.keyAt = st.Size()
+
+	hdr := make([]byte, diskStoreHeader)
+	n, err := d.index.ReadAt(hdr, 0)
+	if n == 0 && err == io.EOF {
+		d.cap = diskStoreInitSize
+		if err := d.index.Truncate(int64(diskStoreHeader + d.cap*diskStoreSlot)); err != nil {
+			return err
+		}
+		return d.writeHeader()
+	}
+	if err != nil {
+		return err
+	}
+	if string(hdr[:8]) != diskStoreMagic {
+		return fmt.Errorf("OpenDiskStore: %s is not a state store", d.dir)
+	}
+	d.cap = binary.LittleEndian.Uint64(hdr[8:])
+	d.count = binary.LittleEndian.Uint64(hdr[16:])
+	return nil
+}
+
+func (d *DiskStore) writeHeader() error {
+	hdr := make([]byte, diskStoreHeader)
+	copy(hdr, diskStoreMagic)
+	binary.LittleEndian.PutUint64(hdr[8:], d.cap)
+	binary.LittleEndian.PutUint64(hdr[16:], d.count)
+	_, err := d.index.WriteAt(hdr, 0)
+	return err
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// probe finds the slot holding key, or the empty slot where it belongs.
+func (d *DiskStore) probe(key string, h uint64) (slot uint64, id StateID, found bool, err error) {
+	buf := make([]byte, diskStoreSlot)
+	for i := uint64(0); i < d.cap; i++ {
+		slot = (h + i) % d.cap
+		if _, err = d.index.ReadAt(buf, int64(diskStoreHeader+slot*diskStoreSlot)); err != nil {
+			return
+		}
+		stored := binary.LittleEndian.Uint64(buf[8:])
+		if stored == 0 {
+			return slot, 0, false, nil
+		}
+		if binary.LittleEndian.Uint64(buf) != h {
+			continue
+		}
+		var k string
+		if k, err = d.readKey(int64(binary.LittleEndian.Uint64(buf[16:]))); err != nil {
+			return
+		}
+		if k == key {
+			return slot, StateID(stored - 1), true, nil
+		}
+	}
+	return 0, 0, false, errors.New("DiskStore: table full")
+}
+
+func (d *DiskStore) readKey(off int64) (string, error) {
+	var lenBuf [4]byte
+	if _, err := d.keys.ReadAt(lenBuf[:], off); err != nil {
+		return "", err
+	}
+	b := make([]byte, binary.LittleEndian.Uint32(lenBuf[:]))
+	if _, err := d.keys.ReadAt(b, off+4); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (d *DiskStore) writeSlot(slot, h uint64, id StateID, off int64) error {
+	buf := make([]byte, diskStoreSlot)
+	binary.LittleEndian.PutUint64(buf, h)
+	binary.LittleEndian.PutUint64(buf[8:], uint64(id)+1)
+	binary.LittleEndian.PutUint64(buf[16:], uint64(off))
+	_, err := d.index.WriteAt(buf, int64(diskStoreHeader+slot*diskStoreSlot))
+	return err
+}
+
+func (d *DiskStore) Lookup(key string) (StateID, bool, error) {
+	_, id, found, err := d.probe(key, hashKey(key))
+	return id, found, err
+}
+
+func (d *DiskStore) Insert(key string, id StateID) error {
+	if (d.count+1)*2 > d.cap {
+		if err := d.grow(); err != nil {
+			return err
+		}
+	}
+	h := hashKey(key)
+	slot, _, found, err := d.probe(key, h)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("DiskStore: duplicate key for state %d", id)
+	}
+
+	off := d.keyAt
+	rec := make([]byte, 4+len(key))
+	binary.LittleEndian.PutUint32(rec, uint32(len(key)))
+	copy(rec[4:], key)
+	if _, err := d.keys.WriteAt(rec, off); err != nil {
+		return err
+	}
+	d.keyAt += int64(len(rec))
+
+	if err := d.writeSlot(slot, h, id, off); err != nil {
+		return err
+	}
+	d.count++
+	return d.writeHeader()
+}
+
+// grow rehashes every slot into a table of twice the capacity. Keys never
+// move, so only the index is rewritten.
+func (d *DiskStore) grow() error {
+	oldCap := d.cap
+	oldPath := filepath.Join(d.dir, "index.tbl")
+	tmpPath := oldPath + ".grow"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	old := d.index
+	d.index = tmp
+	d.cap = oldCap * 2
+	if err := tmp.Truncate(int64(diskStoreHeader + d.cap*diskStoreSlot)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, diskStoreSlot)
+	for s := uint64(0); s < oldCap; s++ {
+		if _, err := old.ReadAt(buf, int64(diskStoreHeader+s*diskStoreSlot)); err != nil {
+			return err
+		}
+		stored := binary.LittleEndian.Uint64(buf[8:])
+		if stored == 0 {
+			continue
+		}
+		h := binary.LittleEndian.Uint64(buf)
+		for i := uint64(0); ; i++ {
+			slot := (h + i) % d.cap
+			var probe [diskStoreSlot]byte
+			if _, err := tmp.ReadAt(probe[:], int64(diskStoreHeader+slot*diskStoreSlot)); err != nil {
+				return err
+			}
+			if binary.LittleEndian.Uint64(probe[8:]) == 0 {
+				if _, err := tmp.WriteAt(buf, int64(diskStoreHeader+slot*diskStoreSlot)); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	if err := d.writeHeader(); err != nil {
+		return err
+	}
+	old.Close()
+	return os.Rename(tmpPath, oldPath)
+}
+
+func (d *DiskStore) Len() int { return int(d.count) }
+
+// Sync flushes both files to stable storage.
+func (d *DiskStore) Sync() error {
+	if err := d.keys.Sync(); err != nil {
+		return err
+	}
+	return d.index.Sync()
+}
+
+func (d *DiskStore) Close() error {
+	var first error
+	for _, f := range []*os.File{d.keys, d.index} {
+		if f == nil {
+			continue
+		}
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
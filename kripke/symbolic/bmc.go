@@ -0,0 +1,476 @@
+package symbolic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+	"github.com/rfielding/kripke-ctl/kripke/bdd"
+	"github.com/rfielding/kripke-ctl/kripke/sat"
+)
+
+// ---------- Bounded model checking ----------
+
+// BMCOption configures BMC.
+type BMCOption func(*bmcOptions)
+
+type bmcOptions struct {
+	solver sat.Solver
+}
+
+// WithSolver makes BMC use s instead of the built-in sat.CDCL, e.g. a
+// sat.Command running an external DIMACS solver.
+func WithSolver(s sat.Solver) BMCOption {
+	return func(o *bmcOptions) { o.solver = s }
+}
+
+// Trace is a counterexample found by BMC: a path of States, where
+// Labels[i] is the edge from States[i] to the next state. When Loop is
+// not -1 the path is a lasso: its last label leads from the last state
+// back to States[Loop], and the run repeats from there forever.
+type Trace struct {
+	States []string
+	Values []map[string]int // variable values, 1 and 0 for booleans
+	Labels []kripke.EdgeLabel
+	Loop   int
+}
+
+// String renders the trace as "a -act-> b -act-> c", followed by the
+// state the loop returns to.
+func (t *Trace) String() string {
+	var b strings.Builder
+	for i, s := range t.States {
+		if i > 0 {
+			fmt.Fprintf(&b, " -%s-> ", t.Labels[i-1])
+		}
+		b.WriteString(s)
+	}
+	if t.Loop >= 0 {
+		fmt.Fprintf(&b, " -%s-> %s (loop)", t.Labels[len(t.Labels)-1], t.States[t.Loop])
+	}
+	return b.String()
+}
+
+// BMC searches for a counterexample to the property f of at most bound
+// steps by unrolling the transition relation into a SAT problem, one
+// depth at a time, so the trace it returns is a shortest one. It returns
+// nil when there is none within the bound, which proves nothing about
+// longer paths.
+//
+// f is an LTL property: A(π) for a path formula π over propositional
+// state formulas, or a universal CTL formula that is one, built from
+// propositional formulas with AG, AX, AF of a propositional formula, &,
+// and | or -> with a propositional side. Counterexamples to safety
+// properties are finite paths; to liveness properties, lassos. As in CTL,
+// a finite path may end in a deadlock, except when f contains A(π):
+// CTL* quantifies over infinite paths only, so there the path must be
+// one that can go on forever.
+func (m *Model) BMC(f kripke.Formula, bound int, opts ...BMCOption) (*Trace, error) {
+	o := bmcOptions{solver: sat.CDCL{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	m.build()
+	path, err := m.ltlProperty(f)
+	if err != nil {
+		return nil, err
+	}
+	// A counterexample is a path satisfying the negation.
+	goal, err := m.nnf(path, true)
+	if err != nil {
+		return nil, err
+	}
+	// Where a finite counterexample may end.
+	end := bdd.True
+	if containsA(f) {
+		end = m.infinite()
+	}
+	for k := 0; k <= bound; k++ {
+		u := m.unroll(k)
+		finite := u.cnf.And(u.translate(goal, 0, -1), u.bddLit(end, k, k))
+		alts := []sat.Lit{finite}
+		for l := 0; l <= k; l++ {
+			alts = append(alts, u.cnf.And(u.bddLit(m.trans, k, l), u.translate(goal, 0, l)))
+		}
+		u.cnf.Add(u.cnf.Or(alts...))
+		res, err := o.solver.Solve(u.cnf)
+		if err != nil {
+			return nil, err
+		}
+		if res.Sat {
+			return u.trace(res, finite, goal), nil
+		}
+	}
+	return nil, nil
+}
+
+// ltlProperty turns a property into the path formula all paths must
+// satisfy.
+func (m *Model) ltlProperty(f kripke.Formula) (kripke.PathFormula, error) {
+	if propositional(f) {
+		return kripke.Now(f), nil
+	}
+	unsupported := fmt.Errorf("symbolic: BMC needs an LTL property, not %s", kripke.Explain(f))
+	switch f := f.(type) {
+	case kripke.AFormula:
+		return f.Path, nil
+	case kripke.AGFormula:
+		p, err := m.ltlProperty(f.Inner)
+		return kripke.Globally(p), err
+	case kripke.AXFormula:
+		p, err := m.ltlProperty(f.Inner)
+		return kripke.Next(p), err
+	case kripke.AFFormula:
+		if !propositional(f.Inner) {
+			return nil, unsupported
+		}
+		return kripke.Eventually(kripke.Now(f.Inner)), nil
+	case kripke.AndFormula:
+		l, err := m.ltlProperty(f.Left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := m.ltlProperty(f.Right)
+		return kripke.AndPath(l, r), err
+	case kripke.OrFormula:
+		// A(p ∨ π) = p ∨ A π only for a state formula p.
+		l, r := f.Left, f.Right
+		if !propositional(l) {
+			l, r = r, l
+		}
+		if !propositional(l) {
+			return nil, unsupported
+		}
+		p, err := m.ltlProperty(r)
+		return kripke.OrPath(kripke.Now(l), p), err
+	}
+	return nil, unsupported
+}
+
+// containsA reports whether a property accepted by ltlProperty uses A(π).
+func containsA(f kripke.Formula) bool {
+	switch f := f.(type) {
+	case kripke.AFormula:
+		return true
+	case kripke.AGFormula:
+		return containsA(f.Inner)
+	case kripke.AXFormula:
+		return containsA(f.Inner)
+	case kripke.AndFormula:
+		return containsA(f.Left) || containsA(f.Right)
+	case kripke.OrFormula:
+		return containsA(f.Left) || containsA(f.Right)
+	}
+	return false
+}
+
+// infinite is the set of states with an infinite path, EG true.
+func (m *Model) infinite() bdd.Node {
+	return gfp(m.valid, func(z bdd.Node) bdd.Node { return m.mgr.And(m.valid, m.pre(m.trans, z)) })
+}
+
+// propositional reports whether f is about the current state only.
+func propositional(f kripke.Formula) bool {
+	switch f := f.(type) {
+	case kripke.TrueFormula, kripke.AtomFormula, kripke.CompareFormula:
+		return true
+	case kripke.NotFormula:
+		return propositional(f.Inner)
+	case kripke.AndFormula:
+		return propositional(f.Left) && propositional(f.Right)
+	case kripke.OrFormula:
+		return propositional(f.Left) && propositional(f.Right)
+	}
+	return false
+}
+
+type ltlKind int
+
+const (
+	ltlProp ltlKind = iota
+	ltlAnd
+	ltlOr
+	ltlNext
+	ltlUntil
+	ltlRelease
+)
+
+// ltl is a path formula in negation normal form with its state formulas
+// evaluated to sets.
+type ltl struct {
+	id   int
+	kind ltlKind
+	set  bdd.Node
+	l, r *ltl
+}
+
+// nnf pushes negations in π, negated when neg, down to the state
+// formulas.
+func (m *Model) nnf(p kripke.PathFormula, neg bool) (*ltl, error) {
+	count := 0
+	var rec func(p kripke.PathFormula, neg bool) (*ltl, error)
+	node := func(kind ltlKind, l, r *ltl) *ltl {
+		count++
+		return &ltl{id: count, kind: kind, l: l, r: r}
+	}
+	binary := func(kind ltlKind, l, r kripke.PathFormula, neg bool) (*ltl, error) {
+		x, err := rec(l, neg)
+		if err != nil {
+			return nil, err
+		}
+		y, err := rec(r, neg)
+		if err != nil {
+			return nil, err
+		}
+		return node(kind, x, y), nil
+	}
+	rec = func(p kripke.PathFormula, neg bool) (*ltl, error) {
+		switch p := p.(type) {
+		case kripke.PathState:
+			if !propositional(p.State) {
+				return nil, fmt.Errorf("symbolic: BMC needs propositional state formulas, not %s", kripke.Explain(p.State))
+			}
+			set, err := m.eval(kripke.Normalize(p.State), nil)
+			if err != nil {
+				return nil, err
+			}
+			if neg {
+				set = m.mgr.AndNot(m.valid, set)
+			}
+			n := node(ltlProp, nil, nil)
+			n.set = set
+			return n, nil
+		case kripke.PathNot:
+			return rec(p.Inner, !neg)
+		case kripke.PathAnd:
+			if neg {
+				return binary(ltlOr, p.Left, p.Right, true)
+			}
+			return binary(ltlAnd, p.Left, p.Right, false)
+		case kripke.PathOr:
+			if neg {
+				return binary(ltlAnd, p.Left, p.Right, true)
+			}
+			return binary(ltlOr, p.Left, p.Right, false)
+		case kripke.PathNext:
+			x, err := rec(p.Inner, neg)
+			if err != nil {
+				return nil, err
+			}
+			return node(ltlNext, x, nil), nil
+		case kripke.PathUntil:
+			// ¬(φ U ψ) = ¬φ R ¬ψ.
+			if neg {
+				return binary(ltlRelease, p.Left, p.Right, true)
+			}
+			return binary(ltlUntil, p.Left, p.Right, false)
+		}
+		return nil, fmt.Errorf("symbolic: unknown path formula %T", p)
+	}
+	return rec(p, neg)
+}
+
+// unrolling is the SAT encoding of paths of k steps: one set of variables
+// per state, the initial condition on the first and the transition
+// relation between neighbours.
+type unrolling struct {
+	m      *Model
+	k      int
+	cnf    *sat.CNF
+	frames [][]sat.Lit // frames[i][b] is logical bit b of state i
+	nodes  map[[3]int]sat.Lit
+	memo   map[[3]int]sat.Lit
+}
+
+func (m *Model) unroll(k int) *unrolling {
+	u := m.newUnrolling(&sat.CNF{}, k)
+	u.cnf.Add(u.bddLit(m.Init(), 0, 0))
+	return u
+}
+
+// newUnrolling encodes k steps of the transition relation into cnf, from
+// any state.
+func (m *Model) newUnrolling(cnf *sat.CNF, k int) *unrolling {
+	u := &unrolling{
+		m:     m,
+		k:     k,
+		cnf:   cnf,
+		nodes: make(map[[3]int]sat.Lit),
+		memo:  make(map[[3]int]sat.Lit),
+	}
+	for i := 0; i <= k; i++ {
+		bits := make([]sat.Lit, m.nbits)
+		for b := range bits {
+			bits[b] = u.cnf.NewVar()
+		}
+		u.frames = append(u.frames, bits)
+	}
+	for i := 0; i < k; i++ {
+		u.cnf.Add(u.bddLit(m.trans, i, i+1))
+	}
+	return u
+}
+
+// states decodes the frames of a model.
+func (u *unrolling) states(value func(sat.Lit) bool) [][]bool {
+	states := make([][]bool, len(u.frames))
+	for i, frame := range u.frames {
+		states[i] = make([]bool, len(frame))
+		for b, l := range frame {
+			states[i][b] = value(l)
+		}
+	}
+	return states
+}
+
+// bddLit encodes f with its current bits in state i and next bits in
+// state j, one if-then-else gate per BDD node.
+func (u *unrolling) bddLit(f bdd.Node, i, j int) sat.Lit {
+	switch f {
+	case bdd.True:
+		return u.cnf.True()
+	case bdd.False:
+		return u.cnf.False()
+	}
+	key := [3]int{int(f), i, j}
+	if l, ok := u.nodes[key]; ok {
+		return l
+	}
+	v, lo, hi := u.m.mgr.Inspect(f)
+	x := u.frames[i][v/2]
+	if v%2 == 1 {
+		x = u.frames[j][v/2]
+	}
+	l := u.cnf.Ite(x, u.bddLit(hi, i, j), u.bddLit(lo, i, j))
+	u.nodes[key] = l
+	return l
+}
+
+// translate encodes that the path from state i satisfies p: a path of
+// k+1 states when loop is -1, and otherwise the infinite path whose last
+// state steps back to state loop (Biere, Cimatti, Clarke and Zhu,
+// "Symbolic model checking without BDDs", 1999).
+func (u *unrolling) translate(p *ltl, i, loop int) sat.Lit {
+	key := [3]int{p.id, i, loop}
+	if l, ok := u.memo[key]; ok {
+		return l
+	}
+	c, k := u.cnf, u.k
+	at := func(p *ltl, j int) sat.Lit { return u.translate(p, j, loop) }
+	// all is the conjunction of p over states from..to.
+	all := func(p *ltl, from, to int) []sat.Lit {
+		var ls []sat.Lit
+		for n := from; n <= to; n++ {
+			ls = append(ls, at(p, n))
+		}
+		return ls
+	}
+	var l sat.Lit
+	switch p.kind {
+	case ltlProp:
+		l = u.bddLit(p.set, i, i)
+	case ltlAnd:
+		l = c.And(at(p.l, i), at(p.r, i))
+	case ltlOr:
+		l = c.Or(at(p.l, i), at(p.r, i))
+	case ltlNext:
+		switch {
+		case i < k:
+			l = at(p.l, i+1)
+		case loop >= 0:
+			l = at(p.l, loop)
+		default:
+			l = c.False()
+		}
+	case ltlUntil:
+		// ψ at some j ahead, φ before it; on a lasso j may also lie
+		// between the loop state and i, reached by going round.
+		var alts []sat.Lit
+		for j := i; j <= k; j++ {
+			alts = append(alts, c.And(append(all(p.l, i, j-1), at(p.r, j))...))
+		}
+		for j := loop; loop >= 0 && j < i; j++ {
+			ls := append(all(p.l, i, k), all(p.l, loop, j-1)...)
+			alts = append(alts, c.And(append(ls, at(p.r, j))...))
+		}
+		l = c.Or(alts...)
+	case ltlRelease:
+		// ψ R φ: φ up to and including a j where ψ holds, or, on a lasso,
+		// φ forever.
+		var alts []sat.Lit
+		if loop >= 0 {
+			alts = append(alts, c.And(all(p.r, min(i, loop), k)...))
+		}
+		for j := i; j <= k; j++ {
+			alts = append(alts, c.And(append(all(p.r, i, j), at(p.l, j))...))
+		}
+		for j := loop; loop >= 0 && j < i; j++ {
+			ls := append(all(p.r, i, k), all(p.r, loop, j)...)
+			alts = append(alts, c.And(append(ls, at(p.l, j))...))
+		}
+		l = c.Or(alts...)
+	}
+	u.memo[key] = l
+	return l
+}
+
+// trace decodes a satisfying assignment; finite is the literal saying the
+// path is a counterexample without a loop.
+func (u *unrolling) trace(res sat.Result, finite sat.Lit, goal *ltl) *Trace {
+	m := u.m
+	loop := -1
+	// Prefer the finite reading when it is a counterexample on its own.
+	if !res.Value(finite) {
+		for l := 0; l <= u.k; l++ {
+			if res.Value(u.bddLit(m.trans, u.k, l)) && res.Value(u.translate(goal, 0, l)) {
+				loop = l
+				break
+			}
+		}
+	}
+	return m.newTrace(u.states(res.Value), loop)
+}
+
+// newTrace builds a trace from states given as logical bits.
+func (m *Model) newTrace(states [][]bool, loop int) *Trace {
+	t := &Trace{Loop: loop}
+	for _, bits := range states {
+		values := make(map[string]int, len(m.vars))
+		for _, v := range m.vars {
+			off := 0
+			for n, b := range v.bits {
+				if bits[b] {
+					off |= 1 << n
+				}
+			}
+			values[v.name] = v.min + off
+		}
+		t.Values = append(t.Values, values)
+		if m.graph != nil {
+			t.States = append(t.States, m.graph.NameOf(m.ids[values["state"]]))
+		} else {
+			t.States = append(t.States, m.stateName(values))
+		}
+	}
+	step := func(from, to []bool) kripke.EdgeLabel {
+		assign := func(v int) bool {
+			if v%2 == 1 {
+				return to[v/2]
+			}
+			return from[v/2]
+		}
+		for _, r := range m.rules {
+			if m.mgr.Eval(r.rel, assign) {
+				return r.label
+			}
+		}
+		return kripke.EdgeLabel{}
+	}
+	for i := 1; i < len(states); i++ {
+		t.Labels = append(t.Labels, step(states[i-1], states[i]))
+	}
+	if loop >= 0 {
+		t.Labels = append(t.Labels, step(states[len(states)-1], states[loop]))
+	}
+	return t
+}
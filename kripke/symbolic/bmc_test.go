@@ -0,0 +1,158 @@
+package symbolic
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+	"github.com/rfielding/kripke-ctl/kripke/sat"
+)
+
+// randomPath builds a random LTL formula over p and q.
+func randomPath(rng *rand.Rand, depth int) kripke.PathFormula {
+	if depth == 0 {
+		return kripke.Now(kripke.Atom([]string{"p", "q"}[rng.Intn(2)]))
+	}
+	sub := func() kripke.PathFormula { return randomPath(rng, depth-1) }
+	switch rng.Intn(7) {
+	case 0:
+		return kripke.NotPath(sub())
+	case 1:
+		return kripke.AndPath(sub(), sub())
+	case 2:
+		return kripke.OrPath(sub(), sub())
+	case 3:
+		return kripke.Next(sub())
+	case 4:
+		return kripke.Until(sub(), sub())
+	case 5:
+		return kripke.Eventually(sub())
+	}
+	return kripke.Globally(sub())
+}
+
+// checkTrace verifies that t is a path of g from its initial state.
+func checkTrace(t *testing.T, g *kripke.Graph, tr *Trace) {
+	t.Helper()
+	ids := make([]kripke.StateID, len(tr.States))
+	for i, name := range tr.States {
+		id, ok := g.StateByName(name)
+		if !ok {
+			t.Fatalf("trace state %s is not in the graph", name)
+		}
+		ids[i] = id
+	}
+	if ids[0] != g.InitialStates()[0] {
+		t.Fatalf("trace %v does not start in the initial state", tr)
+	}
+	edge := func(from, to kripke.StateID) bool {
+		for _, s := range g.Succ(from) {
+			if s == to {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 1; i < len(ids); i++ {
+		if !edge(ids[i-1], ids[i]) {
+			t.Fatalf("trace %v: no edge %s -> %s", tr, tr.States[i-1], tr.States[i])
+		}
+	}
+	if tr.Loop >= 0 && !edge(ids[len(ids)-1], ids[tr.Loop]) {
+		t.Fatalf("trace %v: no loop edge", tr)
+	}
+}
+
+func TestBMCMatchesExplicit(t *testing.T) {
+	rng := rand.New(rand.NewSource(46))
+	for i := 0; i < 200; i++ {
+		n := 2 + rng.Intn(6)
+		g := randomGraph(rng, n, rng.Intn(3*n))
+		m := FromGraph(g)
+		init := g.InitialStates()[0]
+
+		// Safety and liveness are decided exactly once the bound covers
+		// every state.
+		for _, f := range []kripke.Formula{
+			kripke.AG(kripke.Atom("p")),
+			kripke.AF(kripke.Atom("q")),
+			kripke.AG(kripke.Implies(kripke.Atom("p"), kripke.AF(kripke.Atom("q")))),
+		} {
+			tr, err := m.BMC(f, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			holds := f.Sat(g).Contains(init)
+			if holds != (tr == nil) {
+				t.Fatalf("%s: holds %v, trace %v", kripke.Explain(f), holds, tr)
+			}
+			if tr != nil {
+				checkTrace(t, g, tr)
+			}
+		}
+
+		// Any LTL formula: a trace is a real counterexample.
+		f := kripke.A(randomPath(rng, 3))
+		tr, err := m.BMC(f, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tr != nil {
+			checkTrace(t, g, tr)
+			if f.Sat(g).Contains(init) {
+				t.Fatalf("%s holds, yet BMC found %v", kripke.Explain(f), tr)
+			}
+		}
+	}
+}
+
+func TestBMCDeclared(t *testing.T) {
+	m := buffer()
+	tr, err := m.BMC(kripke.MustParseCTL("AG(n <= 2)"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr == nil || len(tr.States) != 4 || tr.Loop != -1 {
+		t.Fatalf("want three puts, got %v", tr)
+	}
+	if got := tr.String(); got != "n=0,busy=false -put-> n=1,busy=false -put-> n=2,busy=false -put-> n=3,busy=false" {
+		t.Fatalf("trace %q", got)
+	}
+
+	if tr, err := m.BMC(kripke.MustParseCTL("AG(busy -> AF(!busy))"), 8); err != nil || tr != nil {
+		t.Fatalf("AG(busy -> AF !busy): %v %v", tr, err)
+	}
+
+	// The buffer need never empty again: a lasso avoiding n == 0.
+	calls := 0
+	counting := solverFunc(func(c *sat.CNF) (sat.Result, error) {
+		calls++
+		return sat.CDCL{}.Solve(c)
+	})
+	tr, err = m.BMC(kripke.MustParseCTL("AG(AF(n == 0))"), 8, WithSolver(counting))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr == nil || tr.Loop < 0 || calls != len(tr.States) {
+		t.Fatalf("want a lasso found at depth %d, got %v", calls-1, tr)
+	}
+	for i := tr.Loop; i < len(tr.Values); i++ {
+		if tr.Values[i]["n"] == 0 {
+			t.Fatalf("loop of %v visits n=0", tr)
+		}
+	}
+	if !strings.HasSuffix(tr.String(), "(loop)") {
+		t.Fatalf("trace %q", tr)
+	}
+
+	for _, src := range []string{"EF(full)", "AF(AG(full))", "!AG(full)"} {
+		if _, err := m.BMC(kripke.MustParseCTL(src), 3); err == nil {
+			t.Errorf("%s accepted", src)
+		}
+	}
+}
+
+type solverFunc func(*sat.CNF) (sat.Result, error)
+
+func (f solverFunc) Solve(c *sat.CNF) (sat.Result, error) { return f(c) }
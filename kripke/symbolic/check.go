@@ -0,0 +1,359 @@
+package symbolic
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+	"github.com/rfielding/kripke-ctl/kripke/bdd"
+)
+
+// ---------- Explicit graphs ----------
+
+// FromGraph encodes g symbolically: its states become the values of one
+// integer variable, in ascending StateID order, and its edges a
+// transition relation per edge label (probabilities are dropped).
+// Propositions, comparisons and any operator the symbolic checker does
+// not implement itself are evaluated on g and encoded, so every Formula
+// that works on g works on the model; Sat maps results back to states.
+func FromGraph(g *kripke.Graph) *Model {
+	m := New()
+	m.graph = g
+	m.ids = g.States()
+	sort.Slice(m.ids, func(i, j int) bool { return m.ids[i] < m.ids[j] })
+	m.index = make(map[kripke.StateID]int, len(m.ids))
+	for i, s := range m.ids {
+		m.index[s] = i
+	}
+	state := m.declare("state", 0, max(len(m.ids)-1, 0), 0, false)
+
+	inits := kripke.NewStateSet()
+	for _, s := range g.InitialStates() {
+		inits.Add(s)
+	}
+	m.SetInit(m.statesBDD(inits))
+
+	byLabel := make(map[kripke.EdgeLabel]bdd.Node)
+	var labels []kripke.EdgeLabel
+	for e := range g.Edges() {
+		l := e.EdgeLabel
+		l.Prob = 0
+		if _, ok := byLabel[l]; !ok {
+			labels = append(labels, l)
+		}
+		step := m.mgr.And(state.Eq(m.index[e.From]), m.encode(state.v, m.index[e.To], true))
+		byLabel[l] = m.mgr.Or(byLabel[l], step)
+	}
+	for _, l := range labels {
+		m.rules = append(m.rules, &rule{label: l, rel: byLabel[l], fixed: true})
+	}
+	return m
+}
+
+// statesBDD encodes states of the source graph.
+func (m *Model) statesBDD(set kripke.StateSet) bdd.Node {
+	r := bdd.False
+	for s := range set {
+		if i, ok := m.index[s]; ok {
+			r = m.mgr.Or(r, m.encode(m.vars[0], i, false))
+		}
+	}
+	return r
+}
+
+// Sat is the set of states of the source graph satisfying f, as f.Sat(g)
+// would compute it. It fails for models not made by FromGraph.
+func (m *Model) Sat(f kripke.Formula) (kripke.StateSet, error) {
+	if m.graph == nil {
+		return nil, fmt.Errorf("symbolic: Sat needs a model made by FromGraph")
+	}
+	set, err := m.Check(f)
+	if err != nil {
+		return nil, err
+	}
+	out := kripke.NewStateSet()
+	for i, s := range m.ids {
+		if m.mgr.Eval(set, func(v int) bool { return v%2 == 0 && i>>(v/2)&1 == 1 }) {
+			out.Add(s)
+		}
+	}
+	return out, nil
+}
+
+// ---------- Checking ----------
+
+// Check returns the states satisfying f. It supports true, propositions,
+// comparisons, the boolean connectives, the CTL operators, and the
+// μ-calculus (<a>φ, [a]φ, fixpoints). On a declared model a proposition
+// is a Label or a boolean variable, a comparison may use its variables,
+// and other operators are an error; on a FromGraph model they are
+// evaluated on the graph. Fixpoint formulas must pass kripke.ValidateMu.
+func (m *Model) Check(f kripke.Formula) (bdd.Node, error) {
+	if err := kripke.ValidateMu(f); err != nil {
+		return bdd.False, fmt.Errorf("symbolic: %w", err)
+	}
+	m.build()
+	return m.eval(kripke.Normalize(f), nil)
+}
+
+// Holds reports whether every initial state satisfies f. Like
+// kripke.CheckModelSpec it is false when there are no initial states.
+func (m *Model) Holds(f kripke.Formula) (bool, error) {
+	set, err := m.Check(f)
+	if err != nil {
+		return false, err
+	}
+	init := m.Init()
+	return init != bdd.False && m.mgr.AndNot(init, set) == bdd.False, nil
+}
+
+func (m *Model) eval(f kripke.Formula, env map[string]bdd.Node) (bdd.Node, error) {
+	mgr := m.mgr
+	unary := func(inner kripke.Formula, op func(bdd.Node) bdd.Node) (bdd.Node, error) {
+		x, err := m.eval(inner, env)
+		if err != nil {
+			return bdd.False, err
+		}
+		return op(x), nil
+	}
+	binary := func(l, r kripke.Formula, op func(x, y bdd.Node) bdd.Node) (bdd.Node, error) {
+		x, err := m.eval(l, env)
+		if err != nil {
+			return bdd.False, err
+		}
+		y, err := m.eval(r, env)
+		if err != nil {
+			return bdd.False, err
+		}
+		return op(x, y), nil
+	}
+	switch f := f.(type) {
+	case kripke.TrueFormula:
+		return m.valid, nil
+	case kripke.NotFormula:
+		return unary(f.Inner, func(x bdd.Node) bdd.Node { return mgr.AndNot(m.valid, x) })
+	case kripke.AndFormula:
+		return binary(f.Left, f.Right, mgr.And)
+	case kripke.OrFormula:
+		return binary(f.Left, f.Right, mgr.Or)
+	case kripke.EXFormula:
+		return unary(f.Inner, func(x bdd.Node) bdd.Node { return m.pre(m.trans, x) })
+	case kripke.EUFormula:
+		return binary(f.Phi, f.Psi, func(phi, psi bdd.Node) bdd.Node {
+			return lfp(func(z bdd.Node) bdd.Node { return mgr.Or(psi, mgr.And(phi, m.pre(m.trans, z))) })
+		})
+	case kripke.EGFormula:
+		return unary(f.Inner, func(phi bdd.Node) bdd.Node {
+			return gfp(phi, func(z bdd.Node) bdd.Node { return mgr.And(phi, m.pre(m.trans, z)) })
+		})
+	case kripke.DiamondFormula:
+		return unary(f.Inner, func(x bdd.Node) bdd.Node { return m.diamond(f.Action, x) })
+	case kripke.BoxFormula:
+		return unary(f.Inner, func(x bdd.Node) bdd.Node {
+			return mgr.AndNot(m.valid, m.diamond(f.Action, mgr.AndNot(m.valid, x)))
+		})
+	case kripke.MuFormula:
+		return m.fixpoint(f.Var, f.Body, bdd.False, env)
+	case kripke.NuFormula:
+		return m.fixpoint(f.Var, f.Body, m.valid, env)
+	case kripke.VarFormula:
+		if x, ok := env[f.Name]; ok {
+			return x, nil
+		}
+		return bdd.False, fmt.Errorf("symbolic: free fixpoint variable %s", f.Name)
+	}
+	if m.graph != nil {
+		return m.statesBDD(f.Sat(m.graph)), nil
+	}
+	switch f := f.(type) {
+	case kripke.AtomFormula:
+		if cond, ok := m.labels[f.Prop]; ok {
+			return mgr.And(cond, m.valid), nil
+		}
+		if v, ok := m.byName[f.Prop]; ok && v.isBool {
+			return mgr.And(m.encode(v, 1, false), m.valid), nil
+		}
+		return bdd.False, fmt.Errorf("symbolic: unknown proposition %s", f.Prop)
+	case kripke.CompareFormula:
+		return m.compare(f)
+	}
+	return bdd.False, fmt.Errorf("symbolic: %T is not supported", f)
+}
+
+// lfp iterates step from the empty set until it stops changing.
+func lfp(step func(bdd.Node) bdd.Node) bdd.Node {
+	z := bdd.False
+	for {
+		next := step(z)
+		if next == z {
+			return z
+		}
+		z = next
+	}
+}
+
+// gfp iterates step from top until it stops changing.
+func gfp(top bdd.Node, step func(bdd.Node) bdd.Node) bdd.Node {
+	z := top
+	for {
+		next := step(z)
+		if next == z {
+			return z
+		}
+		z = next
+	}
+}
+
+// diamond is <a>x: the union of the pre-images of x under the relations
+// of the edge labels a matches.
+func (m *Model) diamond(a kripke.ActionFormula, x bdd.Node) bdd.Node {
+	r := bdd.False
+	for l, rel := range m.byEdge {
+		if a.MatchAction(l) {
+			r = m.mgr.Or(r, m.pre(rel, x))
+		}
+	}
+	return r
+}
+
+// fixpoint evaluates body with x bound to successive approximations from
+// start. Check admits only bodies kripke.ValidateMu accepts, which are
+// monotone in x, so the approximations converge.
+func (m *Model) fixpoint(x string, body kripke.Formula, start bdd.Node, env map[string]bdd.Node) (bdd.Node, error) {
+	inner := make(map[string]bdd.Node, len(env)+1)
+	for k, v := range env {
+		inner[k] = v
+	}
+	z := start
+	for {
+		inner[x] = z
+		next, err := m.eval(body, inner)
+		if err != nil {
+			return bdd.False, err
+		}
+		if next == z {
+			return z, nil
+		}
+		z = next
+	}
+}
+
+// compareConst builds an integer variable compared with a constant bit by
+// bit, however wide its range.
+func (m *Model) compareConst(c kripke.CompareFormula) (bdd.Node, bool) {
+	op, l, r := c.Op, c.Left, c.Right
+	if _, ok := l.(kripke.ConstExpr); ok {
+		l, r = r, l
+		op = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<="}[op]
+		if op == "" {
+			op = c.Op
+		}
+	}
+	ve, ok := l.(kripke.VarExpr)
+	if !ok {
+		return bdd.False, false
+	}
+	ce, ok := r.(kripke.ConstExpr)
+	if !ok {
+		return bdd.False, false
+	}
+	v, ok := m.byName[ve.Name]
+	x, isInt := ce.Value.(int)
+	if !ok || v.isBool || !isInt {
+		return bdd.False, false
+	}
+	vr := Var{m, v}
+	switch op {
+	case "==":
+		return m.mgr.And(vr.Eq(x), m.valid), true
+	case "!=":
+		return m.mgr.AndNot(m.valid, vr.Eq(x)), true
+	case "<":
+		return m.mgr.And(vr.Lt(x), m.valid), true
+	case "<=":
+		return m.mgr.And(vr.Le(x), m.valid), true
+	case ">":
+		return m.mgr.And(vr.Gt(x), m.valid), true
+	case ">=":
+		return m.mgr.And(vr.Ge(x), m.valid), true
+	}
+	return bdd.False, false
+}
+
+// maxCompareValuations bounds the assignments compare enumerates.
+const maxCompareValuations = 1 << 16
+
+// compare encodes a comparison over declared variables by evaluating it
+// on every assignment of the variables it mentions, on a scratch graph
+// with one state per assignment, so it means exactly what it does on an
+// explicit graph.
+func (m *Model) compare(c kripke.CompareFormula) (bdd.Node, error) {
+	if r, ok := m.compareConst(c); ok {
+		return r, nil
+	}
+	var vars []*variable
+	seen := make(map[string]bool)
+	var collect func(e kripke.Expr) error
+	collect = func(e kripke.Expr) error {
+		switch e := e.(type) {
+		case kripke.VarExpr:
+			v, ok := m.byName[e.Name]
+			if !ok {
+				return fmt.Errorf("symbolic: unknown variable %s", e.Name)
+			}
+			if !seen[e.Name] {
+				seen[e.Name] = true
+				vars = append(vars, v)
+			}
+		case kripke.ArithExpr:
+			if err := collect(e.Left); err != nil {
+				return err
+			}
+			return collect(e.Right)
+		case kripke.ConstExpr:
+		default:
+			return fmt.Errorf("symbolic: %s is not supported in comparisons", e)
+		}
+		return nil
+	}
+	if err := collect(c.Left); err != nil {
+		return bdd.False, err
+	}
+	if err := collect(c.Right); err != nil {
+		return bdd.False, err
+	}
+	total := 1
+	for _, v := range vars {
+		total *= v.max - v.min + 1
+		if total > maxCompareValuations {
+			return bdd.False, fmt.Errorf("symbolic: %s ranges over more than %d valuations", kripke.Explain(c), maxCompareValuations)
+		}
+	}
+
+	scratch := kripke.NewGraph()
+	conds := make([]bdd.Node, total)
+	for i := 0; i < total; i++ {
+		id := scratch.AddState(strconv.Itoa(i), nil)
+		values := make(map[string]any, len(vars))
+		cond := bdd.True
+		rest := i
+		for _, v := range vars {
+			x := v.min + rest%(v.max-v.min+1)
+			rest /= v.max - v.min + 1
+			if v.isBool {
+				values[v.name] = x == 1
+			} else {
+				values[v.name] = x
+			}
+			cond = m.mgr.And(cond, m.encode(v, x, false))
+		}
+		scratch.SetVars(id, values)
+		conds[id] = cond
+	}
+	r := bdd.False
+	for s := range c.Sat(scratch) {
+		r = m.mgr.Or(r, conds[s])
+	}
+	return m.mgr.And(r, m.valid), nil
+}
@@ -0,0 +1,422 @@
+package symbolic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+	"github.com/rfielding/kripke-ctl/kripke/bdd"
+	"github.com/rfielding/kripke-ctl/kripke/sat"
+)
+
+// ---------- IC3 ----------
+
+// IC3 proves the invariant f with IC3, also called property directed
+// reachability (Bradley, "SAT-based model checking without unrolling",
+// 2011). It keeps a sequence of frames, each over-approximating the
+// states reachable in that many steps as clauses over the state bits,
+// and strengthens them until one is inductive, which is the invariant it
+// reports, or a chain of states leads from an initial state to one
+// violating f. Unlike BMC and KInduction it needs no bound: on a finite
+// model it always decides. The queries go to one incremental sat.CDCL.
+//
+// Finite means m as declared, with every Int in its range; as with
+// KInduction, unbounded models and kripke.World are out of scope except
+// through FromGraph of an explored graph.
+func (m *Model) IC3(f kripke.Formula) (*Proof, error) {
+	p, err := m.property(f)
+	if err != nil {
+		return nil, err
+	}
+	bad := m.mgr.AndNot(m.valid, p)
+	if init := m.mgr.And(m.Init(), bad); init != bdd.False {
+		state := m.pickState(init)
+		return &Proof{Counterexample: m.newTrace([][]bool{state}, -1)}, nil
+	}
+	c := newIC3(m, bad)
+	c.addFrame()
+	for k := 1; ; k++ {
+		for {
+			ok, err := c.solve(c.frameActs(k), c.bad)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			trace, err := c.block(c.stateCube(), k)
+			if err != nil || trace != nil {
+				return &Proof{Depth: k, Counterexample: trace}, err
+			}
+		}
+		c.addFrame()
+		inv, err := c.propagate(k)
+		if err != nil {
+			return nil, err
+		}
+		if inv > 0 {
+			return c.proof(p, inv, k), nil
+		}
+	}
+}
+
+// literal fixes one state bit.
+type literal struct {
+	bit int
+	val bool
+}
+
+// cube is a conjunction of literals sorted by bit.
+type cube []literal
+
+type ic3 struct {
+	m *Model
+	s *sat.Incremental
+	u *unrolling // state 0 is current, state 1 next
+
+	trans, bad, init sat.Lit
+	// frames[i] holds the cubes blocked in frame i and beyond (its
+	// clauses are their negations), enabled by assuming acts[i]. Frame 0
+	// is the initial states.
+	frames [][]cube
+	acts   []sat.Lit
+}
+
+func newIC3(m *Model, bad bdd.Node) *ic3 {
+	s := &sat.Incremental{}
+	u := m.newUnrolling(&s.CNF, 0)
+	u.k = 1
+	next := make([]sat.Lit, m.nbits)
+	for b := range next {
+		next[b] = s.NewVar()
+	}
+	u.frames = append(u.frames, next)
+	c := &ic3{m: m, s: s, u: u}
+	c.trans = u.bddLit(m.trans, 0, 1)
+	c.bad = u.bddLit(bad, 0, 0)
+	c.init = u.bddLit(m.Init(), 0, 0)
+	s.Add(u.bddLit(m.valid, 0, 0))
+	c.frames = [][]cube{nil}
+	c.acts = []sat.Lit{c.init}
+	return c
+}
+
+func (c *ic3) addFrame() {
+	for len(c.frames) <= len(c.acts) {
+		c.frames = append(c.frames, nil)
+	}
+	c.acts = append(c.acts, c.s.NewVar())
+}
+
+// frameActs are the assumptions enabling frame i.
+func (c *ic3) frameActs(i int) []sat.Lit {
+	if i == 0 {
+		return []sat.Lit{c.init}
+	}
+	return append([]sat.Lit(nil), c.acts[i:]...)
+}
+
+func (c *ic3) solve(assume []sat.Lit, more ...sat.Lit) (bool, error) {
+	return c.s.Solve(append(assume, more...)...)
+}
+
+// lits are the cube's literals in state 0 or 1.
+func (c *ic3) lits(q cube, state int) []sat.Lit {
+	ls := make([]sat.Lit, len(q))
+	for i, l := range q {
+		ls[i] = c.u.frames[state][l.bit]
+		if !l.val {
+			ls[i] = ls[i].Not()
+		}
+	}
+	return ls
+}
+
+// stateCube is the full current state of the last model.
+func (c *ic3) stateCube() cube {
+	q := make(cube, c.m.nbits)
+	for b := range q {
+		q[b] = literal{b, c.s.Value(c.u.frames[0][b])}
+	}
+	return q
+}
+
+func (c *ic3) bits(q cube) []bool {
+	bits := make([]bool, c.m.nbits)
+	for _, l := range q {
+		bits[l.bit] = l.val
+	}
+	return bits
+}
+
+func (c *ic3) cubeBDD(q cube) bdd.Node {
+	mgr := c.m.mgr
+	r := bdd.True
+	for _, l := range q {
+		if l.val {
+			r = mgr.And(r, mgr.Var(2*l.bit))
+		} else {
+			r = mgr.And(r, mgr.NVar(2*l.bit))
+		}
+	}
+	return r
+}
+
+func (c *ic3) initial(q cube) bool {
+	return c.m.mgr.And(c.cubeBDD(q), c.m.Init()) != bdd.False
+}
+
+// relative asks whether ¬q is inductive relative to frame i: whether
+// F_i ∧ ¬q ∧ T ∧ q' is unsatisfiable. If so it returns the literals of q
+// the proof used, else nil and the predecessor is the model's state 0.
+func (c *ic3) relative(q cube, i int) (cube, bool, error) {
+	tmp := c.s.NewVar()
+	neg := []sat.Lit{tmp.Not()}
+	for _, l := range c.lits(q, 0) {
+		neg = append(neg, l.Not())
+	}
+	c.s.Add(neg...)
+	next := c.lits(q, 1)
+	ok, err := c.solve(c.frameActs(i), append([]sat.Lit{tmp, c.trans}, next...)...)
+	c.s.Add(tmp.Not())
+	if err != nil || ok {
+		return nil, false, err
+	}
+	used := make(map[sat.Lit]bool)
+	for _, l := range c.s.Core() {
+		used[l] = true
+	}
+	var core cube
+	for i, l := range q {
+		if used[next[i]] {
+			core = append(core, l)
+		}
+	}
+	return core, true, nil
+}
+
+// generalize shrinks a cube that is blocked relative to frame i while it
+// stays blocked and disjoint from the initial states.
+func (c *ic3) generalize(q, core cube, i int) (cube, error) {
+	if len(core) > 0 && !c.initial(core) {
+		q = core
+	}
+	for j := 0; j < len(q) && len(q) > 1; {
+		try := append(append(cube(nil), q[:j]...), q[j+1:]...)
+		if c.initial(try) {
+			j++
+			continue
+		}
+		core, ok, err := c.relative(try, i)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			j++
+			continue
+		}
+		q = try
+		if len(core) > 0 && len(core) < len(q) && !c.initial(core) {
+			q, j = core, 0
+		}
+	}
+	return q, nil
+}
+
+// addCube blocks q in frames 1..i.
+func (c *ic3) addCube(q cube, i int) {
+	c.frames[i] = append(c.frames[i], q)
+	cl := []sat.Lit{c.acts[i].Not()}
+	for _, l := range c.lits(q, 0) {
+		cl = append(cl, l.Not())
+	}
+	c.s.Add(cl...)
+}
+
+type obligation struct {
+	q      cube
+	level  int
+	parent *obligation
+}
+
+// block removes the bad state q from frame k, recursively blocking its
+// predecessors in earlier frames. It returns a counterexample if some
+// chain of predecessors starts in an initial state.
+func (c *ic3) block(q cube, k int) (*Trace, error) {
+	queue := []*obligation{{q: q, level: k}}
+	for len(queue) > 0 {
+		// Lowest level first.
+		sort.SliceStable(queue, func(a, b int) bool { return queue[a].level < queue[b].level })
+		ob := queue[0]
+		queue = queue[1:]
+		core, ok, err := c.relative(ob.q, ob.level-1)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			pred := &obligation{q: c.stateCube(), level: ob.level - 1, parent: ob}
+			if ob.level-1 == 0 || c.initial(pred.q) {
+				var states [][]bool
+				for o := pred; o != nil; o = o.parent {
+					states = append(states, c.bits(o.q))
+				}
+				return c.m.newTrace(states, -1), nil
+			}
+			queue = append(queue, pred, ob)
+			continue
+		}
+		g, err := c.generalize(ob.q, core, ob.level-1)
+		if err != nil {
+			return nil, err
+		}
+		// Push the clause as far forward as it stays inductive.
+		level := ob.level
+		for level < k {
+			if _, ok, err := c.relative(g, level); err != nil {
+				return nil, err
+			} else if !ok {
+				break
+			}
+			level++
+		}
+		c.addCube(g, level)
+	}
+	return nil, nil
+}
+
+// propagate moves each clause forward while it is inductive relative to
+// its frame. A frame left with no clauses of its own equals the next one,
+// which is then inductive; propagate returns that next frame, else 0.
+func (c *ic3) propagate(k int) (int, error) {
+	for i := 1; i <= k; i++ {
+		var keep []cube
+		for _, q := range c.frames[i] {
+			ok, err := c.solve(c.frameActs(i), append([]sat.Lit{c.trans}, c.lits(q, 1)...)...)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				keep = append(keep, q)
+			} else {
+				c.addCube(q, i+1)
+			}
+		}
+		c.frames[i] = keep
+		if len(keep) == 0 {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// proof reports frame i as the invariant.
+func (c *ic3) proof(p bdd.Node, i, k int) *Proof {
+	mgr := c.m.mgr
+	inv := mgr.And(p, c.m.valid)
+	var lemmas []string
+	for j := i; j < len(c.frames); j++ {
+		for _, q := range c.frames[j] {
+			inv = mgr.AndNot(inv, c.cubeBDD(q))
+			lemmas = append(lemmas, c.m.describeCube(q))
+		}
+	}
+	sort.Strings(lemmas)
+	return &Proof{Holds: true, Invariant: inv, Lemmas: lemmas, Depth: k}
+}
+
+// describeCube renders the clause ruling out q, as "!(n = 3 & busy)" or,
+// where q fixes only some bits of a variable, with the values it allows:
+// "!(n in {1, 3})".
+func (m *Model) describeCube(q cube) string {
+	fixed := make(map[int]bool)
+	for _, l := range q {
+		fixed[l.bit] = l.val
+	}
+	var parts []string
+	for _, v := range m.vars {
+		var vals []int
+		touched := false
+		for _, b := range v.bits {
+			if _, ok := fixed[b]; ok {
+				touched = true
+			}
+		}
+		if !touched {
+			continue
+		}
+		// The allowed offsets from v.min are the fixed bits plus every
+		// combination of the free ones. Spreading k over the free bits in
+		// order keeps them increasing, so at most nine are built.
+		base, free := 0, []int(nil)
+		for i, b := range v.bits {
+			val, ok := fixed[b]
+			switch {
+			case !ok:
+				free = append(free, i)
+			case val:
+				base |= 1 << i
+			}
+		}
+		for k := 0; k>>len(free) == 0 && len(vals) <= 8; k++ {
+			x := base
+			for j, i := range free {
+				if k>>j&1 == 1 {
+					x |= 1 << i
+				}
+			}
+			if v.min+x > v.max {
+				break
+			}
+			vals = append(vals, v.min+x)
+		}
+		parts = append(parts, m.describeValues(v, vals, fixed))
+	}
+	return "!(" + strings.Join(parts, " & ") + ")"
+}
+
+func (m *Model) describeValues(v *variable, vals []int, fixed map[int]bool) string {
+	name := func(x int) string {
+		switch {
+		case m.graph != nil:
+			return m.graph.NameOf(m.ids[x])
+		case v.isBool:
+			return fmt.Sprint(x == 1)
+		}
+		return fmt.Sprint(x)
+	}
+	switch {
+	case len(vals) == 0:
+		return "false"
+	case len(vals) == 1 && v.isBool:
+		if vals[0] == 1 {
+			return v.name
+		}
+		return "!" + v.name
+	case len(vals) == 1:
+		return v.name + " = " + name(vals[0])
+	case len(vals) <= 8:
+		strs := make([]string, len(vals))
+		for i, x := range vals {
+			strs[i] = name(x)
+		}
+		return v.name + " in {" + strings.Join(strs, ", ") + "}"
+	}
+	var bits []string
+	for i, b := range v.bits {
+		if val, ok := fixed[b]; ok {
+			bits = append(bits, fmt.Sprintf("bit %d of %s is %d", i, v.name, map[bool]int{false: 0, true: 1}[val]))
+		}
+	}
+	return strings.Join(bits, " & ")
+}
+
+// pickState returns one state of a non-empty set as logical bits.
+func (m *Model) pickState(set bdd.Node) []bool {
+	state := make([]bool, m.nbits)
+	m.mgr.AllSat(set, m.cur, func(bits []bool) bool {
+		copy(state, bits)
+		return false
+	})
+	return state
+}
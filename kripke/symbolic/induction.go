@@ -0,0 +1,123 @@
+package symbolic
+
+import (
+	"fmt"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+	"github.com/rfielding/kripke-ctl/kripke/bdd"
+	"github.com/rfielding/kripke-ctl/kripke/sat"
+)
+
+// ---------- Invariant proofs ----------
+
+// Proof is the outcome of proving an invariant with KInduction or IC3:
+// either the invariant holds in every reachable state, with an inductive
+// invariant to show for it, or a counterexample trace reaches a state
+// violating it.
+type Proof struct {
+	Holds bool
+	// Invariant, when Holds, is an inductive invariant: it contains the
+	// initial states, every successor of its states is in it, and it
+	// implies the property.
+	Invariant bdd.Node
+	// Lemmas describe Invariant as clauses over the variables, when the
+	// prover found it that way (IC3).
+	Lemmas []string
+	// Depth is the k that worked for KInduction, or the number of frames
+	// IC3 used.
+	Depth          int
+	Counterexample *Trace
+}
+
+// property extracts the set of states an invariant property requires:
+// f is a propositional state formula or AG of one.
+func (m *Model) property(f kripke.Formula) (bdd.Node, error) {
+	inner := f
+	if ag, ok := f.(kripke.AGFormula); ok {
+		inner = ag.Inner
+	}
+	if !propositional(inner) {
+		return bdd.False, fmt.Errorf("symbolic: %s is not an invariant (AG of a state formula)", kripke.Explain(f))
+	}
+	m.build()
+	return m.eval(kripke.Normalize(inner), nil)
+}
+
+// KInduction proves the invariant f by k-induction, for k up to maxK: f
+// holds on every path of k steps from an initial state (the base case,
+// which is BMC), and any k+1 successive states satisfying f on a path
+// without repeated states are followed only by states satisfying f (the
+// step). The step case generalizes over all states, not just reachable
+// ones, so a property that is not inductive on its own may need a larger
+// k. KInduction returns nil when neither a proof nor a counterexample is
+// found within maxK. The options are BMC's.
+//
+// The proof is about m as declared: its Ints cannot leave their ranges,
+// so it says nothing about a model that counts past them. A kripke.World
+// has no encoding here; prove its invariants on FromGraph of the explored
+// graph.
+func (m *Model) KInduction(f kripke.Formula, maxK int, opts ...BMCOption) (*Proof, error) {
+	o := bmcOptions{solver: sat.CDCL{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	p, err := m.property(f)
+	if err != nil {
+		return nil, err
+	}
+	bad := m.mgr.AndNot(m.valid, p)
+	for k := 0; k <= maxK; k++ {
+		base := m.unroll(k)
+		base.cnf.Add(base.bddLit(bad, k, k))
+		res, err := o.solver.Solve(base.cnf)
+		if err != nil {
+			return nil, err
+		}
+		if res.Sat {
+			return &Proof{Depth: k, Counterexample: m.newTrace(base.states(res.Value), -1)}, nil
+		}
+
+		step := m.newUnrolling(&sat.CNF{}, k+1)
+		c := step.cnf
+		for i := 0; i <= k; i++ {
+			c.Add(step.bddLit(p, i, i))
+		}
+		c.Add(step.bddLit(bad, k+1, k+1))
+		for i := 0; i <= k+1; i++ {
+			for j := i + 1; j <= k+1; j++ {
+				var differ []sat.Lit
+				for b := range step.frames[i] {
+					differ = append(differ, c.Equiv(step.frames[i][b], step.frames[j][b]).Not())
+				}
+				c.Add(differ...)
+			}
+		}
+		res, err = o.solver.Solve(c)
+		if err != nil {
+			return nil, err
+		}
+		if !res.Sat {
+			return &Proof{Holds: true, Depth: k, Invariant: m.kInvariant(p, k)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// kInvariant is an inductive invariant once p is proved k-inductive: the
+// states from which p holds for the next k steps. The step case assumed
+// paths without repeated states, which that set does not account for; if
+// it is not inductive, the states from which p holds forever are.
+func (m *Model) kInvariant(p bdd.Node, k int) bdd.Node {
+	mgr := m.mgr
+	ax := func(z bdd.Node) bdd.Node {
+		return mgr.AndNot(m.valid, m.pre(m.trans, mgr.AndNot(m.valid, z)))
+	}
+	q := p
+	for i := 0; i < k; i++ {
+		q = mgr.And(p, ax(q))
+	}
+	if mgr.AndNot(m.Image(q), q) == bdd.False {
+		return q
+	}
+	return gfp(q, func(z bdd.Node) bdd.Node { return mgr.And(q, ax(z)) })
+}
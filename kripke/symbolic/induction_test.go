@@ -0,0 +1,191 @@
+package symbolic
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+	"github.com/rfielding/kripke-ctl/kripke/bdd"
+)
+
+// checkProof verifies a proof's invariant or counterexample against the
+// property p.
+func checkProof(t *testing.T, m *Model, f kripke.Formula, proof *Proof) {
+	t.Helper()
+	p, err := m.property(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := m.BDD()
+	if proof.Holds {
+		inv := proof.Invariant
+		switch {
+		case mgr.AndNot(m.Init(), inv) != bdd.False:
+			t.Fatal("invariant misses an initial state")
+		case mgr.AndNot(m.Image(inv), inv) != bdd.False:
+			t.Fatal("invariant is not inductive")
+		case mgr.AndNot(mgr.And(inv, m.valid), p) != bdd.False:
+			t.Fatal("invariant does not imply the property")
+		}
+		return
+	}
+	tr := proof.Counterexample
+	if tr == nil {
+		t.Fatal("neither invariant nor counterexample")
+	}
+	last := m.valuationBDD(tr.Values[len(tr.Values)-1])
+	if mgr.And(last, p) != bdd.False {
+		t.Fatalf("trace %v ends in a good state", tr)
+	}
+	if mgr.And(m.valuationBDD(tr.Values[0]), m.Init()) == bdd.False {
+		t.Fatalf("trace %v does not start in an initial state", tr)
+	}
+	for i := 1; i < len(tr.Values); i++ {
+		if mgr.And(m.Image(m.valuationBDD(tr.Values[i-1])), m.valuationBDD(tr.Values[i])) == bdd.False {
+			t.Fatalf("trace %v: no step %d", tr, i)
+		}
+	}
+}
+
+func TestInvariantProversMatchExplicit(t *testing.T) {
+	rng := rand.New(rand.NewSource(47))
+	for i := 0; i < 200; i++ {
+		n := 2 + rng.Intn(12)
+		g := randomGraph(rng, n, rng.Intn(3*n))
+		m := FromGraph(g)
+		f := kripke.AG([]kripke.Formula{
+			kripke.Atom("p"),
+			kripke.Or(kripke.Atom("p"), kripke.Atom("q")),
+			kripke.Not(kripke.And(kripke.Atom("q"), kripke.Atom("p"))),
+		}[rng.Intn(3)])
+		holds := f.Sat(g).Contains(g.InitialStates()[0])
+
+		proof, err := m.IC3(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proof.Holds != holds {
+			t.Fatalf("graph %d: IC3 says %v, explicit %v", i, proof.Holds, holds)
+		}
+		checkProof(t, m, f, proof)
+		if proof.Counterexample != nil {
+			checkTrace(t, g, proof.Counterexample)
+		}
+
+		// Paths without repeated states are at most n long.
+		proof, err = m.KInduction(f, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proof == nil || proof.Holds != holds {
+			t.Fatalf("graph %d: k-induction says %+v, explicit %v", i, proof, holds)
+		}
+		checkProof(t, m, f, proof)
+	}
+}
+
+// stepper counts x up by step from 0 in a range of a million values.
+func stepper(step int) *Model {
+	m := New()
+	x := m.Int("x", 0, 1<<20, 0)
+	m.Rule("counter", "step", bdd.True, x.Add(step))
+	m.Rule("counter", "reset", x.Ge(1000), x.Set(0))
+	return m
+}
+
+func TestIC3FindsStrengthening(t *testing.T) {
+	// x != 7 is not inductive (5 steps to 7), but x even is.
+	m := stepper(2)
+	f := kripke.MustParseCTL("AG(x != 7)")
+	proof, err := m.IC3(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proof.Holds {
+		t.Fatalf("counterexample %v", proof.Counterexample)
+	}
+	checkProof(t, m, f, proof)
+	if len(proof.Lemmas) != 1 || proof.Lemmas[0] != "!(bit 0 of x is 1)" {
+		t.Fatalf("lemmas %q", proof.Lemmas)
+	}
+
+	proof, err = m.KInduction(f, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof == nil || !proof.Holds || proof.Depth != 3 {
+		t.Fatalf("k-induction: %+v", proof)
+	}
+	checkProof(t, m, f, proof)
+}
+
+func TestDescribeCube(t *testing.T) {
+	m := New()
+	m.Int("n", 0, 5, 0)
+	m.Int("w", 0, 1<<40, 0)
+	m.Bool("busy", false)
+	bit := func(name string, i int, val bool) literal {
+		return literal{bit: m.byName[name].bits[i], val: val}
+	}
+	for _, tc := range []struct {
+		q    cube
+		want string
+	}{
+		{cube{bit("n", 1, true)}, "!(n in {2, 3})"},
+		{cube{bit("n", 0, true), bit("n", 1, false), bit("n", 2, true)}, "!(n = 5)"},
+		{cube{bit("n", 1, true), bit("n", 2, true)}, "!(false)"},
+		{cube{bit("w", 0, true), bit("busy", 0, true)}, "!(bit 0 of w is 1 & busy)"},
+		{cube{bit("w", 39, true)}, "!(bit 39 of w is 1)"},
+	} {
+		if got := m.describeCube(tc.q); got != tc.want {
+			t.Errorf("describeCube: %s, want %s", got, tc.want)
+		}
+	}
+}
+
+func TestProversFindCounterexamples(t *testing.T) {
+	m := stepper(3)
+	f := kripke.MustParseCTL("AG(x != 9)")
+	want := "x=0 -step-> x=3 -step-> x=6 -step-> x=9"
+	proof, err := m.IC3(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Holds || proof.Counterexample.String() != want {
+		t.Fatalf("IC3: %+v", proof)
+	}
+	proof, err = m.KInduction(f, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof == nil || proof.Holds || proof.Counterexample.String() != want {
+		t.Fatalf("k-induction: %+v", proof)
+	}
+	if _, err := m.IC3(kripke.MustParseCTL("AF(x == 3)")); err == nil {
+		t.Fatal("IC3 accepted a liveness property")
+	}
+}
+
+func TestArithmeticMatchesEnumeration(t *testing.T) {
+	m := New()
+	x := m.Int("x", -3, 9, 0)
+	for _, d := range []int{-5, -1, 0, 2, 7, 13} {
+		add, apply := x.Add(d).rel(), x.Apply(func(v int) (int, bool) { return v + d, true }).rel()
+		rng := m.BDD().And(m.inRange(x.v, true), m.inRange(x.v, false))
+		if m.BDD().And(add, rng) != m.BDD().And(apply, rng) {
+			t.Errorf("Add(%d) differs from Apply", d)
+		}
+	}
+	for c := -5; c <= 11; c++ {
+		for name, pair := range map[string][2]bdd.Node{
+			"Lt": {x.Lt(c), x.In(func(v int) bool { return v < c })},
+			"Le": {x.Le(c), x.In(func(v int) bool { return v <= c })},
+			"Gt": {x.Gt(c), x.In(func(v int) bool { return v > c })},
+			"Ge": {x.Ge(c), x.In(func(v int) bool { return v >= c })},
+		} {
+			if pair[0] != pair[1] {
+				t.Errorf("%s(%d) differs from In", name, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,561 @@
+// Package symbolic checks CTL and μ-calculus formulas on models whose
+// states are valuations of boolean and bounded integer variables. Sets of
+// states and the transition relation are BDDs (package bdd), so a model
+// with 2^40 states costs as much as its BDDs, not its state count.
+//
+// A Model is either declared directly, as variables plus guarded rules
+// that each belong to a process (actor) and carry an action name, or
+// converted from an explicit kripke.Graph with FromGraph. Formulas are the
+// ordinary kripke.Formula values, so
+//
+//	m.Holds(kripke.MustParseCTL("AG(n <= 3)"))
+//
+// checks the same formula a Graph would. EX, EU and EG are computed as
+// fixpoints of BDD pre-images; the other CTL operators are reduced to them
+// with kripke.Normalize. BMC checks LTL properties the other way, by
+// unrolling the transition relation into a SAT problem (package sat) and
+// searching for short counterexamples, and KInduction and IC3 prove
+// invariants without a bound, reporting an inductive invariant. Integer
+// variables are bounded, but Add and the comparisons work bit by bit, so
+// a counter may range over millions of values.
+//
+// Unbounded counters are not supported: every Int has a range, and a rule
+// whose update would leave it is disabled, so a model that counts forever
+// is checked only up to the bound it was declared with. For the same
+// reason there is no translation from a kripke.World or kripke.Actor,
+// whose ints are unbounded and whose processes may be arbitrary Go code;
+// such a model is checked here through kripke.Explore and FromGraph, or
+// declared again with explicit bounds.
+package symbolic
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+	"github.com/rfielding/kripke-ctl/kripke/bdd"
+)
+
+// Model is a symbolic transition system. Each variable is a run of BDD
+// bits; logical bit k is BDD variable 2k in the current state and 2k+1 in
+// the next, which keeps the transition relation small.
+type Model struct {
+	mgr    *bdd.Manager
+	vars   []*variable
+	byName map[string]*variable
+	nbits  int
+	rules  []*rule
+	labels map[string]bdd.Node
+
+	init    bdd.Node
+	hasInit bool
+
+	// graph is set by FromGraph; ids maps the state variable's values to
+	// its states.
+	graph *kripke.Graph
+	ids   []kripke.StateID
+	index map[kripke.StateID]int
+
+	built  bool
+	valid  bdd.Node // valuations within every variable's range
+	trans  bdd.Node
+	byEdge map[kripke.EdgeLabel]bdd.Node
+	cur    []int
+	next   []int
+	toCur  map[int]int
+	toNext map[int]int
+}
+
+type variable struct {
+	name     string
+	min, max int
+	isBool   bool
+	initial  int
+	bits     []int // logical bits, least significant first
+}
+
+// New returns an empty model.
+func New() *Model {
+	return &Model{
+		mgr:    bdd.New(),
+		byName: make(map[string]*variable),
+		labels: make(map[string]bdd.Node),
+	}
+}
+
+// BDD is the manager the model's conditions are built with; use it to
+// combine them with And, Or and Not.
+func (m *Model) BDD() *bdd.Manager { return m.mgr }
+
+// Var is a variable of a Model.
+type Var struct {
+	m *Model
+	v *variable
+}
+
+// Bool declares a boolean variable with its initial value. As a
+// proposition its name holds where it is true.
+func (m *Model) Bool(name string, initial bool) Var {
+	init := 0
+	if initial {
+		init = 1
+	}
+	return m.declare(name, 0, 1, init, true)
+}
+
+// Int declares an integer variable ranging over [lo, hi].
+func (m *Model) Int(name string, lo, hi, initial int) Var {
+	if lo > hi {
+		panic(fmt.Sprintf("symbolic: %s has an empty range [%d, %d]", name, lo, hi))
+	}
+	return m.declare(name, lo, hi, initial, false)
+}
+
+func (m *Model) declare(name string, lo, hi, initial int, isBool bool) Var {
+	if _, ok := m.byName[name]; ok {
+		panic(fmt.Sprintf("symbolic: variable %s declared twice", name))
+	}
+	if initial < lo || initial > hi {
+		panic(fmt.Sprintf("symbolic: initial value %d of %s is outside [%d, %d]", initial, name, lo, hi))
+	}
+	v := &variable{name: name, min: lo, max: hi, isBool: isBool, initial: initial}
+	for i := 0; i < max(bits.Len(uint(hi-lo)), 1); i++ {
+		v.bits = append(v.bits, m.nbits)
+		m.nbits++
+	}
+	m.vars = append(m.vars, v)
+	m.byName[name] = v
+	m.built = false
+	return Var{m, v}
+}
+
+// Name is the variable's name.
+func (v Var) Name() string { return v.v.name }
+
+// encode is the condition that v has value x, in the current or next
+// state; it is false when x is out of range.
+func (m *Model) encode(v *variable, x int, next bool) bdd.Node {
+	if x < v.min || x > v.max {
+		return bdd.False
+	}
+	off := x - v.min
+	r := bdd.True
+	for i, b := range v.bits {
+		idx := 2 * b
+		if next {
+			idx++
+		}
+		if off>>i&1 == 1 {
+			r = m.mgr.And(r, m.mgr.Var(idx))
+		} else {
+			r = m.mgr.And(r, m.mgr.NVar(idx))
+		}
+	}
+	return r
+}
+
+func (m *Model) inRange(v *variable, next bool) bdd.Node {
+	return m.below(v, v.max-v.min+1, next)
+}
+
+// below is the condition that v's offset from its minimum is less than
+// c, built bit by bit from the least significant bit up, so it stays
+// small however wide the range.
+func (m *Model) below(v *variable, c int, next bool) bdd.Node {
+	switch {
+	case c <= 0:
+		return bdd.False
+	case c >= 1<<len(v.bits):
+		return bdd.True
+	}
+	r := bdd.False
+	for i, b := range v.bits {
+		idx := 2 * b
+		if next {
+			idx++
+		}
+		if c>>i&1 == 1 {
+			r = m.mgr.Or(m.mgr.NVar(idx), r)
+		} else {
+			r = m.mgr.And(m.mgr.NVar(idx), r)
+		}
+	}
+	return r
+}
+
+// Eq holds where v equals x; for a boolean, 1 is true and 0 false.
+func (v Var) Eq(x int) bdd.Node { return v.m.encode(v.v, x, false) }
+
+// IsTrue holds where a boolean variable is true.
+func (v Var) IsTrue() bdd.Node { return v.Eq(1) }
+
+// In holds where pred accepts v's value. It tries every value in range;
+// Eq and the comparisons below do not.
+func (v Var) In(pred func(int) bool) bdd.Node {
+	r := bdd.False
+	for x := v.v.min; x <= v.v.max; x++ {
+		if pred(x) {
+			r = v.m.mgr.Or(r, v.Eq(x))
+		}
+	}
+	return r
+}
+
+func (v Var) Lt(x int) bdd.Node {
+	return v.m.mgr.And(v.m.below(v.v, x-v.v.min, false), v.m.inRange(v.v, false))
+}
+func (v Var) Le(x int) bdd.Node { return v.Lt(x + 1) }
+func (v Var) Gt(x int) bdd.Node { return v.m.mgr.AndNot(v.m.inRange(v.v, false), v.Le(x)) }
+func (v Var) Ge(x int) bdd.Node { return v.m.mgr.AndNot(v.m.inRange(v.v, false), v.Lt(x)) }
+
+// ---------- Rules ----------
+
+// Update constrains one variable's next value.
+type Update struct {
+	v   *variable
+	rel func() bdd.Node
+}
+
+// Set makes the next value x.
+func (v Var) Set(x int) Update {
+	if x < v.v.min || x > v.v.max {
+		panic(fmt.Sprintf("symbolic: %s cannot be set to %d", v.v.name, x))
+	}
+	return Update{v.v, func() bdd.Node { return v.m.encode(v.v, x, true) }}
+}
+
+// SetBool makes a boolean's next value b.
+func (v Var) SetBool(b bool) Update {
+	if b {
+		return v.Set(1)
+	}
+	return v.Set(0)
+}
+
+// Apply makes the next value f of the current one. Where f reports
+// false, or its result is out of range, the rule is disabled.
+func (v Var) Apply(f func(int) (int, bool)) Update { return v.From(v, f) }
+
+// Add makes the next value the current one plus delta; the rule is
+// disabled where that leaves the range. Unlike Apply it is built as an
+// adder over the bits, so it suits counters with wide ranges.
+func (v Var) Add(delta int) Update {
+	return Update{v.v, func() bdd.Node {
+		mgr := v.m.mgr
+		d := delta
+		if d < 0 {
+			d = -d
+		}
+		if d >= 1<<len(v.v.bits) {
+			return bdd.False
+		}
+		// carry is the carry (or, subtracting, the borrow) into bit i.
+		r, carry := bdd.True, bdd.False
+		for i, b := range v.v.bits {
+			x, set := mgr.Var(2*b), d>>i&1 == 1
+			sum := mgr.Xor(x, carry)
+			if set {
+				sum = mgr.Not(sum)
+			}
+			r = mgr.And(r, mgr.Equiv(mgr.Var(2*b+1), sum))
+			if delta < 0 {
+				x = mgr.Not(x)
+			}
+			if set {
+				carry = mgr.Or(x, carry)
+			} else {
+				carry = mgr.And(x, carry)
+			}
+		}
+		return mgr.AndNot(r, carry)
+	}}
+}
+
+// From makes the next value of v f of the current value of src.
+func (v Var) From(src Var, f func(int) (int, bool)) Update {
+	return Update{v.v, func() bdd.Node {
+		m := v.m
+		r := bdd.False
+		for x := src.v.min; x <= src.v.max; x++ {
+			if y, ok := f(x); ok {
+				r = m.mgr.Or(r, m.mgr.And(m.encode(src.v, x, false), m.encode(v.v, y, true)))
+			}
+		}
+		return r
+	}}
+}
+
+// Any lets the next value be anything in range.
+func (v Var) Any() Update {
+	return Update{v.v, func() bdd.Node { return v.m.inRange(v.v, true) }}
+}
+
+type rule struct {
+	label   kripke.EdgeLabel
+	guard   bdd.Node
+	updates []Update
+	// rel is the rule's transition relation: given for FromGraph's rules
+	// (fixed), derived by build for declared ones.
+	rel   bdd.Node
+	fixed bool
+}
+
+// Rule adds a transition of process with the given action: where guard
+// holds, the updates apply together and every other variable keeps its
+// value. Rules interleave; each step takes one enabled rule.
+func (m *Model) Rule(process, action string, guard bdd.Node, updates ...Update) {
+	seen := make(map[*variable]bool)
+	for _, u := range updates {
+		if seen[u.v] {
+			panic(fmt.Sprintf("symbolic: rule %s.%s updates %s twice", process, action, u.v.name))
+		}
+		seen[u.v] = true
+	}
+	m.rules = append(m.rules, &rule{
+		label:   kripke.EdgeLabel{Action: action, Process: process},
+		guard:   guard,
+		updates: updates,
+	})
+	m.built = false
+}
+
+// Label defines the proposition name to hold where cond does.
+func (m *Model) Label(name string, cond bdd.Node) {
+	m.labels[name] = cond
+}
+
+// SetInit replaces the initial condition, which by default is every
+// variable at its declared initial value.
+func (m *Model) SetInit(cond bdd.Node) {
+	m.init, m.hasInit = cond, true
+}
+
+// build derives the bit lists and the transition relation.
+func (m *Model) build() {
+	if m.built {
+		return
+	}
+	mgr := m.mgr
+	m.cur, m.next = nil, nil
+	m.toCur, m.toNext = make(map[int]int), make(map[int]int)
+	for b := 0; b < m.nbits; b++ {
+		m.cur = append(m.cur, 2*b)
+		m.next = append(m.next, 2*b+1)
+		m.toNext[2*b] = 2*b + 1
+		m.toCur[2*b+1] = 2 * b
+	}
+	m.valid = bdd.True
+	for _, v := range m.vars {
+		m.valid = mgr.And(m.valid, m.inRange(v, false))
+	}
+	if m.graph != nil {
+		m.valid = mgr.And(m.valid, m.statesBDD(allOf(m.ids)))
+	}
+	m.trans = bdd.False
+	m.byEdge = make(map[kripke.EdgeLabel]bdd.Node)
+	for _, r := range m.rules {
+		if !r.fixed {
+			r.rel = m.relation(r)
+		}
+		m.trans = mgr.Or(m.trans, r.rel)
+		m.byEdge[r.label] = mgr.Or(m.byEdge[r.label], r.rel)
+	}
+	m.built = true
+}
+
+func allOf(ids []kripke.StateID) kripke.StateSet {
+	set := kripke.NewStateSet()
+	for _, s := range ids {
+		set.Add(s)
+	}
+	return set
+}
+
+// Init is the set of initial states.
+func (m *Model) Init() bdd.Node {
+	m.build()
+	if m.hasInit {
+		return m.mgr.And(m.init, m.valid)
+	}
+	r := bdd.True
+	for _, v := range m.vars {
+		r = m.mgr.And(r, m.encode(v, v.initial, false))
+	}
+	return r
+}
+
+// Trans is the transition relation over current and next bits.
+func (m *Model) Trans() bdd.Node {
+	m.build()
+	return m.trans
+}
+
+// pre is the set of states with a rel-successor in set.
+func (m *Model) pre(rel, set bdd.Node) bdd.Node {
+	return m.mgr.AndExists(rel, m.mgr.Rename(set, m.toNext), m.next)
+}
+
+// Image is the set of successors of the states in set.
+func (m *Model) Image(set bdd.Node) bdd.Node {
+	m.build()
+	return m.mgr.Rename(m.mgr.AndExists(m.trans, set, m.cur), m.toCur)
+}
+
+// Reachable is the set of states reachable from the initial states.
+func (m *Model) Reachable() bdd.Node {
+	r := m.Init()
+	for {
+		next := m.mgr.Or(r, m.Image(r))
+		if next == r {
+			return r
+		}
+		r = next
+	}
+}
+
+// Count is the number of states in set.
+func (m *Model) Count(set bdd.Node) float64 {
+	m.build()
+	return m.mgr.SatCount(m.mgr.And(set, m.valid), m.cur)
+}
+
+// Valuations calls fn with each state of set as variable values (1 and 0
+// for booleans) until fn returns false. The map is reused between calls.
+func (m *Model) Valuations(set bdd.Node, fn func(map[string]int) bool) {
+	m.build()
+	values := make(map[string]int, len(m.vars))
+	m.mgr.AllSat(m.mgr.And(set, m.valid), m.cur, func(bitv []bool) bool {
+		for _, v := range m.vars {
+			off := 0
+			for i, b := range v.bits {
+				if bitv[b] {
+					off |= 1 << i
+				}
+			}
+			values[v.name] = v.min + off
+		}
+		return fn(values)
+	})
+}
+
+// stateName renders a valuation as "a=1,b=true" in declaration order.
+func (m *Model) stateName(values map[string]int) string {
+	parts := make([]string, len(m.vars))
+	for i, v := range m.vars {
+		if v.isBool {
+			parts[i] = v.name + "=" + strconv.FormatBool(values[v.name] == 1)
+		} else {
+			parts[i] = v.name + "=" + strconv.Itoa(values[v.name])
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// valuationBDD is the single current state with the given values.
+func (m *Model) valuationBDD(values map[string]int) bdd.Node {
+	r := bdd.True
+	for _, v := range m.vars {
+		r = m.mgr.And(r, m.encode(v, values[v.name], false))
+	}
+	return r
+}
+
+// Explicit enumerates the reachable states into a kripke.Graph, named
+// like "n=2,busy=true" and carrying the values as state variables (bools
+// as bools). Edges are labelled with their rule's process and action, and
+// each Label holds as a state label where it applies. It is meant for
+// models small enough to enumerate: diagrams, and cross-checking.
+func (m *Model) Explicit() *kripke.Graph {
+	m.build()
+	g := kripke.NewGraph()
+	names := make([]string, 0, len(m.labels))
+	for name := range m.labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	add := func(values map[string]int) string {
+		name := m.stateName(values)
+		if _, ok := g.StateByName(name); ok {
+			return name
+		}
+		state := m.valuationBDD(values)
+		labels := make(map[string]bool)
+		for _, l := range names {
+			if m.mgr.And(state, m.labels[l]) != bdd.False {
+				labels[l] = true
+			}
+		}
+		id := g.AddState(name, labels)
+		vars := make(map[string]any, len(m.vars))
+		for _, v := range m.vars {
+			if v.isBool {
+				vars[v.name] = values[v.name] == 1
+			} else {
+				vars[v.name] = values[v.name]
+			}
+		}
+		g.SetVars(id, vars)
+		return name
+	}
+	var queue []map[string]int
+	copyValues := func(values map[string]int) map[string]int {
+		c := make(map[string]int, len(values))
+		for k, v := range values {
+			c[k] = v
+		}
+		return c
+	}
+	m.Valuations(m.Init(), func(values map[string]int) bool {
+		g.SetInitial(add(values))
+		queue = append(queue, copyValues(values))
+		return true
+	})
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		values := queue[0]
+		queue = queue[1:]
+		from := m.stateName(values)
+		if seen[from] {
+			continue
+		}
+		seen[from] = true
+		state := m.valuationBDD(values)
+		for _, r := range m.rules {
+			succ := m.mgr.Rename(m.mgr.AndExists(r.rel, state, m.cur), m.toCur)
+			m.Valuations(succ, func(next map[string]int) bool {
+				to := add(next)
+				g.AddLabeledEdge(from, to, r.label)
+				if !seen[to] {
+					queue = append(queue, copyValues(next))
+				}
+				return true
+			})
+		}
+	}
+	return g
+}
+
+// relation builds a declared rule's relation: guard, updates with their
+// results in range, and every other variable unchanged.
+func (m *Model) relation(r *rule) bdd.Node {
+	mgr := m.mgr
+	rel := mgr.And(r.guard, m.valid)
+	updated := make(map[*variable]bool)
+	for _, u := range r.updates {
+		rel = mgr.And(rel, u.rel())
+		updated[u.v] = true
+	}
+	for _, v := range m.vars {
+		if updated[v] {
+			rel = mgr.And(rel, m.inRange(v, true))
+			continue
+		}
+		for _, b := range v.bits {
+			rel = mgr.And(rel, mgr.Equiv(mgr.Var(2*b), mgr.Var(2*b+1)))
+		}
+	}
+	return rel
+}
@@ -0,0 +1,214 @@
+package symbolic
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+	"github.com/rfielding/kripke-ctl/kripke/bdd"
+)
+
+func randomGraph(rng *rand.Rand, n, edges int) *kripke.Graph {
+	g := kripke.NewGraph()
+	for i := 0; i < n; i++ {
+		g.AddState(fmt.Sprintf("s%d", i), map[string]bool{
+			"p": rng.Intn(2) == 0,
+			"q": rng.Intn(3) == 0,
+		})
+	}
+	for i := 0; i < edges; i++ {
+		g.AddLabeledEdge(fmt.Sprintf("s%d", rng.Intn(n)), fmt.Sprintf("s%d", rng.Intn(n)),
+			kripke.EdgeLabel{Action: []string{"a", "b"}[rng.Intn(2)]})
+	}
+	g.SetInitial("s0")
+	return g
+}
+
+// randomFormula builds a random formula over p and q mixing CTL with
+// modalities and fixpoints.
+func randomFormula(rng *rand.Rand, depth int, fresh *int) kripke.Formula {
+	if depth == 0 {
+		return kripke.Atom([]string{"p", "q"}[rng.Intn(2)])
+	}
+	sub := func() kripke.Formula { return randomFormula(rng, depth-1, fresh) }
+	action := func() kripke.ActionFormula {
+		return []kripke.ActionFormula{kripke.Act("a"), kripke.Act("b"), kripke.AnyAction()}[rng.Intn(3)]
+	}
+	switch rng.Intn(13) {
+	case 0:
+		return kripke.Not(sub())
+	case 1:
+		return kripke.And(sub(), sub())
+	case 2:
+		return kripke.Or(sub(), sub())
+	case 3:
+		return kripke.EU(sub(), sub())
+	case 4:
+		return kripke.EF(sub())
+	case 5:
+		return kripke.AF(sub())
+	case 6:
+		return kripke.EG(sub())
+	case 7:
+		return kripke.AG(sub())
+	case 8:
+		return kripke.EX(sub())
+	case 9:
+		return kripke.AX(sub())
+	case 10:
+		return kripke.Diamond(action(), sub())
+	case 11:
+		return kripke.Box(action(), sub())
+	}
+	*fresh++
+	z := fmt.Sprintf("Z%d", *fresh)
+	if rng.Intn(2) == 0 {
+		return kripke.Mu(z, kripke.Or(sub(), kripke.Diamond(action(), kripke.FixVar(z))))
+	}
+	return kripke.Nu(z, kripke.And(sub(), kripke.Box(action(), kripke.FixVar(z))))
+}
+
+func TestFromGraphMatchesExplicit(t *testing.T) {
+	rng := rand.New(rand.NewSource(45))
+	fresh := 0
+	for i := 0; i < 300; i++ {
+		g := randomGraph(rng, 2+rng.Intn(10), rng.Intn(25))
+		m := FromGraph(g)
+		f := randomFormula(rng, 3, &fresh)
+		got, err := m.Sat(f)
+		if err != nil {
+			t.Fatalf("%v: %v", f, err)
+		}
+		if want := f.Sat(g); !got.Equal(want) {
+			t.Fatalf("%s: symbolic %v, explicit %v", kripke.Explain(f), got, want)
+		}
+		holds, err := m.Holds(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := f.Sat(g).Contains(g.InitialStates()[0]); holds != want {
+			t.Fatalf("%s: Holds %v, want %v", kripke.Explain(f), holds, want)
+		}
+	}
+}
+
+// Past formulas the history product cannot evaluate are errors, not
+// panics.
+func TestFromGraphRejectsBadPast(t *testing.T) {
+	g := randomGraph(rand.New(rand.NewSource(7)), 3, 4)
+	p := kripke.Atom("p")
+	for _, f := range []kripke.Formula{
+		kripke.Once(kripke.EF(kripke.Yesterday(p))),
+		kripke.Mu("X", kripke.Or(p, kripke.Yesterday(kripke.FixVar("X")))),
+		kripke.Nu("X", kripke.Historically(kripke.FixVar("X"))),
+	} {
+		if _, err := FromGraph(g).Sat(f); err == nil {
+			t.Errorf("%s: no error", kripke.Explain(f))
+		}
+	}
+}
+
+// buffer is a producer filling a bounded buffer and a consumer taking
+// items out one at a time.
+func buffer() *Model {
+	m := New()
+	n := m.Int("n", 0, 3, 0)
+	busy := m.Bool("busy", false)
+	mgr := m.BDD()
+	m.Rule("producer", "put", n.Lt(3), n.Apply(func(x int) (int, bool) { return x + 1, true }))
+	m.Rule("consumer", "take", mgr.And(n.Gt(0), mgr.Not(busy.IsTrue())),
+		n.Apply(func(x int) (int, bool) { return x - 1, true }), busy.SetBool(true))
+	m.Rule("consumer", "done", busy.IsTrue(), busy.SetBool(false))
+	m.Label("full", n.Eq(3))
+	return m
+}
+
+func TestDeclaredMatchesExplicit(t *testing.T) {
+	m := buffer()
+	g := m.Explicit()
+	if got, want := m.Count(m.Reachable()), float64(len(g.States())); got != want {
+		t.Fatalf("%v reachable states, explicit graph has %v", got, want)
+	}
+	for _, src := range []string{
+		"AG(n <= 3)",
+		"EF(full)",
+		"AG(EF(n == 0))",
+		"AG(busy -> AF(!busy))",
+		"AG(full -> EX(n == 2 & busy))",
+		"EG(!full)",
+		"E[!busy U n + 1 > 2]",
+		"AG(<take>busy)",
+		"[put](n > 0)",
+		"nu Z. (n < 3 & [put]Z)",
+	} {
+		f := kripke.MustParseMu(src)
+		set, err := m.Check(f)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		want := f.Sat(g)
+		for _, s := range g.States() {
+			values := make(map[string]int)
+			for k, v := range g.Vars(s) {
+				switch v := v.(type) {
+				case bool:
+					if v {
+						values[k] = 1
+					} else {
+						values[k] = 0
+					}
+				case int:
+					values[k] = v
+				}
+			}
+			in := m.BDD().And(set, m.valuationBDD(values)) != bdd.False
+			if in != want.Contains(s) {
+				t.Fatalf("%s at %s: symbolic %v, explicit %v", src, g.NameOf(s), in, want.Contains(s))
+			}
+		}
+	}
+}
+
+func TestManyVariables(t *testing.T) {
+	// Twenty-four independent toggles: 2^24 states, a few hundred BDD
+	// nodes.
+	m := New()
+	mgr := m.BDD()
+	const n = 24
+	allOff := bdd.True
+	for i := 0; i < n; i++ {
+		v := m.Bool(fmt.Sprintf("b%d", i), false)
+		m.Rule(fmt.Sprintf("p%d", i), "flip", mgr.Not(v.IsTrue()), v.SetBool(true))
+		m.Rule(fmt.Sprintf("p%d", i), "flip", v.IsTrue(), v.SetBool(false))
+		allOff = mgr.And(allOff, mgr.Not(v.IsTrue()))
+	}
+	m.Label("off", allOff)
+	if got := m.Count(m.Reachable()); got != 1<<n {
+		t.Fatalf("%v reachable states, want %d", got, 1<<n)
+	}
+	for src, want := range map[string]bool{
+		"AG(EF(off))":        true,
+		"AG(AF(off))":        false,
+		"EF(b3 & b17 & !b5)": true,
+		"AX(!off)":           true,
+		"EX(off)":            false,
+	} {
+		holds, err := m.Holds(kripke.MustParseCTL(src))
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if holds != want {
+			t.Errorf("%s: %v, want %v", src, holds, want)
+		}
+	}
+	if _, err := m.Holds(kripke.Atom("nosuch")); err == nil {
+		t.Error("unknown proposition accepted")
+	}
+	if _, err := m.Sat(kripke.True()); err == nil {
+		t.Error("Sat accepted a declared model")
+	}
+	if _, err := m.Check(kripke.Mu("X", kripke.Not(kripke.FixVar("X")))); err == nil {
+		t.Error("non-monotone fixpoint accepted")
+	}
+}
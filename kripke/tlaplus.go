@@ -1,111 +1,907 @@
 package kripke
 
 import (
+	_ "embed"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-// GenerateTLAPlus generates a TLA+ specification using KripkeLib operators
-// This is for documentation purposes - to show what TLA+ would look like
-// It's not a complete translation, just a readable example
-func (w *World) GenerateTLAPlus(moduleName string) string {
-	var tla strings.Builder
-	
-	// Module header
-	tla.WriteString(fmt.Sprintf("---- MODULE %s ----\n", moduleName))
-	tla.WriteString("EXTENDS Naturals, Sequences, KripkeLib\n\n")
-	
-	// Constants
-	tla.WriteString("CONSTANTS\n")
-	tla.WriteString("    MaxMessages,       \\* Maximum messages to send\n")
-	tla.WriteString("    ChannelCapacity    \\* Channel buffer size\n\n")
-	
-	// Variables
-	tla.WriteString("VARIABLES\n")
-	tla.WriteString("    producer_count,    \\* Messages sent by producer\n")
-	tla.WriteString("    consumer_count,    \\* Messages received by consumer\n")
-	tla.WriteString("    channel            \\* Communication channel\n\n")
-	
-	tla.WriteString("vars == <<producer_count, consumer_count, channel>>\n\n")
-	
-	// Type invariant
-	tla.WriteString("TypeOK ==\n")
-	tla.WriteString("    /\\ producer_count \\in Nat\n")
-	tla.WriteString("    /\\ consumer_count \\in Nat\n")
-	tla.WriteString("    /\\ channel \\in Seq(Nat)\n\n")
-	
-	// Initial state
-	tla.WriteString("Init ==\n")
-	tla.WriteString("    /\\ producer_count = 0\n")
-	tla.WriteString("    /\\ consumer_count = 0\n")
-	tla.WriteString("    /\\ channel = <<>>\n\n")
-	
-	// Producer action
-	tla.WriteString("\\* Producer: sends messages to channel\n")
-	tla.WriteString("Producer ==\n")
-	tla.WriteString("    /\\ producer_count < MaxMessages\n")
-	tla.WriteString("    /\\ can_send(channel, ChannelCapacity)\n")
-	tla.WriteString("    /\\ channel' = snd(channel, producer_count)    \\* Process calculus: channel ! msg\n")
-	tla.WriteString("    /\\ producer_count' = producer_count + 1\n")
-	tla.WriteString("    /\\ UNCHANGED consumer_count\n\n")
-	
-	// Consumer action
-	tla.WriteString("\\* Consumer: receives messages from channel\n")
-	tla.WriteString("Consumer ==\n")
-	tla.WriteString("    LET result == rcv(channel) IN                 \\* Process calculus: channel ? msg\n")
-	tla.WriteString("    /\\ can_recv(channel)\n")
-	tla.WriteString("    /\\ channel' = result.channel\n")
-	tla.WriteString("    /\\ consumer_count' = consumer_count + 1\n")
-	tla.WriteString("    /\\ UNCHANGED producer_count\n\n")
-	
-	// Example: Chance node (optional - show the pattern)
-	tla.WriteString("\\* Example: Action with probabilistic failure\n")
-	tla.WriteString("\\* Dice roll R1 on every scheduler attempt (0-100)\n")
-	tla.WriteString("ProducerWithFailure ==\n")
-	tla.WriteString("    /\\ producer_count < MaxMessages\n")
-	tla.WriteString("    /\\ can_send(channel, ChannelCapacity)\n")
-	tla.WriteString("    /\\ (\n")
-	tla.WriteString("        \\* choice(0 <= R1 < 95, success) - 95% success\n")
-	tla.WriteString("        \\/ choice(0, 95, TRUE,\n")
-	tla.WriteString("                  /\\ channel' = snd(channel, producer_count)\n")
-	tla.WriteString("                  /\\ producer_count' = producer_count + 1)\n")
-	tla.WriteString("        \n")
-	tla.WriteString("        \\* choice(95 <= R1 < 100, failure) - 5% failure\n")
-	tla.WriteString("        \\/ choice(95, 100, TRUE,\n")
-	tla.WriteString("                  UNCHANGED vars)\n")
-	tla.WriteString("       )\n")
-	tla.WriteString("    /\\ UNCHANGED consumer_count\n\n")
-	
-	// Next state relation
-	tla.WriteString("Next ==\n")
-	tla.WriteString("    \\/ Producer\n")
-	tla.WriteString("    \\/ Consumer\n\n")
-	
-	// Specification
-	tla.WriteString("\\* Temporal specification\n")
-	tla.WriteString("Spec == Init /\\ [][Next]_vars\n\n")
-	
-	// Safety properties
-	tla.WriteString("\\* Safety: system never violates these conditions\n")
-	tla.WriteString("Safety ==\n")
-	tla.WriteString("    /\\ TypeOK\n")
-	tla.WriteString("    /\\ producer_count <= MaxMessages\n")
-	tla.WriteString("    /\\ Len(channel) <= ChannelCapacity\n")
-	tla.WriteString("    /\\ consumer_count <= producer_count\n\n")
-	
-	// Liveness properties
-	tla.WriteString("\\* Liveness: system eventually satisfies these conditions\n")
-	tla.WriteString("EventuallyComplete ==\n")
-	tla.WriteString("    <>(producer_count = MaxMessages /\\ Len(channel) = 0)\n\n")
-	
-	// Fairness
-	tla.WriteString("\\* Fairness: ensure all actors make progress\n")
-	tla.WriteString("Fairness ==\n")
-	tla.WriteString("    /\\ WF_vars(Producer)\n")
-	tla.WriteString("    /\\ WF_vars(Consumer)\n\n")
-	
-	// Module footer
-	tla.WriteString("====\n")
-	
-	return tla.String()
+// ---------- TLA+ translation ----------
+
+//go:embed KripkeLib.tla
+var kripkeLib string
+
+// KripkeLib returns the KripkeLib TLA+ module that every generated
+// specification extends: the channel operators can_send, snd, can_recv
+// and rcv, with Channel's FIFO semantics, and choice for branches selected
+// by pre-rolled dice.
+func KripkeLib() string { return kripkeLib }
+
+// TLAModule is a TLA+ specification generated from a World, together
+// with the TLC configuration that checks it.
+type TLAModule struct {
+	Name   string
+	Spec   string // contents of <Name>.tla
+	Config string // contents of <Name>.cfg
+	// Invariants and Properties name the operators the configuration
+	// checks, one per translated formula, in the order given.
+	Invariants []string
+	Properties []string
+	// Unsupported lists the formulas that have no TLA+ form.
+	Unsupported []TLAUnsupported
+}
+
+// TLAUnsupported is a formula GenerateTLAPlus could not translate.
+type TLAUnsupported struct {
+	Name    string // CTLSpec.Name or Requirement.ID
+	Formula string
+	Reason  string
+}
+
+func (u TLAUnsupported) String() string {
+	return fmt.Sprintf("%s (%s): %s", u.Name, u.Formula, u.Reason)
+}
+
+// TLAOption configures GenerateTLAPlus.
+type TLAOption func(*tlaOptions)
+
+type tlaOptions struct {
+	formulas []tlaFormula
+}
+
+// tlaFormula is a named formula in ParseCTL syntax.
+type tlaFormula struct {
+	name, src string
+}
+
+// WithCTLSpecs translates each spec's formula into a property named after
+// it. Repeated options accumulate.
+func WithCTLSpecs(specs ...CTLSpec) TLAOption {
+	return func(o *tlaOptions) {
+		for _, s := range specs {
+			o.formulas = append(o.formulas, tlaFormula{s.Name, s.Formula})
+		}
+	}
+}
+
+// WithRequirements translates each requirement's FormulaString into a
+// property named after its ID. Repeated options accumulate.
+func WithRequirements(reqs ...Requirement) TLAOption {
+	return func(o *tlaOptions) {
+		for _, r := range reqs {
+			o.formulas = append(o.formulas, tlaFormula{r.ID, r.FormulaString})
+		}
+	}
+}
+
+// Files returns every file TLC needs to check the module, by name:
+// <Name>.tla, <Name>.cfg and KripkeLib.tla.
+func (m *TLAModule) Files() map[string]string {
+	return map[string]string{
+		m.Name + ".tla": m.Spec,
+		m.Name + ".cfg": m.Config,
+		"KripkeLib.tla": kripkeLib,
+	}
+}
+
+// WriteFiles writes Files into dir.
+func (m *TLAModule) WriteFiles(dir string) error {
+	files := m.Files()
+	for _, name := range sortedKeys(files) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(files[name]), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateTLAPlus translates w into a TLA+ module. Every process must be
+// an *Actor, whose steps are data rather than closures:
+//
+//   - each actor variable becomes a variable <actor>_<var>, and each
+//     channel a sequence <actor>_<channel> of payloads;
+//   - Init fixes them to their values in w, queued messages included;
+//   - each action becomes an operator <actor>_<action>, its guard
+//     conjoined with can_recv/can_send for the channels it uses;
+//   - Next is their disjunction and Spec adds weak fairness on Next, so a
+//     behaviour only stops where the World has no enabled step.
+//
+// The channel operators come from KripkeLib, which WriteFiles emits
+// alongside the specification. Identifiers are the names
+// with every character other than a letter, digit or underscore replaced
+// by an underscore. The configuration checks Spec and the TypeOK
+// invariant, which bounds every channel by its capacity, and disables
+// TLC's deadlock check: a World whose actors have all finished has a
+// terminal state, not an error.
+//
+// WithCTLSpecs and WithRequirements add formulas to check. AG of a state
+// formula becomes an INVARIANT; AF p, AG(p -> AF q), A(π) over G and F,
+// and their conjunctions become a PROPERTY. State formulas name variables
+// as ActorVariables, ChannelVariables and ChannelPropositions do.
+// Formulas without a TLA+ form, such as EF p, AX p or A[p U q], are
+// listed in Unsupported and in a comment in the specification rather
+// than reported as errors.
+func (w *World) GenerateTLAPlus(moduleName string, opts ...TLAOption) (*TLAModule, error) {
+	var o tlaOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !isTLAIdent(moduleName) {
+		return nil, fmt.Errorf("GenerateTLAPlus: %q is not a TLA+ module name", moduleName)
+	}
+	t, err := newTLATranslator(w)
+	if err != nil {
+		return nil, fmt.Errorf("GenerateTLAPlus: %w", err)
+	}
+	m := &TLAModule{Name: moduleName}
+	props := t.properties(o.formulas, m)
+	spec, err := t.module(moduleName, props, m.Unsupported)
+	if err != nil {
+		return nil, fmt.Errorf("GenerateTLAPlus: %w", err)
+	}
+	m.Spec = spec
+	m.Config = t.config(moduleName, m)
+	return m, nil
+}
+
+// tlaVar is one TLA+ variable: an actor variable or a channel.
+type tlaVar struct {
+	ident  string
+	source string   // "<actor>.<var>" or the channel address
+	value  any      // initial value of an actor variable
+	ch     *Channel // the channel, for channel variables
+}
+
+type tlaTranslator struct {
+	actors []*Actor
+	vars   []*tlaVar
+	byName map[string]*tlaVar // keyed by source
+	// idents maps every identifier the module defines to what it
+	// translates.
+	idents map[string]string
+	// stopped records that a property refers to Stopped.
+	stopped bool
+}
+
+func newTLATranslator(w *World) (*tlaTranslator, error) {
+	t := &tlaTranslator{byName: make(map[string]*tlaVar), idents: make(map[string]string)}
+	for _, op := range []string{"vars", "TypeOK", "Init", "Next", "Spec", "Stopped"} {
+		t.idents[op] = "the operator " + op
+	}
+	define := func(ident, source string) error {
+		if prev, ok := t.idents[ident]; ok {
+			return fmt.Errorf("%s and %s both translate to %s", prev, source, ident)
+		}
+		t.idents[ident] = source
+		return nil
+	}
+	add := func(v *tlaVar) error {
+		if err := define(v.ident, v.source); err != nil {
+			return err
+		}
+		t.vars = append(t.vars, v)
+		t.byName[v.source] = v
+		return nil
+	}
+	for _, p := range w.Procs {
+		if p == nil {
+			continue
+		}
+		a, ok := p.(*Actor)
+		if !ok {
+			return nil, fmt.Errorf("process %s (%T) is not an *Actor", p.ID(), p)
+		}
+		t.actors = append(t.actors, a)
+		for _, name := range a.VarNames() {
+			source := a.id + "." + name
+			if err := add(&tlaVar{ident: tlaIdent(source), source: source, value: a.vars[name]}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, key := range sortedKeys(w.Channels) {
+		if err := add(&tlaVar{ident: tlaIdent(key), source: key, ch: w.Channels[key]}); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range t.actors {
+		for _, act := range a.actions {
+			if err := define(tlaIdent(a.id+"_"+act.Name), "action "+a.id+"."+act.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// tlaIdent turns a name into a TLA+ identifier.
+func tlaIdent(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// isTLAIdent reports whether s is a TLA+ identifier: letters, digits and
+// underscores, with at least one letter.
+func isTLAIdent(s string) bool {
+	letter := false
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			letter = true
+		case r == '_' || r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return letter
+}
+
+// tlaValue renders an int, string or bool as a TLA+ literal.
+func tlaValue(v any) (string, error) {
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x), nil
+	case bool:
+		if x {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case string:
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+		return `"` + r.Replace(x) + `"`, nil
+	}
+	nv, ok := normalizeValue(v)
+	if !ok {
+		return "", fmt.Errorf("value %v of type %T has no TLA+ form", v, v)
+	}
+	return tlaValue(nv)
+}
+
+// lookup resolves a name: first as a variable of a, when a is not nil,
+// then as a full "<actor>.<var>" name or channel address.
+func (t *tlaTranslator) lookup(a *Actor, name string) (*tlaVar, bool) {
+	if a != nil {
+		if v, ok := t.byName[a.id+"."+name]; ok && v.ch == nil {
+			return v, true
+		}
+	}
+	v, ok := t.byName[name]
+	return v, ok
+}
+
+// isString reports whether e is string valued, so that + concatenates.
+func (t *tlaTranslator) isString(a *Actor, e Expr) bool {
+	switch e := e.(type) {
+	case ConstExpr:
+		_, ok := e.Value.(string)
+		return ok
+	case VarExpr:
+		v, ok := t.lookup(a, e.Name)
+		if !ok {
+			return false
+		}
+		_, ok = v.value.(string)
+		return ok
+	case ArithExpr:
+		return e.Op == "+" && (t.isString(a, e.Left) || t.isString(a, e.Right))
+	}
+	return false
+}
+
+// tlaArith has no / or %: Go's integer division truncates toward zero,
+// TLA+'s \div and % floor, and neither is defined for a zero divisor, on
+// which an Actor's action is disabled.
+var tlaArith = map[string]string{"+": "+", "-": "-", "*": "*"}
+
+// expr translates e in the scope of a (nil for the whole World). Every
+// TLA+ arithmetic operator binds tighter than the comparisons, so only
+// nested arithmetic needs parentheses.
+func (t *tlaTranslator) expr(a *Actor, e Expr) (string, error) {
+	s, err := t.operand(a, e)
+	if _, ok := e.(ArithExpr); ok && err == nil {
+		s = s[1 : len(s)-1]
+	}
+	return s, err
+}
+
+// operand is expr with arithmetic parenthesized.
+func (t *tlaTranslator) operand(a *Actor, e Expr) (string, error) {
+	switch e := e.(type) {
+	case ConstExpr:
+		return tlaValue(e.Value)
+	case VarExpr:
+		if v, ok := t.lookup(a, e.Name); ok && v.ch == nil {
+			return v.ident, nil
+		}
+		if name, ok := strings.CutSuffix(e.Name, ".len"); ok {
+			if v, ok := t.lookup(a, name); ok && v.ch != nil {
+				return "Len(" + v.ident + ")", nil
+			}
+		}
+		return "", fmt.Errorf("unknown variable %s", e.Name)
+	case LenExpr:
+		if v, ok := t.lookup(a, e.Name); ok {
+			if _, isStr := v.value.(string); v.ch != nil || isStr {
+				return "Len(" + v.ident + ")", nil
+			}
+		}
+		return "", fmt.Errorf("%s is neither a channel nor a string variable", e.Name)
+	case ArithExpr:
+		l, err := t.operand(a, e.Left)
+		if err != nil {
+			return "", err
+		}
+		r, err := t.operand(a, e.Right)
+		if err != nil {
+			return "", err
+		}
+		op, ok := tlaArith[e.Op]
+		if !ok {
+			return "", fmt.Errorf("%s: Go's %s truncates toward zero and has no TLA+ form", e, e.Op)
+		}
+		if e.Op == "+" && t.isString(a, e) {
+			op = `\o`
+		}
+		return "(" + l + " " + op + " " + r + ")", nil
+	}
+	return "", fmt.Errorf("expression %s has no TLA+ form", e)
+}
+
+var tlaCompare = map[string]string{"==": "=", "!=": "#", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+// state translates a formula without temporal operators in the scope of a.
+func (t *tlaTranslator) state(a *Actor, f Formula) (string, error) {
+	bin := func(l, r Formula, op string) (string, error) {
+		ls, err := t.state(a, l)
+		if err != nil {
+			return "", err
+		}
+		rs, err := t.state(a, r)
+		if err != nil {
+			return "", err
+		}
+		return "(" + ls + " " + op + " " + rs + ")", nil
+	}
+	switch f := f.(type) {
+	case TrueFormula:
+		return "TRUE", nil
+	case AtomFormula:
+		if v, ok := t.lookup(a, f.Prop); ok {
+			if _, ok := v.value.(bool); ok {
+				return v.ident, nil
+			}
+		}
+		if a == nil {
+			if p, ok := t.channelProposition(f.Prop); ok {
+				return p, nil
+			}
+		}
+		return "", fmt.Errorf("proposition %s is not a bool variable", f.Prop)
+	case CompareFormula:
+		l, err := t.expr(a, f.Left)
+		if err != nil {
+			return "", err
+		}
+		r, err := t.expr(a, f.Right)
+		if err != nil {
+			return "", err
+		}
+		return l + " " + tlaCompare[f.Op] + " " + r, nil
+	case NotFormula:
+		inner, err := t.state(a, f.Inner)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := f.Inner.(CompareFormula); ok {
+			inner = "(" + inner + ")"
+		}
+		return "~" + inner, nil
+	case AndFormula:
+		return bin(f.Left, f.Right, `/\`)
+	case OrFormula:
+		return bin(f.Left, f.Right, `\/`)
+	}
+	return "", fmt.Errorf("%s is not a state formula", Explain(f))
+}
+
+// module renders the specification.
+func (t *tlaTranslator) module(name string, props []tlaProperty, unsupported []TLAUnsupported) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "---- MODULE %s ----\n", name)
+	sb.WriteString("EXTENDS Integers, Sequences, KripkeLib\n\n")
+
+	idents := make([]string, len(t.vars))
+	width := 0
+	for i, v := range t.vars {
+		idents[i] = v.ident
+		width = max(width, len(v.ident))
+	}
+	if len(t.vars) > 0 {
+		sb.WriteString("VARIABLES\n")
+		for i, v := range t.vars {
+			sep := ","
+			if i == len(t.vars)-1 {
+				sep = ""
+			}
+			what := v.source
+			if v.ch != nil {
+				what = fmt.Sprintf("channel %s, capacity %d", v.source, v.ch.Capacity())
+			}
+			fmt.Fprintf(&sb, "    %-*s \\* %s\n", width+1, v.ident+sep, what)
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "vars == <<%s>>\n\n", strings.Join(idents, ", "))
+
+	var typeOK []string
+	for _, v := range t.vars {
+		switch v.value.(type) {
+		case int:
+			typeOK = append(typeOK, v.ident+` \in Int`)
+		case string:
+			typeOK = append(typeOK, v.ident+` \in STRING`)
+		case bool:
+			typeOK = append(typeOK, v.ident+` \in BOOLEAN`)
+		}
+		if v.ch != nil {
+			typeOK = append(typeOK, fmt.Sprintf("Len(%s) <= %d", v.ident, v.ch.Capacity()))
+		}
+	}
+	fmt.Fprintf(&sb, "TypeOK ==\n%s\n", conjunction(typeOK))
+
+	var init []string
+	for _, v := range t.vars {
+		var val string
+		if v.ch != nil {
+			elems := make([]string, len(v.ch.buf))
+			for i, msg := range v.ch.buf {
+				s, err := tlaValue(msg.Payload)
+				if err != nil {
+					return "", fmt.Errorf("channel %s: %w", v.source, err)
+				}
+				elems[i] = s
+			}
+			val = "<<" + strings.Join(elems, ", ") + ">>"
+		} else {
+			s, err := tlaValue(v.value)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", v.source, err)
+			}
+			val = s
+		}
+		init = append(init, v.ident+" = "+val)
+	}
+	fmt.Fprintf(&sb, "Init ==\n%s\n", conjunction(init))
+
+	var next []string
+	for _, a := range t.actors {
+		for _, act := range a.actions {
+			op := tlaIdent(a.id + "_" + act.Name)
+			body, err := t.action(a, act)
+			if err != nil {
+				return "", fmt.Errorf("actor %s, action %s: %w", a.id, act.Name, err)
+			}
+			fmt.Fprintf(&sb, "\\* %s: %s\n%s ==\n%s\n", a.id, EdgeLabel{Action: act.Name, Guard: act.Guard}, op, body)
+			next = append(next, op)
+		}
+	}
+
+	sb.WriteString("Next ==\n")
+	if len(next) == 0 {
+		sb.WriteString("    FALSE\n")
+	}
+	for _, op := range next {
+		fmt.Fprintf(&sb, "    \\/ %s\n", op)
+	}
+	sb.WriteString("\n")
+	sb.WriteString("Spec == Init /\\ [][Next]_vars /\\ WF_vars(Next)\n\n")
+	if t.stopped {
+		sb.WriteString("\\* No actor can take a step: the World has stopped, and the behaviour\n")
+		sb.WriteString("\\* stutters from here on.\n")
+		sb.WriteString("Stopped == ~ENABLED Next\n\n")
+	}
+	for _, p := range props {
+		fmt.Fprintf(&sb, "\\* %s: %s\n%s ==\n    %s\n\n", p.name, p.src, p.ident, p.text)
+	}
+	if len(unsupported) > 0 {
+		sb.WriteString("\\* Not expressible in TLA+:\n")
+		for _, u := range unsupported {
+			fmt.Fprintf(&sb, "\\*   %s\n", u)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("====\n")
+	return sb.String(), nil
+}
+
+// action renders the body of act's operator.
+func (t *tlaTranslator) action(a *Actor, act *action) (string, error) {
+	var lines []string
+	conj := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	if act.Guard != "" {
+		g, err := t.state(a, act.guard)
+		if err != nil {
+			return "", err
+		}
+		conj("%s", g)
+	}
+	changed := make(map[*tlaVar]bool)
+	channel := func(addr Address) (*tlaVar, error) {
+		v, ok := t.byName[addr.String()]
+		if !ok || v.ch == nil {
+			return nil, fmt.Errorf("no channel %s", addr)
+		}
+		return v, nil
+	}
+	if act.Recv != "" {
+		ch, err := channel(Address{a.id, act.Recv})
+		if err != nil {
+			return "", err
+		}
+		conj("can_recv(%s)", ch.ident)
+		conj("%s' = rcv(%s).channel", ch.ident, ch.ident)
+		changed[ch] = true
+		if act.Into != "" {
+			into, _ := t.lookup(a, act.Into)
+			conj("%s' = rcv(%s).msg", into.ident, ch.ident)
+			changed[into] = true
+		}
+	}
+	for i, send := range act.Send {
+		ch, err := channel(send.To)
+		if err != nil {
+			return "", err
+		}
+		payload, err := t.expr(a, act.payloads[i])
+		if err != nil {
+			return "", err
+		}
+		conj("can_send(%s, %d)", ch.ident, ch.ch.Capacity())
+		conj("%s' = snd(%s, %s)", ch.ident, ch.ident, payload)
+		changed[ch] = true
+	}
+	for _, name := range sortedKeys(act.set) {
+		v, _ := t.lookup(a, name)
+		e, err := t.expr(a, act.set[name])
+		if err != nil {
+			return "", err
+		}
+		conj("%s' = %s", v.ident, e)
+		changed[v] = true
+	}
+	var unchanged []string
+	for _, v := range t.vars {
+		if !changed[v] {
+			unchanged = append(unchanged, v.ident)
+		}
+	}
+	if len(unchanged) > 0 {
+		conj("UNCHANGED <<%s>>", strings.Join(unchanged, ", "))
+	}
+	return conjunction(lines), nil
+}
+
+// conjunction renders the conjuncts as an indented /\ list, or TRUE.
+func conjunction(conjuncts []string) string {
+	if len(conjuncts) == 0 {
+		return "    TRUE\n"
+	}
+	var sb strings.Builder
+	for _, c := range conjuncts {
+		sb.WriteString("    /\\ " + c + "\n")
+	}
+	return sb.String()
+}
+
+// config renders the TLC configuration.
+func (t *tlaTranslator) config(name string, m *TLAModule) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\\* TLC configuration for %s.tla\n", name)
+	sb.WriteString("SPECIFICATION Spec\n")
+	sb.WriteString("INVARIANT TypeOK\n")
+	for _, inv := range m.Invariants {
+		fmt.Fprintf(&sb, "INVARIANT %s\n", inv)
+	}
+	for _, prop := range m.Properties {
+		fmt.Fprintf(&sb, "PROPERTY %s\n", prop)
+	}
+	sb.WriteString("\\* A World whose actors have all finished stops in a terminal state.\n")
+	sb.WriteString("CHECK_DEADLOCK FALSE\n")
+	return sb.String()
+}
+
+// ---------- Properties ----------
+
+// channelProposition translates the propositions of ChannelPropositions.
+func (t *tlaTranslator) channelProposition(name string) (string, bool) {
+	if name == "deadlock" {
+		t.stopped = true
+		return "Stopped", true
+	}
+	for suffix, format := range map[string]string{".empty": "%s = <<>>", ".full": "Len(%s) >= %d"} {
+		if addr, ok := strings.CutSuffix(name, suffix); ok {
+			if v, ok := t.byName[addr]; ok && v.ch != nil {
+				if suffix == ".empty" {
+					return fmt.Sprintf(format, v.ident), true
+				}
+				return fmt.Sprintf(format, v.ident, v.ch.Capacity()), true
+			}
+		}
+	}
+	return "", false
+}
+
+// tlaProperty is a formula translated into the operator ident.
+type tlaProperty struct {
+	ident, name, src, text string
+}
+
+// properties translates every formula, recording each operator in m's
+// Invariants or Properties and each failure in m.Unsupported.
+func (t *tlaTranslator) properties(formulas []tlaFormula, m *TLAModule) []tlaProperty {
+	taken := make(map[string]bool, len(t.idents))
+	for ident := range t.idents {
+		taken[ident] = true
+	}
+	var out []tlaProperty
+	for _, f := range formulas {
+		unsupported := func(reason string) {
+			m.Unsupported = append(m.Unsupported, TLAUnsupported{Name: f.name, Formula: f.src, Reason: reason})
+		}
+		if f.src == "" {
+			unsupported("no textual formula")
+			continue
+		}
+		parsed, err := ParseCTL(f.src)
+		if err != nil {
+			unsupported(err.Error())
+			continue
+		}
+		text, invariant, err := t.translateProperty(parsed)
+		if err != nil {
+			unsupported(err.Error())
+			continue
+		}
+		prefix := "Prop_"
+		if invariant {
+			prefix = "Inv_"
+		}
+		ident := prefix + tlaIdent(f.name)
+		for i := 2; taken[ident]; i++ {
+			ident = fmt.Sprintf("%s%s_%d", prefix, tlaIdent(f.name), i)
+		}
+		taken[ident] = true
+		if invariant {
+			m.Invariants = append(m.Invariants, ident)
+		} else {
+			m.Properties = append(m.Properties, ident)
+		}
+		out = append(out, tlaProperty{ident: ident, name: f.name, src: f.src, text: text})
+	}
+	return out
+}
+
+// translateProperty translates f into a TLA+ formula every behaviour of
+// Spec must satisfy, which is what it means for f to hold in the initial
+// states. invariant reports that f is AG of a state formula, and text
+// then is that state formula.
+//
+// CTL's path quantifiers range over infinite paths, while a behaviour
+// that reaches a state where the World has stopped stutters there
+// forever. So AF p becomes <>(p \/ Stopped), and A(π) becomes
+// <>Stopped \/ π: a behaviour that stops satisfies both, as a finite
+// path does.
+//
+// TLA+ properties are linear-time and invariant under stuttering, so f
+// must be equivalent to an LTL formula without next: state formulas,
+// AG φ ([]φ), AF p (<>p) for a state formula p, AG(p -> AF q) (p ~> q),
+// A(π) for a path formula of G, F and the connectives over state formulas,
+// conjunctions, and disjunctions with a state formula on one side.
+// Negations are pushed inward first, so !EF p is AG !p. Everything else is
+// rejected with the reason: existential path quantifiers, next and until,
+// AF of a temporal formula and path quantifiers nested in A(π) (AF AG p
+// and A(F AG p) are stronger than <>[]p), past operators and the
+// μ-calculus.
+func (t *tlaTranslator) translateProperty(f Formula) (text string, invariant bool, err error) {
+	for {
+		n, ok := f.(NotFormula)
+		if _, _, au := matchAU(f); !ok || au || isStateFormula(f) {
+			break
+		}
+		pushed, ok := pushNot(n.Inner)
+		if !ok {
+			break
+		}
+		f = pushed
+	}
+	if ag, ok := f.(AGFormula); ok && isStateFormula(ag.Inner) {
+		text, err := t.state(nil, ag.Inner)
+		return text, true, err
+	}
+	text, err = t.temporal(f)
+	return text, false, err
+}
+
+func (t *tlaTranslator) temporal(f Formula) (string, error) {
+	if isStateFormula(f) {
+		return t.state(nil, f)
+	}
+	if _, _, ok := matchAU(f); ok {
+		return "", fmt.Errorf("TLA+ has no until operator")
+	}
+	switch f := f.(type) {
+	case AGFormula:
+		if or, ok := f.Inner.(OrFormula); ok {
+			if p, ok := or.Left.(NotFormula); ok && isStateFormula(p.Inner) {
+				if q, ok := or.Right.(AFFormula); ok && isStateFormula(q.Inner) {
+					return t.leadsTo(p.Inner, q.Inner)
+				}
+			}
+		}
+		inner, err := t.temporal(f.Inner)
+		if err != nil {
+			return "", err
+		}
+		return "[](" + inner + ")", nil
+	case AFFormula:
+		if !isStateFormula(f.Inner) {
+			return "", fmt.Errorf("AF of a temporal formula has no LTL equivalent")
+		}
+		inner, err := t.state(nil, f.Inner)
+		if err != nil {
+			return "", err
+		}
+		t.stopped = true
+		return "<>(" + inner + " \\/ Stopped)", nil
+	case AFormula:
+		inner, err := t.path(f.Path)
+		if err != nil {
+			return "", err
+		}
+		t.stopped = true
+		return "(<>Stopped \\/ " + inner + ")", nil
+	case AndFormula:
+		return t.binary(f.Left, f.Right, `/\`)
+	case OrFormula:
+		if !isStateFormula(f.Left) && !isStateFormula(f.Right) {
+			return "", fmt.Errorf("a disjunction of temporal formulas has no LTL equivalent")
+		}
+		return t.binary(f.Left, f.Right, `\/`)
+	case NotFormula:
+		if pushed, ok := pushNot(f.Inner); ok {
+			return t.temporal(pushed)
+		}
+		return "", fmt.Errorf("the negation of a universal property is existential")
+	case AXFormula:
+		return "", fmt.Errorf("TLA+ has no next-state operator in properties")
+	case EXFormula, EFFormula, EGFormula, EUFormula, EFormula:
+		return "", fmt.Errorf("existential path quantifier: TLA+ properties hold on every behaviour")
+	case HistoryFormula:
+		return "", fmt.Errorf("past operators have no TLA+ form")
+	}
+	return "", fmt.Errorf("%s has no TLA+ form", Explain(f))
+}
+
+func (t *tlaTranslator) binary(l, r Formula, op string) (string, error) {
+	ls, err := t.temporal(l)
+	if err != nil {
+		return "", err
+	}
+	rs, err := t.temporal(r)
+	if err != nil {
+		return "", err
+	}
+	return "(" + ls + " " + op + " " + rs + ")", nil
+}
+
+func (t *tlaTranslator) leadsTo(p, q Formula) (string, error) {
+	ps, err := t.state(nil, p)
+	if err != nil {
+		return "", err
+	}
+	qs, err := t.state(nil, q)
+	if err != nil {
+		return "", err
+	}
+	t.stopped = true
+	return "(" + ps + ") ~> (" + qs + " \\/ Stopped)", nil
+}
+
+// pushNot rewrites !f one level down, if that is possible without
+// leaving the universal fragment.
+func pushNot(f Formula) (Formula, bool) {
+	switch f := f.(type) {
+	case NotFormula:
+		return f.Inner, true
+	case EFFormula:
+		return AG(Not(f.Inner)), true
+	case EGFormula:
+		return AF(Not(f.Inner)), true
+	case EXFormula:
+		return AX(Not(f.Inner)), true
+	case EFormula:
+		return A(NotPath(f.Path)), true
+	case AndFormula:
+		return Or(Not(f.Left), Not(f.Right)), true
+	case OrFormula:
+		return And(Not(f.Left), Not(f.Right)), true
+	}
+	return nil, false
+}
+
+// path translates a path formula of A(π).
+func (t *tlaTranslator) path(p PathFormula) (string, error) {
+	switch p := p.(type) {
+	case PathState:
+		// A nested path quantifier does not commute with the operators
+		// around it: A(F AG p) is stronger than <>[]p.
+		if !isStateFormula(p.State) {
+			return "", fmt.Errorf("path quantifier nested in A(...) has no LTL equivalent")
+		}
+		return t.state(nil, p.State)
+	case PathNot:
+		// G π is !(true U !π).
+		if u, ok := p.Inner.(PathUntil); ok && isTruePath(u.Left) {
+			if n, ok := u.Right.(PathNot); ok {
+				inner, err := t.path(n.Inner)
+				if err != nil {
+					return "", err
+				}
+				return "[](" + inner + ")", nil
+			}
+		}
+		inner, err := t.path(p.Inner)
+		if err != nil {
+			return "", err
+		}
+		return "~(" + inner + ")", nil
+	case PathAnd, PathOr:
+		var l, r PathFormula
+		op := `/\`
+		if a, ok := p.(PathAnd); ok {
+			l, r = a.Left, a.Right
+		} else {
+			o := p.(PathOr)
+			l, r, op = o.Left, o.Right, `\/`
+		}
+		ls, err := t.path(l)
+		if err != nil {
+			return "", err
+		}
+		rs, err := t.path(r)
+		if err != nil {
+			return "", err
+		}
+		return "(" + ls + " " + op + " " + rs + ")", nil
+	case PathUntil:
+		if !isTruePath(p.Left) {
+			return "", fmt.Errorf("TLA+ has no until operator")
+		}
+		inner, err := t.path(p.Right)
+		if err != nil {
+			return "", err
+		}
+		return "<>(" + inner + ")", nil
+	case PathNext:
+		return "", fmt.Errorf("TLA+ has no next-state operator in properties")
+	}
+	return "", fmt.Errorf("path formula %T has no TLA+ form", p)
+}
+
+func isTruePath(p PathFormula) bool {
+	s, ok := p.(PathState)
+	if !ok {
+		return false
+	}
+	_, ok = s.State.(TrueFormula)
+	return ok
 }
@@ -0,0 +1,240 @@
+package kripke
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateTLAPlus(t *testing.T) {
+	w := actorWorld(3, 2)
+	w.ChannelByAddress(Address{"C", "inbox"}).TrySend(Message{Payload: 7})
+	m, err := w.GenerateTLAPlus("ProducerConsumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---- MODULE ProducerConsumer ----
+EXTENDS Integers, Sequences, KripkeLib
+
+VARIABLES
+    P_max,   \* P.max
+    P_next,  \* P.next
+    C_count, \* C.count
+    C_last,  \* C.last
+    C_inbox  \* channel C.inbox, capacity 2
+
+vars == <<P_max, P_next, C_count, C_last, C_inbox>>
+
+TypeOK ==
+    /\ P_max \in Int
+    /\ P_next \in Int
+    /\ C_count \in Int
+    /\ C_last \in Int
+    /\ Len(C_inbox) <= 2
+
+Init ==
+    /\ P_max = 3
+    /\ P_next = 1
+    /\ C_count = 0
+    /\ C_last = 0
+    /\ C_inbox = <<7>>
+
+\* P: send [next <= max]
+P_send ==
+    /\ P_next <= P_max
+    /\ can_send(C_inbox, 2)
+    /\ C_inbox' = snd(C_inbox, P_next)
+    /\ P_next' = P_next + 1
+    /\ UNCHANGED <<P_max, C_count, C_last>>
+
+\* C: take
+C_take ==
+    /\ can_recv(C_inbox)
+    /\ C_inbox' = rcv(C_inbox).channel
+    /\ C_last' = rcv(C_inbox).msg
+    /\ C_count' = C_count + 1
+    /\ UNCHANGED <<P_max, P_next>>
+
+Next ==
+    \/ P_send
+    \/ C_take
+
+Spec == Init /\ [][Next]_vars /\ WF_vars(Next)
+
+====
+`
+	if m.Spec != want {
+		t.Fatalf("spec:\n%s", m.Spec)
+	}
+	for _, line := range []string{"SPECIFICATION Spec", "INVARIANT TypeOK", "CHECK_DEADLOCK FALSE"} {
+		if !strings.Contains(m.Config, line+"\n") {
+			t.Errorf("config lacks %q:\n%s", line, m.Config)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := m.WriteFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+	files := m.Files()
+	if len(files) != 3 || files["KripkeLib.tla"] != KripkeLib() {
+		t.Fatalf("files %v", sortedKeys(files))
+	}
+	for name, content := range files {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(b) != content {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestKripkeLibDefinesGeneratedOperators(t *testing.T) {
+	lib := KripkeLib()
+	if !strings.HasPrefix(lib, "---- MODULE KripkeLib ----\n") || !strings.HasSuffix(lib, "====\n") {
+		t.Fatalf("KripkeLib is not a module:\n%s", lib)
+	}
+	m, err := actorWorld(2, 1).GenerateTLAPlus("M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"can_send", "snd", "can_recv", "rcv", "choice"} {
+		if !strings.Contains(lib, "\n"+op+"(") {
+			t.Errorf("KripkeLib does not define %s", op)
+		}
+		if op != "choice" && !strings.Contains(m.Spec, " "+op+"(") {
+			t.Errorf("generated spec does not use %s", op)
+		}
+	}
+}
+
+func TestGenerateTLAPlusExpressions(t *testing.T) {
+	out := NewChannel("B", "in", 1)
+	a := MustNewActor("A.1", map[string]any{"on": true, "name": `say "hi"`, "n": -2},
+		Action{
+			Name:  "go",
+			Guard: `!on | (n != 3 & !(n - 2 == 0)) | len(B.in) > 0`,
+			Send:  []Send{{To: out.Address(), Payload: `name + "!"`}},
+			Set:   map[string]string{"n": "(n + 1) * 2 - 3", "on": "false"},
+		})
+	b := MustNewActor("B", nil)
+	m, err := NewWorld([]Process{a, b}, []*Channel{out}, 1).GenerateTLAPlus("M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`A_1_name = "say \"hi\""`,
+		`A_1_n = -2`,
+		`/\ ((~A_1_on \/ (A_1_n # 3 /\ ~(A_1_n - 2 = 0))) \/ Len(B_in) > 0)`,
+		`/\ B_in' = snd(B_in, A_1_name \o "!")`,
+		`/\ A_1_n' = ((A_1_n + 1) * 2) - 3`,
+		`/\ A_1_on' = FALSE`,
+		`/\ UNCHANGED <<A_1_name>>`,
+	} {
+		if !strings.Contains(m.Spec, want) {
+			t.Errorf("spec lacks %s:\n%s", want, m.Spec)
+		}
+	}
+}
+
+func TestGenerateTLAPlusErrors(t *testing.T) {
+	ch := NewChannel("C", "in", 1)
+	for _, tc := range []struct {
+		name  string
+		procs []Process
+		chans []*Channel
+		want  string
+	}{
+		{"M", []Process{&testConsumer{id: "C"}}, nil, "not an *Actor"},
+		{"M", []Process{MustNewActor("A", map[string]any{"b_c": 0}), MustNewActor("A_b", map[string]any{"c": 0})}, nil, "both translate to A_b_c"},
+		{"M", []Process{MustNewActor("P", map[string]any{"go": 0}, Action{Name: "go"})}, nil, "P.go and action P.go both translate to P_go"},
+		{"M", []Process{MustNewActor("a_b", nil, Action{Name: "c"}), MustNewActor("a", nil, Action{Name: "b_c"})}, nil, "action a_b.c and action a.b_c both translate to a_b_c"},
+		{"M", []Process{MustNewActor("A", nil, Action{Name: "s", Send: []Send{{To: Address{"X", "y"}, Payload: "1"}}})}, nil, "no channel X.y"},
+		{"M", []Process{MustNewActor("A", map[string]any{"x": 0}, Action{Name: "s", Set: map[string]string{"x": "x / 2"}})}, nil, "truncates"},
+		{"M", []Process{MustNewActor("A", map[string]any{"x": 0}, Action{Name: "s", Guard: "x % 2 == 0"})}, nil, "truncates"},
+		{"M", nil, []*Channel{ch}, "has no TLA+ form"},
+		{"1-bad", nil, nil, "not a TLA+ module name"},
+	} {
+		if len(tc.chans) > 0 {
+			ch.TrySend(Message{Payload: 1.5})
+		}
+		_, err := NewWorld(tc.procs, tc.chans, 1).GenerateTLAPlus(tc.name)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("error %v, want %q", err, tc.want)
+		}
+	}
+}
+
+func TestGenerateTLAPlusProperties(t *testing.T) {
+	w := actorWorld(3, 2)
+	reqs := []Requirement{
+		{ID: "R1", FormulaString: "AG(P.next <= 4 & !(C.inbox.full & len(C.inbox) < 2))"},
+		{ID: "R2", FormulaString: "!EF(deadlock & C.count < 3)"},
+		{ID: "R3", FormulaString: "AF(C.count == 3)"},
+		{ID: "R4", FormulaString: "AG(P.next > 1 -> AF(C.inbox.empty))"},
+		{ID: "R5", FormulaString: "A(GF C.count == 3) & C.last == 0"},
+		{ID: "R6", FormulaString: "EF(C.count == 3)"},
+		{ID: "R7", FormulaString: "AX(P.next == 2)"},
+		{ID: "R8", FormulaString: "A[P.next < 3 U C.count > 0]"},
+		{ID: "R9", FormulaString: "AF(AG(C.inbox.empty))"},
+		{ID: "R10", FormulaString: "AG(P.nope > 0)"},
+		{ID: "R11"},
+		{ID: "R12", FormulaString: "A(F AG C.inbox.empty)"},
+	}
+	m, err := w.GenerateTLAPlus("PC", WithRequirements(reqs...), WithCTLSpecs(CTLSpec{Name: "R1", Formula: "AG(C.count <= 3)"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\\* R1: AG(P.next <= 4 & !(C.inbox.full & len(C.inbox) < 2))\nInv_R1 ==\n    (P_next <= 4 /\\ ~(Len(C_inbox) >= 2 /\\ Len(C_inbox) < 2))\n",
+		"Inv_R2 ==\n    ~(Stopped /\\ C_count < 3)\n",
+		"Prop_R3 ==\n    <>(C_count = 3 \\/ Stopped)\n",
+		"Prop_R4 ==\n    (P_next > 1) ~> (C_inbox = <<>> \\/ Stopped)\n",
+		"Prop_R5 ==\n    ((<>Stopped \\/ [](<>(C_count = 3))) /\\ C_last = 0)\n",
+		"Inv_R1_2 ==\n    C_count <= 3\n",
+		"Stopped == ~ENABLED Next\n",
+		"\\* Not expressible in TLA+:\n\\*   R6 (EF(C.count == 3)): ",
+	} {
+		if !strings.Contains(m.Spec, want) {
+			t.Errorf("spec lacks %q:\n%s", want, m.Spec)
+		}
+	}
+	if got := strings.Join(m.Invariants, " "); got != "Inv_R1 Inv_R2 Inv_R1_2" {
+		t.Errorf("invariants %s", got)
+	}
+	if got := strings.Join(m.Properties, " "); got != "Prop_R3 Prop_R4 Prop_R5" {
+		t.Errorf("properties %s", got)
+	}
+	if !strings.Contains(m.Config, "INVARIANT TypeOK\nINVARIANT Inv_R1\nINVARIANT Inv_R2\nINVARIANT Inv_R1_2\nPROPERTY Prop_R3\nPROPERTY Prop_R4\nPROPERTY Prop_R5\n") {
+		t.Errorf("config:\n%s", m.Config)
+	}
+
+	reasons := map[string]string{
+		"R6":  "existential",
+		"R7":  "next-state",
+		"R8":  "until",
+		"R9":  "AF of a temporal formula",
+		"R10": "unknown variable P.nope",
+		"R11": "no textual formula",
+		"R12": "path quantifier nested in A(...)",
+	}
+	if len(m.Unsupported) != len(reasons) {
+		t.Fatalf("unsupported: %v", m.Unsupported)
+	}
+	for _, u := range m.Unsupported {
+		if !strings.Contains(u.Reason, reasons[u.Name]) {
+			t.Errorf("%s: reason %q, want %q", u.Name, u.Reason, reasons[u.Name])
+		}
+	}
+
+	// The translated requirements use the names Explore records, and hold.
+	g, err := Explore(w, WithVariables(ActorVariables(w)), WithVariables(ChannelVariables(w)), WithPropositions(ChannelPropositions(w)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range g.EvaluateRequirements(reqs[:5]) {
+		if r.Verdict != VerdictPass {
+			t.Errorf("%s: %s %s", r.ID, r.Verdict, r.Error)
+		}
+	}
+}
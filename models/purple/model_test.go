@@ -0,0 +1,36 @@
+package purple
+
+import (
+	"testing"
+
+	"github.com/rfielding/kripke-ctl/kripke"
+)
+
+func TestCheckModelSpec(t *testing.T) {
+	res, err := kripke.CheckModelSpec(PurpleModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unsolved may loop forever, so the key is reachable but not
+	// inevitable, and neither formula holds.
+	want := map[string]bool{"AF attackerKnowsKey": false, "AG !attackerKnowsKey": false}
+	if len(res) != len(want) {
+		t.Fatalf("%d results, want %d", len(res), len(want))
+	}
+	for _, r := range res {
+		if r.Holds != want[r.Spec.Name] {
+			t.Errorf("%s: holds = %v", r.Spec.Name, r.Holds)
+		}
+	}
+
+	g := kripke.ModelGraph(PurpleModel{})
+	for src, want := range map[string]bool{
+		"EF attackerKnowsKey":              true,
+		"AG (VowelSolved -> AF UniqueKey)": true,
+	} {
+		sat := kripke.MustParseCTL(src).Sat(g)
+		if holds := sat.Contains(g.InitialStates()[0]); holds != want {
+			t.Errorf("%s: holds = %v, want %v", src, holds, want)
+		}
+	}
+}